@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// accountOptionName is the option that picks the seller account a command acts for
+const accountOptionName = "account"
+
+// accountOption is the optional account option added to commands when several seller
+// accounts are configured. Names are suggested as the user types.
+func accountOption(description string) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionString,
+		Name:         accountOptionName,
+		Description:  description,
+		Autocomplete: true,
+	}
+}
+
+// withAccountOption adds the account option to cmd, or to each of its subcommands
+func withAccountOption(cmd *discordgo.ApplicationCommand) {
+	const description = "eBay seller account (default: the first configured)"
+	if !hasSubcommands(cmd.Options) {
+		cmd.Options = appendAccountOption(cmd.Options, description)
+		return
+	}
+	for _, opt := range cmd.Options {
+		addAccountToSubcommands(opt, description)
+	}
+}
+
+func addAccountToSubcommands(opt *discordgo.ApplicationCommandOption, description string) {
+	switch opt.Type {
+	case discordgo.ApplicationCommandOptionSubCommand:
+		opt.Options = appendAccountOption(opt.Options, description)
+	case discordgo.ApplicationCommandOptionSubCommandGroup:
+		for _, sub := range opt.Options {
+			addAccountToSubcommands(sub, description)
+		}
+	}
+}
+
+// appendAccountOption adds the account option to options unless it is already there, as
+// the shared command definitions keep it once registered
+func appendAccountOption(options []*discordgo.ApplicationCommandOption, description string) []*discordgo.ApplicationCommandOption {
+	for _, opt := range options {
+		if opt.Name == accountOptionName {
+			return options
+		}
+	}
+	return append(options, accountOption(description))
+}
+
+func hasSubcommands(options []*discordgo.ApplicationCommandOption) bool {
+	for _, opt := range options {
+		if opt.Type == discordgo.ApplicationCommandOptionSubCommand || opt.Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// accountFlag returns the account option in a command's (sub)options, if it was given
+func accountFlag(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Name == accountOptionName && opt.Type == discordgo.ApplicationCommandOptionString {
+			return opt
+		}
+		if found := accountFlag(opt.Options); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// requestedAccount returns the account named by a command, or "" for the default
+func requestedAccount(i *discordgo.InteractionCreate) string {
+	if opt := accountFlag(i.ApplicationCommandData().Options); opt != nil {
+		return strings.TrimSpace(opt.StringValue())
+	}
+	return ""
+}
+
+// resolveAccount returns the client for the account a command names
+func (h *Handler) resolveAccount(i *discordgo.InteractionCreate) (*ebay.Client, error) {
+	return h.accounts.Get(requestedAccount(i))
+}
+
+// client returns the eBay client for the account a command names. interactionHandler
+// has already refused unknown accounts, so this falls back to the default.
+func (h *Handler) client(i *discordgo.InteractionCreate) *ebay.Client {
+	if client, err := h.resolveAccount(i); err == nil {
+		return client
+	}
+	return h.accounts.Default()
+}
+
+// multiAccount reports whether more than one seller account is configured
+func (h *Handler) multiAccount() bool {
+	return h.accounts.Len() > 1
+}
+
+// buttonAccount is the account recorded in offer button IDs: empty with a single account,
+// so the IDs stay as they were
+func (h *Handler) buttonAccount(client *ebay.Client) string {
+	if !h.multiAccount() {
+		return ""
+	}
+	return client.Account()
+}
+
+// storeAccount is the account local store records and sync cursors are kept under:
+// empty for the default account
+func (h *Handler) storeAccount(client *ebay.Client) string {
+	if client == h.accounts.Default() {
+		return ""
+	}
+	return client.Account()
+}
+
+// accountHeading names the account at the top of a reply when several are configured
+func (h *Handler) accountHeading(client *ebay.Client) string {
+	if !h.multiAccount() {
+		return ""
+	}
+	return fmt.Sprintf("🏪 Account **%s**\n", client.Account())
+}
+
+// refuseUnknownAccount answers a command that names an account that isn't configured
+func refuseUnknownAccount(s *discordgo.Session, i *discordgo.InteractionCreate, err error) {
+	commandFailed(i)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("❌ %v", err),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// handleAccountAutocomplete suggests the account names starting with what was typed
+func (h *Handler) handleAccountAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	opt := accountFlag(i.ApplicationCommandData().Options)
+	if opt == nil || !opt.Focused {
+		return
+	}
+	typed := strings.ToLower(strings.TrimSpace(opt.StringValue()))
+
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, name := range h.accounts.Names() {
+		if strings.HasPrefix(name, typed) && len(choices) < 25 {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+		}
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+}
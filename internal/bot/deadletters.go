@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DeadLetterQueue is the subset of the delivery queue the /dead-letters command needs
+type DeadLetterQueue interface {
+	DeadLetters(limit int) ([]store.OutboxMessage, error)
+	Replay(id string) error
+}
+
+// adminPermission restricts admin-only commands to server administrators
+var adminPermission int64 = discordgo.PermissionAdministrator
+
+// deadLettersCommand defines /dead-letters list|replay
+var deadLettersCommand = &discordgo.ApplicationCommand{
+	Name:                     "dead-letters",
+	Description:              "Inspect and replay notifications that could not be delivered",
+	DefaultMemberPermissions: &adminPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List undelivered notifications",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "replay",
+			Description: "Queue a dead letter for delivery again",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "id",
+					Description: "Dead letter ID, or \"all\"",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// SetDeadLetterQueue sets the delivery queue managed by /dead-letters
+func (h *Handler) SetDeadLetterQueue(queue DeadLetterQueue) {
+	h.deadLetters = queue
+}
+
+func (h *Handler) handleDeadLetters(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var msg string
+	if h.deadLetters == nil {
+		msg = "⚠️ The delivery queue is not enabled"
+	} else {
+		sub := i.ApplicationCommandData().Options[0]
+		switch sub.Name {
+		case "list":
+			msg = h.listDeadLetters()
+		case "replay":
+			msg = h.replayDeadLetters(sub.Options[0].StringValue())
+		}
+	}
+
+	countReply(i, msg)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (h *Handler) listDeadLetters() string {
+	letters, err := h.deadLetters.DeadLetters(0)
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to read dead letters: %v", err)
+	}
+	if len(letters) == 0 {
+		return "📭 No dead letters - every notification was delivered"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "☠️ **Dead Letters** (%d)\n\n", len(letters))
+	for n, l := range letters {
+		if n == 15 {
+			fmt.Fprintf(&b, "…and %d more\n", len(letters)-n)
+			break
+		}
+		fmt.Fprintf(&b, "• `%s` %s → <#%s> (%d attempts, <t:%d:R>)\n   %s\n",
+			l.ID, l.EventType, l.ChannelID, l.Attempts, l.UpdatedAt.Unix(), truncateText(l.LastError, 150))
+	}
+	b.WriteString("\n💡 Replay with `/dead-letters replay id:<id>` or `id:all`")
+	return b.String()
+}
+
+func (h *Handler) replayDeadLetters(id string) string {
+	if !strings.EqualFold(id, "all") {
+		if err := h.deadLetters.Replay(id); err != nil {
+			return fmt.Sprintf("❌ Failed to replay `%s`: %v", id, err)
+		}
+		return fmt.Sprintf("🔁 Dead letter `%s` queued for delivery", id)
+	}
+
+	letters, err := h.deadLetters.DeadLetters(0)
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to read dead letters: %v", err)
+	}
+	replayed := 0
+	for _, l := range letters {
+		if err := h.deadLetters.Replay(l.ID); err == nil {
+			replayed++
+		}
+	}
+	return fmt.Sprintf("🔁 %d of %d dead letters queued for delivery", replayed, len(letters))
+}
+
+// truncateText shortens s to at most n runes
+func truncateText(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
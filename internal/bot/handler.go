@@ -8,27 +8,38 @@ import (
 	"time"
 
 	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+	"ebaymanager-bot/internal/syncer"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-// WebhookServer interface for OAuth callbacks
+// WebhookServer interface for OAuth callbacks and delivery stats
 type WebhookServer interface {
-	RegisterOAuthCallback(state string, discord *discordgo.Session, interaction *discordgo.Interaction)
+	BeginOAuth(discord *discordgo.Session, interaction *discordgo.Interaction, client *ebay.Client, scopes []string) (string, error)
+	DuplicatesSuppressed() uint64
+	ReplayNotification(id string) (int, error)
+	SimulateNotification(event, account string, signed bool) (int, error)
+	ReconcileSubscriptions(account string, dryRun bool) (string, error)
+	PublicBaseURL() string
 }
 
 // Handler manages Discord bot interactions
 type Handler struct {
 	discord       *discordgo.Session
-	ebay          *ebay.Client
+	accounts      *ebay.Accounts
 	webhookServer WebhookServer
+	store         store.Repository
+	deadLetters   DeadLetterQueue
+	commandIDs    map[string]string // registered slash command IDs by name
 }
 
-// NewHandler creates a new bot handler
-func NewHandler(discord *discordgo.Session, ebayClient *ebay.Client) *Handler {
+// NewHandler creates a new bot handler for the configured seller accounts
+func NewHandler(discord *discordgo.Session, accounts *ebay.Accounts) *Handler {
 	return &Handler{
-		discord: discord,
-		ebay:    ebayClient,
+		discord:    discord,
+		accounts:   accounts,
+		commandIDs: make(map[string]string),
 	}
 }
 
@@ -37,6 +48,11 @@ func (h *Handler) SetWebhookServer(server WebhookServer) {
 	h.webhookServer = server
 }
 
+// SetStore sets the local store that /get-orders and /get-listings read from once synced
+func (h *Handler) SetStore(repo store.Repository) {
+	h.store = repo
+}
+
 // RegisterCommands sets up Discord slash commands and message handlers
 func (h *Handler) RegisterCommands() {
 	// Register message handler
@@ -89,11 +105,19 @@ func (h *Handler) RegisterCommands() {
 		},
 		{
 			Name:        "ebay-scopes",
-			Description: "Check what API scopes your OAuth token has",
+			Description: "Compare the API scopes your OAuth token has with the ones the bot needs",
 		},
 		{
 			Name:        "ebay-authorize",
 			Description: "Authorize bot with your eBay account (get refresh token)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "missing-only",
+					Description: "Keep the granted scopes and add only the ones the authorization is missing",
+					Required:    false,
+				},
+			},
 		},
 		{
 			Name:        "ebay-code",
@@ -114,7 +138,7 @@ func (h *Handler) RegisterCommands() {
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "url",
-					Description: "Webhook URL (leave empty to use jacob.it.com)",
+					Description: "Webhook URL (leave empty to reconcile WEBHOOK_PUBLIC_URL)",
 					Required:    false,
 				},
 			},
@@ -169,6 +193,25 @@ func (h *Handler) RegisterCommands() {
 				},
 			},
 		},
+		deadLettersCommand,
+		routeCommand,
+		notificationsCommand,
+		simulateCommand,
+		webhookTopicsCommand,
+		webhookSubscriptionCommand,
+	}
+
+	// With several seller accounts, commands that call eBay take the account to act for
+	if h.multiAccount() {
+		for _, cmd := range commands {
+			switch cmd.Name {
+			case "dead-letters", "notifications", "webhook-test":
+			case "route":
+				addRouteAccountOption()
+			default:
+				withAccountOption(cmd)
+			}
+		}
 	}
 
 	// Delete all existing commands first (cleans up old/removed commands)
@@ -192,11 +235,21 @@ func (h *Handler) RegisterCommands() {
 			log.Printf("❌ Failed to create command %s: %v", cmd.Name, err)
 		} else {
 			log.Printf("✅ Registered command: /%s (ID: %s)", createdCmd.Name, createdCmd.ID)
+			h.commandIDs[createdCmd.Name] = createdCmd.ID
 		}
 	}
 	log.Println("Command registration complete!")
 }
 
+// CommandMention returns a clickable mention of a registered slash command, or its
+// name in code style if it isn't registered
+func (h *Handler) CommandMention(name string) string {
+	if id := h.commandIDs[name]; id != "" {
+		return "</" + name + ":" + id + ">"
+	}
+	return "`/" + name + "`"
+}
+
 // messageHandler handles regular Discord messages
 func (h *Handler) messageHandler(s *discordgo.Session, m *discordgo.MessageCreate) {
 	// Ignore messages from the bot itself
@@ -210,9 +263,37 @@ func (h *Handler) messageHandler(s *discordgo.Session, m *discordgo.MessageCreat
 	}
 }
 
-// interactionHandler handles slash command interactions
+// interactionHandler handles slash commands, button presses and modal submissions
 func (h *Handler) interactionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	switch i.ApplicationCommandData().Name {
+	switch i.Type {
+	case discordgo.InteractionMessageComponent:
+		if action, account, offerID, ok := parseOfferButton(i.MessageComponentData().CustomID); ok {
+			h.handleOfferButton(s, i, action, account, offerID)
+		}
+		return
+	case discordgo.InteractionModalSubmit:
+		if id, ok := strings.CutPrefix(i.ModalSubmitData().CustomID, counterModalPrefix); ok {
+			offerID, account := splitOfferAccount(id)
+			h.handleCounterModal(s, i, account, offerID)
+		}
+		return
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		h.handleAccountAutocomplete(s, i)
+		return
+	case discordgo.InteractionApplicationCommand:
+	default:
+		return
+	}
+
+	command := i.ApplicationCommandData().Name
+	defer finishCommand(s, i, command, time.Now())
+
+	if _, err := h.resolveAccount(i); err != nil {
+		refuseUnknownAccount(s, i, err)
+		return
+	}
+
+	switch command {
 	case "get-orders":
 		h.handleGetOrders(s, i)
 	case "get-offers":
@@ -243,24 +324,42 @@ func (h *Handler) interactionHandler(s *discordgo.Session, i *discordgo.Interact
 		h.handleCounterOffer(s, i)
 	case "decline-offer":
 		h.handleDeclineOffer(s, i)
+	case "dead-letters":
+		h.handleDeadLetters(s, i)
+	case "route":
+		h.handleRoute(s, i)
+	case "notifications":
+		h.handleNotifications(s, i)
+	case "webhook-simulate":
+		h.handleWebhookSimulate(s, i)
+	case "webhook-topics":
+		h.handleWebhookTopics(s, i)
+	case "webhook-subscription":
+		h.handleWebhookSubscription(s, i)
+	default:
+		commandErrors.Inc(command)
+		log.Printf("⚠️ Unknown command /%s", command)
 	}
 }
 
 func (h *Handler) handleGetBalance(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	client := h.client(i)
+
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	balance, err := h.ebay.GetSellerBalance()
+	balance, err := client.GetSellerBalance()
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ Failed to get balance: %v", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
 		return
 	}
 
-	msg := fmt.Sprintf("💰 **Your eBay Balance**\n\n**Available for Next Payout:** $%.2f\n**Total Balance:** $%.2f\n\n💡 *Available funds will be included in your next scheduled payout. Use `/get-payouts` to see completed payouts.*",
+	msg := h.accountHeading(client) + fmt.Sprintf("💰 **Your eBay Balance**\n\n**Available for Next Payout:** $%.2f\n**Total Balance:** $%.2f\n\n💡 *Available funds will be included in your next scheduled payout. Use `/get-payouts` to see completed payouts.*",
 		balance["available"], balance["total"])
 
 	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
@@ -269,20 +368,22 @@ func (h *Handler) handleGetBalance(s *discordgo.Session, i *discordgo.Interactio
 }
 
 func (h *Handler) handleGetPayouts(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
+	client := h.client(i)
+	options := optionMap(i.ApplicationCommandData().Options)
 	limit := 10
 
-	if len(options) > 0 {
-		limit = int(options[0].IntValue())
+	if opt, ok := options["limit"]; ok {
+		limit = int(opt.IntValue())
 	}
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	payouts, err := h.ebay.GetPayouts(limit)
+	payouts, err := client.GetPayouts(limit)
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ Failed to get payouts: %v", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
@@ -323,9 +424,11 @@ func (h *Handler) handleGetOrders(s *discordgo.Session, i *discordgo.Interaction
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	orders, err := h.ebay.GetOrders(10) // Get last 10 orders
+	client := h.client(i)
+	orders, lastSynced, err := h.loadOrders(client, 10) // Get last 10 orders
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ Failed to fetch orders: %v", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
@@ -341,7 +444,7 @@ func (h *Handler) handleGetOrders(s *discordgo.Session, i *discordgo.Interaction
 	}
 
 	// Build response message with embeds for images
-	response := "📋 **Recent Orders**\n\n"
+	response := h.accountHeading(client) + "📋 **Recent Orders**\n" + syncedLine(lastSynced) + "\n"
 	embeds := []*discordgo.MessageEmbed{}
 
 	for i, order := range orders {
@@ -391,15 +494,79 @@ func (h *Handler) handleGetOrders(s *discordgo.Session, i *discordgo.Interaction
 	})
 }
 
+// loadOrders returns client's recent orders from the local store when a background sync
+// has completed, falling back to a live API call. lastSynced is zero for live results.
+func (h *Handler) loadOrders(client *ebay.Client, limit int) ([]ebay.Order, time.Time, error) {
+	account := h.storeAccount(client)
+	if h.store != nil {
+		if lastSynced, ok := syncer.LastSynced(h.store, syncer.CursorName(syncer.OrdersCursor, account)); ok {
+			records, err := h.store.ListOrders(0)
+			if err == nil {
+				orders := make([]ebay.Order, 0, limit)
+				for _, r := range records {
+					if r.Account == account && len(orders) < limit {
+						orders = append(orders, ebay.OrderFromRecord(r))
+					}
+				}
+				if len(orders) > 0 {
+					return orders, lastSynced, nil
+				}
+			}
+		}
+	}
+
+	orders, err := client.GetOrders(limit)
+	return orders, time.Time{}, err
+}
+
+// loadListings returns client's active listings from the local store when a background
+// sync has completed, falling back to a live API call. lastSynced is zero for live results.
+func (h *Handler) loadListings(client *ebay.Client, limit int) ([]ebay.Listing, time.Time, error) {
+	account := h.storeAccount(client)
+	if h.store != nil {
+		if lastSynced, ok := syncer.LastSynced(h.store, syncer.CursorName(syncer.ListingsCursor, account)); ok {
+			records, err := h.store.ListListings(0)
+			if err == nil {
+				listings := make([]ebay.Listing, 0, len(records))
+				for _, r := range records {
+					if r.Account == account {
+						listings = append(listings, ebay.ListingFromRecord(r))
+					}
+				}
+				if limit > 0 && len(listings) > limit {
+					listings = listings[:limit]
+				}
+				if len(listings) > 0 {
+					return listings, lastSynced, nil
+				}
+			}
+		}
+	}
+
+	listings, err := client.GetListings(limit)
+	return listings, time.Time{}, err
+}
+
+// syncedLine renders a "last synced" note for results served from the local store
+func syncedLine(lastSynced time.Time) string {
+	if lastSynced.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("🕒 Last synced <t:%d:R>\n", lastSynced.Unix())
+}
+
 func (h *Handler) handleGetOffers(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	client := h.client(i)
+
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
 	// Try to fetch offers from eBay API
-	offers, err := h.ebay.GetOffers()
+	offers, err := client.GetOffers()
 	if err != nil {
 		// If API call fails, show setup instructions
+		commandFailed(i)
 		msg := "💰 **Buyer Offers**\n\n" +
 			"⚠️ Unable to fetch offers directly from API.\n\n" +
 			"🔔 **Set up offer notifications to get alerts in Discord when buyers make offers!**\n\n" +
@@ -427,8 +594,9 @@ func (h *Handler) handleGetOffers(s *discordgo.Session, i *discordgo.Interaction
 		return
 	}
 
-	// Display offers
-	msg := fmt.Sprintf("💰 **Pending Offers** (%d found):\n\n", len(offers))
+	// Display offers, with buttons for the first few that are still pending
+	msg := h.accountHeading(client) + fmt.Sprintf("💰 **Pending Offers** (%d found):\n\n", len(offers))
+	var components []discordgo.MessageComponent
 	for i, offer := range offers {
 		if i >= 10 { // Limit to 10 offers
 			msg += fmt.Sprintf("\n*...and %d more offers*", len(offers)-10)
@@ -447,23 +615,29 @@ func (h *Handler) handleGetOffers(s *discordgo.Session, i *discordgo.Interaction
 		msg += fmt.Sprintf("   👤 %s | 📦 %s\n", offer.BuyerUsername, offer.ItemTitle)
 		msg += fmt.Sprintf("   🆔 `%s`\n", offer.OfferID)
 		msg += fmt.Sprintf("   📅 %s\n\n", offer.CreatedDate.Format("Jan 02, 2006"))
+
+		if offer.Status == "PENDING" && len(components) < maxOfferButtonRows {
+			components = append(components, offerButtonRow(h.buttonAccount(client), offer.OfferID, fmt.Sprintf(" #%d", i+1)))
+		}
 	}
 
-	msg += "\n💡 Respond with:\n" +
+	msg += "\n💡 Respond with the buttons below, or:\n" +
 		"• `/accept-offer offer-id:<ID>`\n" +
 		"• `/counter-offer offer-id:<ID> price:<AMOUNT>`\n" +
 		"• `/decline-offer offer-id:<ID>`"
 
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &msg,
-	})
+	edit := &discordgo.WebhookEdit{Content: &msg}
+	if len(components) > 0 {
+		edit.Components = &components
+	}
+	s.InteractionResponseEdit(i.Interaction, edit)
 }
 
 func (h *Handler) handleGetListings(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
+	options := optionMap(i.ApplicationCommandData().Options)
 	limit := 10
-	if len(options) > 0 {
-		limit = int(options[0].IntValue())
+	if opt, ok := options["limit"]; ok {
+		limit = int(opt.IntValue())
 	}
 
 	log.Printf("[listings] Command triggered, limit=%d", limit)
@@ -472,10 +646,12 @@ func (h *Handler) handleGetListings(s *discordgo.Session, i *discordgo.Interacti
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	listings, err := h.ebay.GetListings(limit)
+	client := h.client(i)
+	listings, lastSynced, err := h.loadListings(client, limit)
 	if err != nil {
 		log.Printf("[listings] ERROR: %v", err)
 		errMsg := fmt.Sprintf("❌ Failed to fetch listings: %v", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &errMsg})
 		return
 	}
@@ -491,7 +667,7 @@ func (h *Handler) handleGetListings(s *discordgo.Session, i *discordgo.Interacti
 		return
 	}
 
-	header := fmt.Sprintf("📦 **Active Listings** (%d found)\n\u200b", len(listings))
+	header := h.accountHeading(client) + fmt.Sprintf("📦 **Active Listings** (%d found)\n%s\u200b", len(listings), syncedLine(lastSynced))
 	embeds := []*discordgo.MessageEmbed{}
 
 	for idx, listing := range listings {
@@ -552,7 +728,14 @@ func (h *Handler) handleGetListings(s *discordgo.Session, i *discordgo.Interacti
 }
 
 func (h *Handler) handleEbayStatus(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	status := h.ebay.CheckConnection()
+	client := h.client(i)
+	status := h.accountHeading(client) + client.CheckConnection()
+	if seller := client.Username(); seller != "" {
+		status += fmt.Sprintf("\n👤 **Seller:** %s", seller)
+	}
+	if expiry := h.tokenExpiry(client.Status()); expiry != "" {
+		status += "\n\n" + expiry
+	}
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -562,41 +745,75 @@ func (h *Handler) handleEbayStatus(s *discordgo.Session, i *discordgo.Interactio
 	})
 }
 
-func (h *Handler) handleEbayScopes(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	scopeInfo := h.ebay.GetTokenScopes()
+// tokenExpiry describes when the access and refresh tokens expire
+func (h *Handler) tokenExpiry(status ebay.APIStatus) string {
+	if !status.HasToken {
+		return ""
+	}
 
-	hasToken := scopeInfo["hasToken"].(bool)
-	env := scopeInfo["environment"].(string)
-	requested := scopeInfo["requestedScopes"].([]string)
+	msg := "⏳ **Access token expires:** "
+	if status.TokenExpiry.IsZero() {
+		msg += "unknown\n"
+	} else {
+		msg += fmt.Sprintf("<t:%d:f> (<t:%d:R>)\n", status.TokenExpiry.Unix(), status.TokenExpiry.Unix())
+	}
+
+	msg += "🔑 **Refresh token expires:** "
+	switch {
+	case status.RefreshExpiry.IsZero():
+		msg += "unknown - run " + h.CommandMention("ebay-authorize") + " to start tracking it"
+	case time.Until(status.RefreshExpiry) < 30*24*time.Hour:
+		msg += fmt.Sprintf("<t:%d:f> (<t:%d:R>) ⚠️ run %s to renew", status.RefreshExpiry.Unix(), status.RefreshExpiry.Unix(), h.CommandMention("ebay-authorize"))
+	default:
+		msg += fmt.Sprintf("<t:%d:f> (<t:%d:R>)", status.RefreshExpiry.Unix(), status.RefreshExpiry.Unix())
+	}
+	return msg
+}
 
-	response := "🔐 **OAuth Scopes Configuration**\n\n"
+func (h *Handler) handleEbayScopes(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	client := h.client(i)
+	scopes := client.GetTokenScopes()
+
+	response := "🔐 **OAuth Scopes**\n\n"
 
-	if !hasToken {
+	if !scopes.HasToken {
 		response += "❌ **No token found**\n\nRun `/ebay-authorize` to connect your eBay account.\n\n"
 	} else {
-		response += fmt.Sprintf("✅ **Token Status:** Active\n**Environment:** `%s`\n\n", env)
+		response += fmt.Sprintf("✅ **Token Status:** Active\n**Environment:** `%s`\n\n", scopes.Environment)
 	}
 
-	response += "**Requested Scopes:**\n"
-	for _, scope := range requested {
-		// Extract just the last part of the scope URL for readability
-		parts := strings.Split(scope, "/")
-		scopeName := parts[len(parts)-1]
-		if strings.Contains(scopeName, ".") {
-			response += fmt.Sprintf("• `%s`\n", scopeName)
-		} else {
-			response += fmt.Sprintf("• `%s` (Basic API access)\n", scopeName)
+	switch {
+	case scopes.Granted == nil:
+		response += "**Granted vs required:** granted scopes unknown (token predates scope tracking)\n"
+	case scopes.Assumed:
+		response += "**Granted vs required:** assumed from the authorization request, as eBay didn't list the grant\n"
+	default:
+		response += "**Granted vs required:**\n"
+	}
+	missing := make(map[string]bool)
+	for _, scope := range scopes.Missing {
+		missing[scope] = true
+	}
+	for _, scope := range scopes.Required {
+		marker := "✅"
+		switch {
+		case scopes.Granted == nil:
+			marker = "❔"
+		case missing[scope]:
+			marker = "❌"
 		}
+		response += fmt.Sprintf("%s `%s` - %s\n", marker, ebay.ScopeName(scope), ebay.ScopeDescription(scope))
 	}
 
-	response += "\n**What each scope does:**\n"
-	response += "• **sell.inventory** - Manage your listings\n"
-	response += "• **sell.fulfillment** - View & manage orders\n"
-	response += "• **sell.account** - Account settings & policies\n"
-	response += "• **sell.finances** - Balance, payouts & transactions\n"
-
-	if !hasToken {
+	switch {
+	case !scopes.HasToken:
 		response += "\n💡 Authorize now to enable all bot features!"
+	case scopes.Granted == nil:
+		response += "\n💡 Run `/ebay-authorize` once so the bot records which scopes eBay granted."
+	case len(scopes.Missing) > 0:
+		response += fmt.Sprintf("\n⚠️ %d scope(s) missing. Run `/ebay-authorize missing-only:true` to grant them.", len(scopes.Missing))
+	default:
+		response += "\n🎉 Every scope the bot needs has been granted."
 	}
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -608,28 +825,59 @@ func (h *Handler) handleEbayScopes(s *discordgo.Session, i *discordgo.Interactio
 }
 
 func (h *Handler) handleEbayAuthorize(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	client := h.client(i)
+	// Only the requester sees the link: whoever signs in through it connects their eBay account
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
 	})
 
-	// Generate state for OAuth
-	state := fmt.Sprintf("state_%d", time.Now().Unix())
-
-	// Register OAuth callback with webhook server if available
-	if h.webhookServer != nil {
-		h.webhookServer.RegisterOAuthCallback(state, s, i.Interaction)
-	} else {
+	if h.webhookServer == nil {
 		errMsg := "❌ Webhook server not configured. OAuth flow unavailable."
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
 		return
 	}
 
-	// Generate authorization URL (will redirect to jacob.it.com/webhook/oauth/callback)
-	authURL := h.ebay.GetUserAuthorizationURL(state)
+	// Request every scope, or with missing-only the granted ones plus those the last grant
+	// lacked: the new token replaces the old one, so nothing granted may be left out
+	scopes := ebay.Scopes
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "missing-only" && opt.BoolValue() {
+			if client.GrantedScopes() == nil {
+				break // nothing recorded to compare against
+			}
+			if len(client.MissingScopes(ebay.Scopes)) == 0 {
+				msg := "✅ The current authorization already has every scope the bot needs."
+				s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+					Content: &msg,
+				})
+				return
+			}
+			scopes = client.WithMissingScopes(ebay.Scopes)
+		}
+	}
 
-	msg := fmt.Sprintf("🔐 **eBay Authorization - AUTOMATIC MODE**\n\n✨ **Just click the link below and sign in - that's it!**\n\n%s\n\n🎯 **What happens next:**\n1. You'll be redirected to eBay to sign in\n2. Click \"Agree\" to authorize the bot\n3. You'll be redirected to jacob.it.com\n4. The bot will automatically exchange your code for tokens\n5. Done! You'll be notified here when complete!\n\n⏱️ Authorization will expire in 10 minutes.\n\n💡 **Make sure your eBay RuName is configured:**\n• Accepted URL: `https://jacob.it.com/webhook/oauth/callback`\n• Declined URL: `https://jacob.it.com/webhook/oauth/declined`", authURL)
+	// The link opens on the webhook server, which ties the flow to the browser that opens
+	// it and sends it on to eBay; it completes the flow once
+	authURL, err := h.webhookServer.BeginOAuth(s, i.Interaction, client, scopes)
+	if err != nil {
+		errMsg := fmt.Sprintf("❌ Failed to start authorization: %v", err)
+		commandFailed(i)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &errMsg,
+		})
+		return
+	}
+
+	// eBay redirects to /webhook/oauth/callback on the public host
+	base := h.publicBaseURL()
+
+	msg := fmt.Sprintf("🔐 **eBay Authorization - AUTOMATIC MODE**\n\n✨ **Just click the link below and sign in - that's it!**\n\n%s\n\n🎯 **What happens next:**\n1. You'll be redirected to eBay to sign in\n2. Click \"Agree\" to authorize the bot\n3. You'll be redirected to %s\n4. The bot will automatically exchange your code for tokens\n5. Done! You'll be notified here when complete!\n\n⏱️ This link works once, in the browser you first open it in, and expires in 10 minutes.\n\n💡 **Make sure your eBay RuName is configured:**\n• Accepted URL: `%s/webhook/oauth/callback`\n• Declined URL: `%s/webhook/oauth/declined`", authURL, base, base, base)
 
 	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: &msg,
@@ -637,8 +885,8 @@ func (h *Handler) handleEbayAuthorize(s *discordgo.Session, i *discordgo.Interac
 }
 
 func (h *Handler) handleEbayCode(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
-	code := options[0].StringValue()
+	client := h.client(i)
+	code := optionMap(i.ApplicationCommandData().Options)["code"].StringValue()
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
@@ -652,9 +900,10 @@ func (h *Handler) handleEbayCode(s *discordgo.Session, i *discordgo.InteractionC
 	}
 
 	// Exchange the code for tokens
-	tokens, err := h.ebay.ExchangeCodeForToken(decodedCode)
+	tokens, err := client.ExchangeCodeForToken(decodedCode)
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ Failed to exchange code for tokens: %v\n\n💡 Tips:\n- Copy the ENTIRE code value from the URL (it's very long)\n- The code starts after `code=` and ends before `&expires_in`\n- It should look like: `v^1.1#i^1#f^0#I^3...` (very long)", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
@@ -662,16 +911,21 @@ func (h *Handler) handleEbayCode(s *discordgo.Session, i *discordgo.InteractionC
 	}
 
 	// Save the tokens
-	oauthServer := ebay.NewOAuthServer(h.ebay)
-	if err := oauthServer.SaveTokensToEnv(tokens); err != nil {
+	if err := client.SaveTokens(); err != nil {
 		errMsg := fmt.Sprintf("❌ Tokens received but failed to save: %v", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
 		return
 	}
 
-	successMsg := fmt.Sprintf("✅ **Authorization Successful!**\n\nAccess token and refresh token have been saved to .env file.\nYour bot will now automatically refresh tokens every 90 minutes.\n\nToken expires in: %d seconds", tokens.ExpiresIn)
+	// Learn which seller signed in, so notifications can be matched to the account
+	if err := client.Identify(); err != nil {
+		log.Printf("⚠️ Failed to identify the seller for account %s: %v", client.Account(), err)
+	}
+
+	successMsg := fmt.Sprintf("✅ **Authorization Successful!**\n\nAccess token and refresh token have been saved.\nYour bot will now automatically refresh tokens every 90 minutes.\n\nToken expires in: %d seconds", tokens.ExpiresIn)
 	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: &successMsg,
 	})
@@ -679,73 +933,51 @@ func (h *Handler) handleEbayCode(s *discordgo.Session, i *discordgo.InteractionC
 	// Start auto-refresh if we have a refresh token
 	if tokens.RefreshToken != "" {
 		log.Println("Starting automatic token refresh...")
-		go h.ebay.AutoRefreshToken(tokens.RefreshToken, 90*time.Minute)
+		go client.AutoRefreshToken(tokens.RefreshToken, 90*time.Minute)
 	}
 }
 
 func (h *Handler) handleWebhookSubscribe(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
+	client := h.client(i)
+	options := optionMap(i.ApplicationCommandData().Options)
 
-	// Use default URL if not provided
-	webhookURL := "https://jacob.it.com/webhook/ebay/notification"
-	if len(options) > 0 && options[0].StringValue() != "" {
-		webhookURL = options[0].StringValue()
+	webhookURL := ""
+	if opt, ok := options["url"]; ok {
+		webhookURL = opt.StringValue()
 	}
 
-	log.Printf("🔔 webhook-subscribe command called for URL: %s", webhookURL)
+	log.Printf("🔔 webhook-subscribe command called for URL: %q", webhookURL)
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	// Check for existing subscriptions first and delete any for the same endpoint
-	log.Printf("📋 Checking for existing subscriptions...")
-	existingSubscriptions, err := h.ebay.ListWebhookSubscriptions()
-	if err != nil {
-		log.Printf("⚠️ Warning: Failed to list existing subscriptions: %v", err)
-		log.Printf("Proceeding anyway, but might encounter conflicts...")
-	} else {
-		log.Printf("✅ Found %d existing subscriptions", len(existingSubscriptions))
-		foundMatch := false
-		for _, sub := range existingSubscriptions {
-			if endpoint, ok := sub.DeliveryConfig["endpoint"].(string); ok {
-				log.Printf("  - Subscription %s: %s (status: %s)", sub.DestinationID, endpoint, sub.Status)
-				if endpoint == webhookURL {
-					foundMatch = true
-					log.Printf("🗑️ Found existing subscription for %s, deleting it first (ID: %s)", webhookURL, sub.DestinationID)
-					if delErr := h.ebay.DeleteWebhookSubscription(sub.DestinationID); delErr != nil {
-						log.Printf("❌ Failed to delete existing subscription: %v", delErr)
-						errMsg := fmt.Sprintf("❌ **Cannot create webhook subscription**\n\nThere's already a subscription for this endpoint, but I couldn't delete it.\n\nError: %v\n\n**Manual fix required:**\n1. Run `/webhook-list` to see the subscription ID\n2. Ask eBay support to delete it, or\n3. Try using a different webhook URL\n\n**Existing endpoint:** `%s`", delErr, endpoint)
-						s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-							Content: &errMsg,
-						})
-						return
-					} else {
-						log.Printf("✅ Successfully deleted existing subscription for %s", webhookURL)
-					}
-				}
-			}
-		}
-		if !foundMatch {
-			log.Printf("ℹ️ No existing subscription found for %s", webhookURL)
-		}
+	// Without a URL, converge on the configured WEBHOOK_PUBLIC_URL and topics
+	if webhookURL == "" {
+		content := truncateText(h.reconcileSubscriptions(client, false), 2000)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+		return
 	}
 
-	// Actually create the subscription with eBay
-	log.Printf("🔨 Creating new webhook subscription for %s...", webhookURL)
-	err = h.ebay.CreateWebhookSubscription(webhookURL)
+	// Reuses the destination and subscriptions for this endpoint when they already exist
+	subscriptions, err := client.CreateWebhookSubscription(webhookURL)
 	if err != nil {
 		log.Printf("❌ Failed to create subscription: %v", err)
-		errMsg := fmt.Sprintf("❌ **Failed to create webhook subscription**\n\nError: %v\n\n**Troubleshooting:**\n• Make sure you're authorized: `/ebay-authorize`\n• Check if subscription already exists: `/webhook-list`\n• Verify your webhook URL is accessible from the internet\n• URL must use HTTPS (not HTTP)\n• Make sure your webhook server is running and responding to challenges\n\n**Your webhook URL:** `%s`\n\n**Debug Info:**\nTo test if your webhook is reachable, visit:\n`%s?challenge_code=test`", err, webhookURL, webhookURL)
+		errMsg := fmt.Sprintf("❌ **Failed to create webhook subscription**\n\nError: %v\n\n**Troubleshooting:**\n• Make sure you're authorized: `/ebay-authorize`\n• Check existing subscriptions: `/webhook-subscription list`\n• Verify your webhook URL is accessible from the internet\n• URL must use HTTPS (not HTTP)\n• Make sure your webhook server is running and responding to challenges\n\n**Your webhook URL:** `%s`\n\n**Debug Info:**\nTo test if your webhook is reachable, visit:\n`%s?challenge_code=test`", err, webhookURL, webhookURL)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
 		return
 	}
 
-	log.Printf("✅ Successfully created webhook subscription for %s", webhookURL)
+	log.Printf("✅ Successfully subscribed %s to %d topics", webhookURL, len(subscriptions))
 
-	msg := fmt.Sprintf("✅ **Webhook Subscription Created!**\n\n🎣 **Your Webhook URL:**\n`%s`\n\n**📋 Active Subscriptions:**\n• 🛒 **Order notifications** - New orders, payments, shipments\n• 💰 **Offer notifications** - New offers, counters, acceptances\n• 📦 **Inventory updates** - Listing changes\n• 🔔 **Account events** - Important account notifications\n\n**✨ What happens now:**\nWhen eBay sends notifications for these events, they'll appear automatically in this Discord channel!\n\n**🧪 Test it:**\n1. Have someone make an offer on one of your listings\n2. The notification will appear here within seconds!\n3. Use `/accept-offer`, `/counter-offer`, or `/decline-offer` to respond\n\n**📊 View subscriptions:** `/webhook-list`\n\n💡 Your webhook server is running at jacob.it.com and ready to receive notifications!", webhookURL)
+	var topics strings.Builder
+	for _, sub := range subscriptions {
+		fmt.Fprintf(&topics, "• **%s** - `%s` (schema %s)\n", sub.TopicID, sub.SubscriptionID, sub.Payload.SchemaVersion)
+	}
+	msg := fmt.Sprintf("✅ **Webhook Subscription Created!**\n\n🎣 **Your Webhook URL:**\n`%s`\n\n**📋 Subscribed Topics:**\n%s\n**✨ What happens now:**\nWhen eBay sends notifications for these events, they'll appear automatically in this Discord channel!\n\n**🧪 Test it:**\n• `/webhook-subscription test id:<subscription id>` asks eBay to send a test notification\n• Or have someone make an offer on one of your listings\n\n**📊 Manage subscriptions:** `/webhook-subscription list` · **Available topics:** `/webhook-topics`", webhookURL, topics.String())
 
 	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: &msg,
@@ -753,58 +985,32 @@ func (h *Handler) handleWebhookSubscribe(s *discordgo.Session, i *discordgo.Inte
 }
 
 func (h *Handler) handleWebhookList(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	client := h.client(i)
+
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	// Fetch actual subscriptions from eBay
-	subscriptions, err := h.ebay.ListWebhookSubscriptions()
+	// Fetch actual destinations and subscriptions from eBay
+	msg, err := h.subscriptionSummary(client)
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ Failed to list subscriptions: %v\n\n💡 Make sure you're authorized with `/ebay-authorize`", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
 		return
 	}
 
-	if len(subscriptions) == 0 {
-		msg := "📋 **Active Webhook Subscriptions**\n\n📭 No active subscriptions found.\n\n**Get started:**\nRun `/webhook-subscribe` to create a webhook subscription and start receiving real-time notifications for:\n• 🛒 Orders (placed, paid, shipped)\n• 💰 Offers (created, accepted, declined, countered)\n• 📦 Inventory updates\n• 🔔 Account events"
-		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: &msg,
-		})
-		return
-	}
-
-	msg := fmt.Sprintf("📋 **Active Webhook Subscriptions** (%d found)\n\n", len(subscriptions))
-
-	for i, sub := range subscriptions {
-		status := "✅"
-		if sub.Status != "ENABLED" {
-			status = "⚠️"
-		}
-
-		msg += fmt.Sprintf("%d. %s **%s** (%s)\n", i+1, status, sub.Name, sub.Status)
-		if endpoint, ok := sub.DeliveryConfig["endpoint"].(string); ok {
-			msg += fmt.Sprintf("   📍 Endpoint: `%s`\n", endpoint)
-		}
-		msg += fmt.Sprintf("   🆔 ID: `%s`\n", sub.DestinationID)
-
-		if len(sub.Topics) > 0 {
-			msg += "   📢 Topics:\n"
-			for _, topic := range sub.Topics {
-				if topicName, ok := topic["topicName"]; ok {
-					msg += fmt.Sprintf("      • %s\n", topicName)
-				}
-			}
-		}
-		msg += "\n"
-	}
-
 	msg += "\n**Webhook Server Status:**\n"
-	msg += "✅ Server running on port 8081\n"
-	msg += "📍 Health: https://jacob.it.com/webhook/health\n"
+	msg += "✅ Server running\n"
+	msg += fmt.Sprintf("📍 Health: %s/webhook/health\n", h.publicBaseURL())
 	msg += "📍 Notification endpoint: /webhook/ebay/notification\n"
+	if h.webhookServer != nil {
+		msg += fmt.Sprintf("🔁 Duplicate deliveries suppressed: %d\n", h.webhookServer.DuplicatesSuppressed())
+	}
 	msg += "\n💡 To create a new subscription, run `/webhook-subscribe`"
+	msg = truncateText(msg, 2000)
 
 	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: &msg,
@@ -841,17 +1047,18 @@ func (h *Handler) handleWebhookTest(s *discordgo.Session, i *discordgo.Interacti
 }
 
 func (h *Handler) handleAcceptOffer(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
-	offerID := options[0].StringValue()
+	client := h.client(i)
+	offerID := optionMap(i.ApplicationCommandData().Options)["offer-id"].StringValue()
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
 	// Call eBay API to accept the offer
-	err := h.ebay.RespondToOffer(offerID, "ACCEPT", 0)
+	err := client.RespondToOffer(offerID, "ACCEPT", 0)
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ **Failed to accept offer**\n\nError: %v\n\n**Troubleshooting:**\n• Verify offer ID is correct\n• Check if offer is still pending\n• Ensure you have authorization: `/ebay-status`\n• Offer may have expired or been withdrawn", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
@@ -866,6 +1073,7 @@ func (h *Handler) handleAcceptOffer(s *discordgo.Session, i *discordgo.Interacti
 }
 
 func (h *Handler) handleCounterOffer(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	client := h.client(i)
 	options := i.ApplicationCommandData().Options
 	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
 	for _, opt := range options {
@@ -884,6 +1092,7 @@ func (h *Handler) handleCounterOffer(s *discordgo.Session, i *discordgo.Interact
 	_, err := fmt.Sscanf(priceStr, "%f", &price)
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ Invalid price format: %s. Please enter a number (e.g., 250.00)", priceStr)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
@@ -892,6 +1101,7 @@ func (h *Handler) handleCounterOffer(s *discordgo.Session, i *discordgo.Interact
 
 	if price <= 0 {
 		errMsg := "❌ Price must be greater than $0.00"
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
@@ -899,9 +1109,10 @@ func (h *Handler) handleCounterOffer(s *discordgo.Session, i *discordgo.Interact
 	}
 
 	// Call eBay API to counter the offer
-	err = h.ebay.RespondToOffer(offerID, "COUNTER", price)
+	err = client.RespondToOffer(offerID, "COUNTER", price)
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ **Failed to counter offer**\n\nError: %v\n\n**Troubleshooting:**\n• Verify offer ID is correct\n• Check if offer is still pending\n• Ensure counter price is valid\n• Ensure you have authorization: `/ebay-status`", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
@@ -916,17 +1127,18 @@ func (h *Handler) handleCounterOffer(s *discordgo.Session, i *discordgo.Interact
 }
 
 func (h *Handler) handleDeclineOffer(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
-	offerID := options[0].StringValue()
+	client := h.client(i)
+	offerID := optionMap(i.ApplicationCommandData().Options)["offer-id"].StringValue()
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
 	// Call eBay API to decline the offer
-	err := h.ebay.RespondToOffer(offerID, "DECLINE", 0)
+	err := client.RespondToOffer(offerID, "DECLINE", 0)
 	if err != nil {
 		errMsg := fmt.Sprintf("❌ **Failed to decline offer**\n\nError: %v\n\n**Troubleshooting:**\n• Verify offer ID is correct\n• Check if offer is still pending\n• Ensure you have authorization: `/ebay-status`\n• Offer may have already been processed", err)
+		commandFailed(i)
 		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 			Content: &errMsg,
 		})
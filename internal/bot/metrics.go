@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"log"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"ebaymanager-bot/internal/metrics"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	commandInvocations = metrics.NewCounterVec("discord_commands_total",
+		"Slash command invocations by command", "command")
+	commandErrors = metrics.NewCounterVec("discord_command_errors_total",
+		"Slash commands that answered with an error, panicked or were not recognised, by command", "command")
+	commandLatency = metrics.NewHistogramVec("discord_command_duration_seconds",
+		"Time spent handling a slash command", metrics.DefaultBuckets, "command")
+)
+
+// finishCommand records a command's metrics. Deferred by interactionHandler, it also
+// recovers a panicking handler so one bad command doesn't take the bot down.
+func finishCommand(s *discordgo.Session, i *discordgo.InteractionCreate, command string, start time.Time) {
+	commandInvocations.Inc(command)
+	commandLatency.Observe(time.Since(start).Seconds(), command)
+
+	r := recover()
+	if r == nil {
+		return
+	}
+	commandErrors.Inc(command)
+	log.Printf("❌ /%s panicked: %v\n%s", command, r, debug.Stack())
+
+	content := "❌ Something went wrong handling this command - check the bot logs"
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: content, Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		// Already acknowledged: replace the deferred response instead
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+	}
+}
+
+// commandFailed counts a command that answered with an error
+func commandFailed(i *discordgo.InteractionCreate) {
+	commandErrors.Inc(i.ApplicationCommandData().Name)
+}
+
+// countReply counts a command whose reply reports an error; error replies start with ❌
+func countReply(i *discordgo.InteractionCreate, reply string) {
+	if strings.HasPrefix(reply, "❌") {
+		commandFailed(i)
+	}
+}
@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// notificationsCommand defines /notifications recent|show|replay
+var notificationsCommand = &discordgo.ApplicationCommand{
+	Name:                     "notifications",
+	Description:              "Inspect and replay notifications received from eBay",
+	DefaultMemberPermissions: &adminPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "recent",
+			Description: "List recently received notifications",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "limit",
+					Description: "Number of notifications to show (default: 10, max: 25)",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "show",
+			Description: "Show a notification's details with its raw JSON attached",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "id",
+					Description: "Notification ID from /notifications recent",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "replay",
+			Description: "Run a stored notification through processing again",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "id",
+					Description: "Notification ID from /notifications recent",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+func (h *Handler) handleNotifications(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	if h.store == nil {
+		data.Content = "⚠️ Notification history needs the local store, which is not configured"
+	} else {
+		sub := i.ApplicationCommandData().Options[0]
+		switch sub.Name {
+		case "recent":
+			limit := 10
+			if len(sub.Options) > 0 {
+				limit = int(sub.Options[0].IntValue())
+			}
+			data.Content = h.recentNotifications(limit)
+		case "show":
+			data.Content, data.Files = h.showNotification(sub.Options[0].StringValue())
+		case "replay":
+			data.Content = h.replayNotification(sub.Options[0].StringValue())
+		}
+	}
+
+	countReply(i, data.Content)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+}
+
+func (h *Handler) recentNotifications(limit int) string {
+	if limit < 1 || limit > 25 {
+		limit = 10
+	}
+	notifications, err := h.store.ListNotifications(limit)
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to load notifications: %v", err)
+	}
+	if len(notifications) == 0 {
+		return "📭 No notifications received yet"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📨 **Recent Notifications** (%d)\n\n", len(notifications))
+	for _, n := range notifications {
+		event := n.EventType
+		if event == "" {
+			event = "unknown"
+		}
+		fmt.Fprintf(&b, "• `%s` %s - %s · signature %s · <t:%d:R>\n", n.ID, event, n.Outcome, n.Signature, n.ReceivedAt.Unix())
+	}
+	b.WriteString("\n💡 Inspect with `/notifications show id:<id>`")
+	return truncateText(b.String(), 2000)
+}
+
+func (h *Handler) showNotification(id string) (string, []*discordgo.File) {
+	n, err := h.store.GetNotification(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Sprintf("❌ No notification with ID `%s` - see `/notifications recent`", id), nil
+		}
+		return fmt.Sprintf("❌ Failed to load notification: %v", err), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📨 **Notification `%s`**\n\n", n.ID)
+	fmt.Fprintf(&b, "**Event:** %s\n", n.EventType)
+	if n.NotificationID != "" {
+		fmt.Fprintf(&b, "**eBay ID:** `%s`\n", n.NotificationID)
+	}
+	fmt.Fprintf(&b, "**Received:** <t:%d:f>\n", n.ReceivedAt.Unix())
+	fmt.Fprintf(&b, "**Signature:** %s\n", n.Signature)
+	fmt.Fprintf(&b, "**Outcome:** %s\n", n.Outcome)
+	if n.Error != "" {
+		fmt.Fprintf(&b, "**Error:** %s\n", truncateText(n.Error, 300))
+	}
+	if !n.ReplayedAt.IsZero() {
+		fmt.Fprintf(&b, "**Last replayed:** <t:%d:R>\n", n.ReplayedAt.Unix())
+	}
+	if len(n.Deliveries) > 0 {
+		b.WriteString("\n**Deliveries:**\n")
+		for _, d := range n.Deliveries {
+			fmt.Fprintf(&b, "• <#%s> %s", d.ChannelID, d.Status)
+			if d.Error != "" {
+				fmt.Fprintf(&b, " - %s", truncateText(d.Error, 150))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(n.Headers) > 0 {
+		names := make([]string, 0, len(n.Headers))
+		for name := range n.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("\n**Headers:**\n```\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "%s: %s\n", name, truncateText(n.Headers[name], 200))
+		}
+		b.WriteString("```")
+	}
+
+	var files []*discordgo.File
+	switch {
+	case len(n.Payload) > 0:
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, n.Payload, "", "  "); err != nil {
+			pretty.Reset()
+			pretty.Write(n.Payload)
+		}
+		files = append(files, &discordgo.File{Name: "notification-" + n.ID + ".json", ContentType: "application/json", Reader: &pretty})
+	case n.Body != "":
+		files = append(files, &discordgo.File{Name: "notification-" + n.ID + ".txt", ContentType: "text/plain", Reader: strings.NewReader(n.Body)})
+	default:
+		b.WriteString("\n_The payload was not kept for this notification._")
+	}
+
+	return truncateText(b.String(), 2000), files
+}
+
+func (h *Handler) replayNotification(id string) string {
+	if h.webhookServer == nil {
+		return "⚠️ The webhook server is not running"
+	}
+	queued, err := h.webhookServer.ReplayNotification(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Sprintf("❌ No notification with ID `%s` - see `/notifications recent`", id)
+		}
+		return fmt.Sprintf("❌ Failed to replay `%s`: %v", id, err)
+	}
+	if queued == 0 {
+		return fmt.Sprintf("🔁 Notification `%s` replayed, but no channel matched it", id)
+	}
+	return fmt.Sprintf("🔁 Notification `%s` replayed and queued for %d channel(s)", id, queued)
+}
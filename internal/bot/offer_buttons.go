@@ -0,0 +1,238 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Custom IDs are "offer:<action>:<offerID>" for buttons and "offer-counter:<offerID>" for the
+// counter price modal. With several seller accounts the offer ID is followed by "@<account>".
+const (
+	offerButtonPrefix  = "offer:"
+	counterModalPrefix = "offer-counter:"
+	counterPriceInput  = "price"
+
+	// maxOfferButtonRows is Discord's limit on rows of components per message
+	maxOfferButtonRows = 5
+)
+
+// OfferComponents returns the Accept / Counter / Decline buttons for an offer notification.
+// account names the seller account the offer is for; empty means the default account.
+func OfferComponents(account, offerID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{offerButtonRow(account, offerID, "")}
+}
+
+// offerButtonRow builds one row of offer buttons; suffix distinguishes rows in a list (e.g. " #2")
+func offerButtonRow(account, offerID, suffix string) discordgo.ActionsRow {
+	id := offerAccountID(account, offerID)
+	return discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: "✅ Accept" + suffix, Style: discordgo.SuccessButton, CustomID: offerButtonPrefix + "accept:" + id},
+		discordgo.Button{Label: "💬 Counter" + suffix, Style: discordgo.PrimaryButton, CustomID: offerButtonPrefix + "counter:" + id},
+		discordgo.Button{Label: "❌ Decline" + suffix, Style: discordgo.DangerButton, CustomID: offerButtonPrefix + "decline:" + id},
+	}}
+}
+
+// offerAccountID joins an offer ID and its account for a custom ID
+func offerAccountID(account, offerID string) string {
+	if account == "" {
+		return offerID
+	}
+	return offerID + "@" + account
+}
+
+// splitOfferAccount splits the account off an offer ID taken from a custom ID. IDs
+// without one, including those on messages sent before accounts existed, are for the
+// default account.
+func splitOfferAccount(id string) (offerID, account string) {
+	offerID, account, _ = strings.Cut(id, "@")
+	return offerID, account
+}
+
+// parseOfferButton splits an offer button custom ID into its action, account and offer ID
+func parseOfferButton(customID string) (action, account, offerID string, ok bool) {
+	rest, found := strings.CutPrefix(customID, offerButtonPrefix)
+	if !found {
+		return "", "", "", false
+	}
+	action, id, ok := strings.Cut(rest, ":")
+	offerID, account = splitOfferAccount(id)
+	return action, account, offerID, ok && offerID != ""
+}
+
+// handleOfferButton handles a press of one of the offer buttons
+func (h *Handler) handleOfferButton(s *discordgo.Session, i *discordgo.InteractionCreate, action, account, offerID string) {
+	switch action {
+	case "accept", "decline":
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+		h.respondToOfferFromMessage(s, i, account, offerID, action, 0)
+	case "counter":
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				CustomID: counterModalPrefix + offerAccountID(account, offerID),
+				Title:    "Counter Offer",
+				Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    counterPriceInput,
+						Label:       "Counter price",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "e.g. 250.00",
+						Required:    true,
+						MaxLength:   12,
+					},
+				}}},
+			},
+		})
+		if err != nil {
+			log.Printf("❌ Failed to open counter offer modal: %v", err)
+		}
+	}
+}
+
+// handleCounterModal handles the price submitted from the Counter button's modal
+func (h *Handler) handleCounterModal(s *discordgo.Session, i *discordgo.InteractionCreate, account, offerID string) {
+	priceStr := modalValue(i.ModalSubmitData(), counterPriceInput)
+	price, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(priceStr), "$"), 64)
+	if err != nil || price <= 0 {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("❌ Invalid price: %s. Please enter a number greater than 0 (e.g., 250.00)", priceStr),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	h.respondToOfferFromMessage(s, i, account, offerID, "counter", price)
+}
+
+// respondToOfferFromMessage sends the seller's response to eBay, then edits the message the
+// buttons are on to show the result and who acted. Failures are reported only to the presser.
+func (h *Handler) respondToOfferFromMessage(s *discordgo.Session, i *discordgo.InteractionCreate, account, offerID, action string, price float64) {
+	client, err := h.accounts.Get(account)
+	if err == nil {
+		err = client.RespondToOffer(offerID, strings.ToUpper(action), price)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to %s offer %s: %v", action, offerID, err)
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: fmt.Sprintf("❌ **Failed to %s offer** `%s`\n\nError: %v\n\n• Check if the offer is still pending\n• Ensure you have authorization: `/ebay-status`", action, offerID, err),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return
+	}
+
+	result := offerResult(action, price, interactionUserID(i))
+	log.Printf("✅ Offer %s: %s", offerID, result)
+
+	if i.Message == nil {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{Content: result, Flags: discordgo.MessageFlagsEphemeral})
+		return
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, offerResultEdit(i.Message, offerID, result)); err != nil {
+		log.Printf("⚠️ Failed to update offer message: %v", err)
+	}
+}
+
+// offerResult describes a completed response, e.g. "✅ Accepted by @seller"
+func offerResult(action string, price float64, userID string) string {
+	by := ""
+	if userID != "" {
+		by = fmt.Sprintf(" by <@%s>", userID)
+	}
+	switch action {
+	case "accept":
+		return "✅ Accepted" + by
+	case "decline":
+		return "❌ Declined" + by
+	default:
+		return fmt.Sprintf("💬 Countered at $%.2f%s", price, by)
+	}
+}
+
+// offerResultEdit records result on msg and disables the buttons for offerID, leaving
+// buttons for other offers (as on /get-offers) usable
+func offerResultEdit(msg *discordgo.Message, offerID, result string) *discordgo.WebhookEdit {
+	components := disableOfferButtons(msg.Components, offerID)
+	edit := &discordgo.WebhookEdit{Components: &components}
+
+	if len(msg.Embeds) > 0 {
+		embeds := make([]*discordgo.MessageEmbed, len(msg.Embeds))
+		copy(embeds, msg.Embeds)
+		first := *embeds[0]
+		first.Fields = append(append([]*discordgo.MessageEmbedField{}, first.Fields...), &discordgo.MessageEmbedField{
+			Name:  "📝 Response",
+			Value: result,
+		})
+		embeds[0] = &first
+		edit.Embeds = &embeds
+		return edit
+	}
+
+	content := fmt.Sprintf("%s\n%s (`%s`)", msg.Content, result, offerID)
+	edit.Content = &content
+	return edit
+}
+
+// disableOfferButtons returns a copy of rows with the buttons for offerID disabled
+func disableOfferButtons(rows []discordgo.MessageComponent, offerID string) []discordgo.MessageComponent {
+	out := make([]discordgo.MessageComponent, 0, len(rows))
+	for _, c := range rows {
+		var row discordgo.ActionsRow
+		switch r := c.(type) {
+		case *discordgo.ActionsRow:
+			row = *r
+		case discordgo.ActionsRow:
+			row = r
+		default:
+			out = append(out, c)
+			continue
+		}
+
+		buttons := make([]discordgo.MessageComponent, 0, len(row.Components))
+		for _, rc := range row.Components {
+			var button discordgo.Button
+			switch b := rc.(type) {
+			case *discordgo.Button:
+				button = *b
+			case discordgo.Button:
+				button = b
+			default:
+				buttons = append(buttons, rc)
+				continue
+			}
+			if _, _, id, ok := parseOfferButton(button.CustomID); ok && id == offerID {
+				button.Disabled = true
+			}
+			buttons = append(buttons, button)
+		}
+		out = append(out, discordgo.ActionsRow{Components: buttons})
+	}
+	return out
+}
+
+// modalValue returns the value of the text input with customID in a submitted modal
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, c := range data.Components {
+		row, ok := c.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rc := range row.Components {
+			if input, ok := rc.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
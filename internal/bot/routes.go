@@ -0,0 +1,219 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// routeCommand defines /route add|list|remove
+var routeCommand = &discordgo.ApplicationCommand{
+	Name:                     "route",
+	Description:              "Route notifications to channels by event type, amount, SKU or buyer",
+	DefaultMemberPermissions: &adminPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "add",
+			Description: "Send matching notifications to a channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "Channel to post matching notifications in",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					Required:     true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "event",
+					Description: "Event type to match, e.g. OFFER, ORDER or ORDER.PAID",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "min-amount",
+					Description: "Only notifications for at least this amount (e.g. 500)",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "sku-prefix",
+					Description: "Only items whose SKU starts with this",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "buyer",
+					Description: "Only notifications from this buyer username",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Name:        "mention",
+					Description: "Role to mention with matching notifications",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List routing rules",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "remove",
+			Description: "Remove a routing rule",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "id",
+					Description: "Rule ID from /route list",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// addRouteAccountOption lets /route add match a single seller account when several are configured
+func addRouteAccountOption() {
+	for _, opt := range routeCommand.Options {
+		if opt.Name == "add" {
+			opt.Options = appendAccountOption(opt.Options, "Only notifications for this eBay seller account")
+		}
+	}
+}
+
+func (h *Handler) handleRoute(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var msg string
+	if h.store == nil {
+		msg = "⚠️ Routing rules need the local store, which is not configured"
+	} else {
+		sub := i.ApplicationCommandData().Options[0]
+		switch sub.Name {
+		case "add":
+			msg = h.addRoute(sub.Options, interactionUserID(i))
+		case "list":
+			msg = h.listRoutes()
+		case "remove":
+			msg = h.removeRoute(sub.Options[0].StringValue())
+		}
+	}
+
+	countReply(i, msg)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (h *Handler) addRoute(options []*discordgo.ApplicationCommandInteractionDataOption, userID string) string {
+	route := &store.Route{CreatedBy: userID}
+	for _, opt := range options {
+		switch opt.Name {
+		case "channel":
+			route.ChannelID = opt.ChannelValue(nil).ID
+		case "event":
+			route.EventType = strings.TrimSpace(opt.StringValue())
+		case "min-amount":
+			route.MinAmount = opt.FloatValue()
+		case "sku-prefix":
+			route.SKUPrefix = strings.TrimSpace(opt.StringValue())
+		case "buyer":
+			route.Buyer = strings.TrimSpace(opt.StringValue())
+		case "mention":
+			route.MentionRoleID = opt.RoleValue(nil, "").ID
+		case accountOptionName:
+			route.Account = strings.ToLower(strings.TrimSpace(opt.StringValue()))
+		}
+	}
+
+	// A rule for an account that isn't configured would never match
+	if route.Account != "" {
+		client, err := h.accounts.Get(route.Account)
+		if err != nil {
+			return fmt.Sprintf("❌ %v", err)
+		}
+		route.Account = client.Account()
+	}
+
+	if err := h.store.SaveRoute(route); err != nil {
+		return fmt.Sprintf("❌ Failed to save route: %v", err)
+	}
+	return fmt.Sprintf("✅ Route `%s` added: %s → <#%s>%s", route.ID, describeRoute(*route), route.ChannelID, mentionSuffix(*route))
+}
+
+func (h *Handler) listRoutes() string {
+	routes, err := h.store.ListRoutes()
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to load routes: %v", err)
+	}
+	if len(routes) == 0 {
+		return "📭 No routing rules - every notification goes to the default notification channel\n\n💡 Add one with `/route add`"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🧭 **Notification Routes** (%d)\n\n", len(routes))
+	for _, r := range routes {
+		fmt.Fprintf(&b, "• `%s` %s → <#%s>%s\n", r.ID, describeRoute(r), r.ChannelID, mentionSuffix(r))
+	}
+	b.WriteString("\nA notification is posted to every channel with a matching rule; if none match it goes to the default channel.")
+	return b.String()
+}
+
+func (h *Handler) removeRoute(id string) string {
+	if err := h.store.DeleteRoute(id); err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Sprintf("❌ No route with ID `%s` - see `/route list`", id)
+		}
+		return fmt.Sprintf("❌ Failed to remove route: %v", err)
+	}
+	return fmt.Sprintf("🗑️ Route `%s` removed", id)
+}
+
+// describeRoute renders a rule's criteria
+func describeRoute(route store.Route) string {
+	var parts []string
+	if route.EventType != "" {
+		parts = append(parts, "event ~ "+strings.ToUpper(route.EventType))
+	}
+	if route.MinAmount > 0 {
+		parts = append(parts, fmt.Sprintf("amount ≥ $%.2f", route.MinAmount))
+	}
+	if route.SKUPrefix != "" {
+		parts = append(parts, "SKU starts with "+route.SKUPrefix)
+	}
+	if route.Buyer != "" {
+		parts = append(parts, "buyer "+route.Buyer)
+	}
+	if route.Account != "" {
+		parts = append(parts, "account "+route.Account)
+	}
+	if len(parts) == 0 {
+		return "all notifications"
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func mentionSuffix(route store.Route) string {
+	if route.MentionRoleID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (mentions <@&%s>)", route.MentionRoleID)
+}
+
+// interactionUserID returns the ID of the user who ran a command in a guild or DM
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// simulateCommand defines /webhook-simulate, which sends a synthetic notification
+// through the webhook endpoint to test routing and formatting without eBay
+var simulateCommand = &discordgo.ApplicationCommand{
+	Name:                     "webhook-simulate",
+	Description:              "Send a synthetic eBay notification through the webhook endpoint",
+	DefaultMemberPermissions: &adminPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "event",
+			Description: "Notification to simulate",
+			Required:    true,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "New order", Value: "order-placed"},
+				{Name: "Payment received", Value: "order-paid"},
+				{Name: "Order shipped", Value: "order-shipped"},
+				{Name: "Offer created", Value: "offer-created"},
+				{Name: "Offer countered", Value: "offer-countered"},
+				{Name: "Offer expired", Value: "offer-expired"},
+				{Name: "Listing ended", Value: "listing-ended"},
+				{Name: "Account deletion", Value: "account-deletion"},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "signed",
+			Description: "Sign it like eBay does (default: true)",
+		},
+	},
+}
+
+func (h *Handler) handleWebhookSimulate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	event, signed := "", true
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "event":
+			event = opt.StringValue()
+		case "signed":
+			signed = opt.BoolValue()
+		}
+	}
+
+	// Order notifications look the order up on eBay, so answer after processing
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	content := h.simulateNotification(event, h.client(i).Account(), signed)
+	countReply(i, content)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+}
+
+func (h *Handler) simulateNotification(event, account string, signed bool) string {
+	if h.webhookServer == nil {
+		return "⚠️ The webhook server is not running"
+	}
+	signature := "unsigned"
+	if signed {
+		signature = "signed"
+	}
+
+	status, err := h.webhookServer.SimulateNotification(event, account, signed)
+	if err != nil {
+		return fmt.Sprintf("❌ Simulated `%s` (%s) was not accepted: %v", event, signature, err)
+	}
+	return fmt.Sprintf("🧪 Simulated `%s` (%s): the endpoint answered %d\n\n💡 See where it went with `/notifications recent`", event, signature, status)
+}
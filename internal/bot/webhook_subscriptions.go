@@ -0,0 +1,503 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// webhookTopicsCommand defines /webhook-topics
+var webhookTopicsCommand = &discordgo.ApplicationCommand{
+	Name:        "webhook-topics",
+	Description: "List the eBay notification topics that can be subscribed to",
+}
+
+// subscriptionIDOption is the required subscription ID taken by most /webhook-subscription subcommands
+var subscriptionIDOption = &discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionString,
+	Name:        "id",
+	Description: "Subscription ID from /webhook-subscription list",
+	Required:    true,
+}
+
+// webhookSubscriptionCommand defines /webhook-subscription for managing Notification API
+// subscriptions, their filters and destinations
+var webhookSubscriptionCommand = &discordgo.ApplicationCommand{
+	Name:                     "webhook-subscription",
+	Description:              "Manage eBay notification subscriptions, filters and destinations",
+	DefaultMemberPermissions: &adminPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List destinations and their subscriptions",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "reconcile",
+			Description: "Fix subscriptions that differ from WEBHOOK_PUBLIC_URL and WEBHOOK_TOPICS",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "dry-run",
+					Description: "Only report the differences (default: false)",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "create",
+			Description: "Subscribe a destination to a topic",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "topic",
+					Description: "Topic ID from /webhook-topics, e.g. MARKETPLACE_OFFER",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "url",
+					Description: "Endpoint to deliver to (default: the only existing destination)",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "enable",
+			Description: "Resume delivery for a subscription",
+			Options:     []*discordgo.ApplicationCommandOption{subscriptionIDOption},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "disable",
+			Description: "Pause delivery without deleting the subscription",
+			Options:     []*discordgo.ApplicationCommandOption{subscriptionIDOption},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "test",
+			Description: "Ask eBay to send a test notification for a subscription",
+			Options:     []*discordgo.ApplicationCommandOption{subscriptionIDOption},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "delete",
+			Description: "Delete a subscription",
+			Options:     []*discordgo.ApplicationCommandOption{subscriptionIDOption},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "filter",
+			Description: "Manage a subscription's filter",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "set",
+					Description: "Only deliver notifications matching a JSON Schema (filterable topics only)",
+					Options: []*discordgo.ApplicationCommandOption{
+						subscriptionIDOption,
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "schema",
+							Description: `JSON Schema, e.g. {"properties":{"data":{"properties":{"eventType":{"enum":["CREATED"]}}}}}`,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "show",
+					Description: "Show a subscription's filter and its status",
+					Options:     []*discordgo.ApplicationCommandOption{subscriptionIDOption},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "delete",
+					Description: "Remove a subscription's filter",
+					Options:     []*discordgo.ApplicationCommandOption{subscriptionIDOption},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "destination",
+			Description: "Manage notification destinations",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "update",
+					Description: "Change a destination's endpoint or status",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "id",
+							Description: "Destination ID from /webhook-subscription list",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "url",
+							Description: "New endpoint URL",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "status",
+							Description: "Enable or disable delivery to the destination",
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Enabled", Value: ebay.StatusEnabled},
+								{Name: "Disabled", Value: ebay.StatusDisabled},
+							},
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "delete",
+					Description: "Delete a destination that no subscription uses",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "id",
+							Description: "Destination ID from /webhook-subscription list",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func (h *Handler) handleWebhookTopics(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+
+	content := h.webhookTopics(h.client(i))
+	countReply(i, content)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+}
+
+func (h *Handler) webhookTopics(client *ebay.Client) string {
+	topics, err := client.GetNotificationTopics()
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to list topics: %v", err)
+	}
+	if len(topics) == 0 {
+		return "📭 eBay returned no notification topics"
+	}
+
+	// Subscriptions need the seller's token; without it topics are still listed
+	subscribed := make(map[string]string)
+	if subs, err := client.GetSubscriptions(); err == nil {
+		for _, sub := range subs {
+			subscribed[sub.TopicID] = sub.Status
+		}
+	}
+
+	sort.Slice(topics, func(a, b int) bool { return topics[a].TopicID < topics[b].TopicID })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📢 **Notification Topics** (%d)\n\n", len(topics))
+	for _, t := range topics {
+		marker := "•"
+		if status, ok := subscribed[t.TopicID]; ok {
+			marker = "✅"
+			if status != ebay.StatusEnabled {
+				marker = "⏸️"
+			}
+		}
+		fmt.Fprintf(&b, "%s **%s** - %s scope", marker, t.TopicID, strings.ToLower(t.Scope))
+		if t.Filterable {
+			b.WriteString(", filterable")
+		}
+		if t.Status != "" && t.Status != ebay.StatusEnabled {
+			fmt.Fprintf(&b, ", %s", strings.ToLower(t.Status))
+		}
+		b.WriteString("\n")
+		if t.Description != "" {
+			fmt.Fprintf(&b, "   %s\n", truncateText(t.Description, 120))
+		}
+		if len(t.AuthorizationScopes) > 0 {
+			scopes := make([]string, len(t.AuthorizationScopes))
+			for n, scope := range t.AuthorizationScopes {
+				scopes[n] = "`" + strings.TrimPrefix(scope, "https://api.ebay.com/oauth/api_scope/") + "`"
+			}
+			fmt.Fprintf(&b, "   🔑 %s\n", strings.Join(scopes, ", "))
+		}
+	}
+	b.WriteString("\n✅ subscribed · ⏸️ disabled\n💡 Subscribe with `/webhook-subscription create topic:<topic>`")
+	return truncateText(b.String(), 2000)
+}
+
+func (h *Handler) handleWebhookSubscription(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	client := h.client(i)
+	sub := i.ApplicationCommandData().Options[0]
+	var content string
+	switch sub.Type {
+	case discordgo.ApplicationCommandOptionSubCommandGroup:
+		action := sub.Options[0]
+		opts := optionMap(action.Options)
+		switch sub.Name + " " + action.Name {
+		case "filter set":
+			content = h.setSubscriptionFilter(client, opts["id"].StringValue(), opts["schema"].StringValue())
+		case "filter show":
+			content = h.showSubscriptionFilter(client, opts["id"].StringValue())
+		case "filter delete":
+			content = h.deleteSubscriptionFilter(client, opts["id"].StringValue())
+		case "destination update":
+			content = h.updateDestination(client, opts)
+		case "destination delete":
+			id := opts["id"].StringValue()
+			content = subscriptionResult(client.DeleteDestination(id), fmt.Sprintf("🗑️ Destination `%s` deleted", id))
+		}
+	default:
+		opts := optionMap(sub.Options)
+		switch sub.Name {
+		case "list":
+			msg, err := h.subscriptionSummary(client)
+			content = subscriptionResult(err, h.accountHeading(client)+msg)
+		case "reconcile":
+			dryRun := false
+			if opt, ok := opts["dry-run"]; ok {
+				dryRun = opt.BoolValue()
+			}
+			content = h.reconcileSubscriptions(client, dryRun)
+		case "create":
+			url := ""
+			if opt, ok := opts["url"]; ok {
+				url = opt.StringValue()
+			}
+			content = h.createSubscription(client, strings.ToUpper(opts["topic"].StringValue()), url)
+		case "enable":
+			id := opts["id"].StringValue()
+			content = subscriptionResult(client.EnableSubscription(id), fmt.Sprintf("▶️ Subscription `%s` enabled", id))
+		case "disable":
+			id := opts["id"].StringValue()
+			content = subscriptionResult(client.DisableSubscription(id), fmt.Sprintf("⏸️ Subscription `%s` disabled", id))
+		case "test":
+			id := opts["id"].StringValue()
+			content = subscriptionResult(client.TestSubscription(id), fmt.Sprintf("🧪 eBay is sending a test notification for `%s` - it should appear in the notification channel shortly", id))
+		case "delete":
+			id := opts["id"].StringValue()
+			content = subscriptionResult(client.DeleteSubscription(id), fmt.Sprintf("🗑️ Subscription `%s` deleted", id))
+		}
+	}
+
+	content = truncateText(content, 2000)
+	countReply(i, content)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+}
+
+// optionMap indexes command options by name
+func optionMap(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	m := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		m[opt.Name] = opt
+	}
+	return m
+}
+
+// subscriptionResult returns success, or the error with a hint when the call failed
+func subscriptionResult(err error, success string) string {
+	if err != nil {
+		log.Printf("❌ Notification API call failed: %v", err)
+		return fmt.Sprintf("❌ %v\n\n💡 Check IDs with `/webhook-subscription list` and authorization with `/ebay-status`", err)
+	}
+	return success
+}
+
+// subscriptionSummary lists each destination with the subscriptions delivering to it
+func (h *Handler) subscriptionSummary(client *ebay.Client) (string, error) {
+	destinations, err := client.GetDestinations()
+	if err != nil {
+		return "", err
+	}
+	subs, err := client.GetSubscriptions()
+	if err != nil {
+		return "", err
+	}
+	if len(destinations) == 0 && len(subs) == 0 {
+		return "📋 **Webhook Subscriptions**\n\n📭 No destinations or subscriptions found.\n\nRun `/webhook-subscribe` to subscribe the bot's endpoint to orders, offers and inventory updates, or see `/webhook-topics` for everything available.\n", nil
+	}
+
+	byDestination := make(map[string][]ebay.NotificationSubscription)
+	for _, sub := range subs {
+		byDestination[sub.DestinationID] = append(byDestination[sub.DestinationID], sub)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📋 **Webhook Subscriptions** (%d across %d destinations)\n\n", len(subs), len(destinations))
+	writeSubs := func(subs []ebay.NotificationSubscription) {
+		for _, sub := range subs {
+			fmt.Fprintf(&b, "   %s **%s** `%s` (schema %s)", statusIcon(sub.Status), sub.TopicID, sub.SubscriptionID, sub.Payload.SchemaVersion)
+			if sub.FilterID != "" {
+				b.WriteString(" 🔍 filtered")
+			}
+			b.WriteString("\n")
+		}
+	}
+	for _, dest := range destinations {
+		fmt.Fprintf(&b, "%s **%s** `%s`\n   📍 `%s`\n", statusIcon(dest.Status), dest.Name, dest.DestinationID, dest.DeliveryConfig.Endpoint)
+		writeSubs(byDestination[dest.DestinationID])
+		delete(byDestination, dest.DestinationID)
+		b.WriteString("\n")
+	}
+	for destID, orphaned := range byDestination {
+		fmt.Fprintf(&b, "⚠️ Unknown destination `%s`\n", destID)
+		writeSubs(orphaned)
+	}
+	return b.String(), nil
+}
+
+func statusIcon(status string) string {
+	if status == ebay.StatusEnabled {
+		return "✅"
+	}
+	return "⏸️"
+}
+
+// createSubscription subscribes the destination for url (or the only destination) to topic
+func (h *Handler) createSubscription(client *ebay.Client, topicID, url string) string {
+	var destinationID string
+	if url != "" {
+		dest, err := client.EnsureDestination(url)
+		if err != nil {
+			return subscriptionResult(err, "")
+		}
+		destinationID = dest.DestinationID
+	} else {
+		destinations, err := client.GetDestinations()
+		if err != nil {
+			return subscriptionResult(err, "")
+		}
+		if len(destinations) != 1 {
+			return fmt.Sprintf("❌ There are %d destinations - pass `url:` to choose one (see `/webhook-subscription list`)", len(destinations))
+		}
+		destinationID = destinations[0].DestinationID
+	}
+
+	sub, err := client.SubscribeTopic(topicID, destinationID)
+	if err != nil {
+		return subscriptionResult(err, "")
+	}
+	return fmt.Sprintf("✅ Subscribed destination `%s` to **%s** (subscription `%s`, schema %s)\n\n💡 Send a test with `/webhook-subscription test id:%s`", destinationID, topicID, sub.SubscriptionID, sub.Payload.SchemaVersion, sub.SubscriptionID)
+}
+
+func (h *Handler) setSubscriptionFilter(client *ebay.Client, subscriptionID, schema string) string {
+	if !json.Valid([]byte(schema)) {
+		return "❌ The filter schema must be valid JSON"
+	}
+	sub, err := client.GetSubscription(subscriptionID)
+	if err != nil {
+		return subscriptionResult(err, "")
+	}
+	// A subscription has at most one filter, so replace the current one
+	if sub.FilterID != "" {
+		if err := client.DeleteSubscriptionFilter(subscriptionID, sub.FilterID); err != nil {
+			return subscriptionResult(err, "")
+		}
+	}
+	filterID, err := client.CreateSubscriptionFilter(subscriptionID, json.RawMessage(schema))
+	if err != nil {
+		return subscriptionResult(err, "")
+	}
+	return fmt.Sprintf("🔍 Filter `%s` added to `%s`. eBay validates it first - check `/webhook-subscription filter show id:%s` until it is ENABLED", filterID, subscriptionID, subscriptionID)
+}
+
+func (h *Handler) showSubscriptionFilter(client *ebay.Client, subscriptionID string) string {
+	sub, err := client.GetSubscription(subscriptionID)
+	if err != nil {
+		return subscriptionResult(err, "")
+	}
+	if sub.FilterID == "" {
+		return fmt.Sprintf("📭 Subscription `%s` (%s) has no filter - every notification is delivered", subscriptionID, sub.TopicID)
+	}
+	filter, err := client.GetSubscriptionFilter(subscriptionID, sub.FilterID)
+	if err != nil {
+		return subscriptionResult(err, "")
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, filter.FilterSchema, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(filter.FilterSchema)
+	}
+	return fmt.Sprintf("🔍 **Filter `%s`** on `%s` (%s)\n**Status:** %s\n```json\n%s\n```", filter.FilterID, subscriptionID, sub.TopicID, filter.FilterStatus, truncateText(pretty.String(), 1700))
+}
+
+func (h *Handler) deleteSubscriptionFilter(client *ebay.Client, subscriptionID string) string {
+	sub, err := client.GetSubscription(subscriptionID)
+	if err != nil {
+		return subscriptionResult(err, "")
+	}
+	if sub.FilterID == "" {
+		return fmt.Sprintf("📭 Subscription `%s` has no filter", subscriptionID)
+	}
+	return subscriptionResult(client.DeleteSubscriptionFilter(subscriptionID, sub.FilterID), fmt.Sprintf("🗑️ Filter removed from `%s` - every notification is delivered again", subscriptionID))
+}
+
+func (h *Handler) updateDestination(client *ebay.Client, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	id := opts["id"].StringValue()
+	destinations, err := client.GetDestinations()
+	if err != nil {
+		return subscriptionResult(err, "")
+	}
+
+	var dest *ebay.Destination
+	for n := range destinations {
+		if destinations[n].DestinationID == id {
+			dest = &destinations[n]
+		}
+	}
+	if dest == nil {
+		return fmt.Sprintf("❌ No destination with ID `%s` - see `/webhook-subscription list`", id)
+	}
+
+	if opt, ok := opts["url"]; ok {
+		dest.DeliveryConfig.Endpoint = opt.StringValue()
+	}
+	if opt, ok := opts["status"]; ok {
+		dest.Status = opt.StringValue()
+	}
+	// eBay doesn't return the verification token, and challenges the endpoint with it again
+	dest.DeliveryConfig.VerificationToken = client.WebhookVerifyToken()
+
+	return subscriptionResult(client.UpdateDestination(*dest), fmt.Sprintf("✏️ Destination `%s` now delivers to `%s` (%s)", id, dest.DeliveryConfig.Endpoint, dest.Status))
+}
+
+// reconcileSubscriptions converges client's eBay subscriptions on the configured endpoint and topics
+func (h *Handler) reconcileSubscriptions(client *ebay.Client, dryRun bool) string {
+	if h.webhookServer == nil {
+		return "❌ Webhook server not configured"
+	}
+	summary, err := h.webhookServer.ReconcileSubscriptions(client.Account(), dryRun)
+	if err != nil {
+		log.Printf("❌ Subscription reconciliation failed: %v", err)
+		return fmt.Sprintf("❌ Reconciliation failed: %v\n\n💡 Make sure you're authorized with `/ebay-authorize`, or pass `url:` to subscribe an endpoint directly", err)
+	}
+	return summary
+}
+
+// publicBaseURL returns the webhook server's public scheme and host for help text
+func (h *Handler) publicBaseURL() string {
+	if h.webhookServer != nil {
+		if base := h.webhookServer.PublicBaseURL(); base != "" {
+			return base
+		}
+	}
+	return "https://yourdomain.com"
+}
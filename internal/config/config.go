@@ -3,15 +3,44 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 )
 
+// DefaultAccount names the seller account when EBAY_ACCOUNTS isn't set
+const DefaultAccount = "default"
+
+// accountName is what EBAY_ACCOUNTS entries may look like
+var accountName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
 // Config holds all application configuration
 type Config struct {
 	DiscordToken          string
-	EbayConfig            EbayConfig
+	EbayConfig            EbayConfig      // the default seller account, Accounts[0]
+	Accounts              []AccountConfig // every seller account; never empty
 	WebhookPort           string
 	WebhookVerifyToken    string
+	WebhookStrictSigs     bool   // reject notifications without a valid X-EBAY-SIGNATURE
+	WebhookTLSCert        string // serve HTTPS directly when both cert and key are set
+	WebhookTLSKey         string
+	WebhookSimulatorKey   string   // PEM key shared with the simulator CLI; development only
+	WebhookPublicURL      string   // notification endpoint registered with eBay; enables reconciliation
+	WebhookTopics         []string // Notification API topics to subscribe; empty means the defaults
 	NotificationChannelID string
+	AdminChannelID        string        // operational reports such as subscription drift
+	DataPath              string        // file backing the local store
+	TokenStorePath        string        // file the seller's tokens are kept in
+	TokenStorePassphrase  string        // encrypts the token file; or use TokenStoreKeyFile
+	TokenStoreKeyFile     string        // 32-byte key encrypting the token file
+	SyncInterval          time.Duration // background order/listing sync; 0 disables
+	PollInterval          time.Duration // polling fallback for notifications; 0 disables
+	DiscordEvents         []string      // event types posted to Discord; empty means all
+	EventWebhookURL       string        // generic HTTP sink; disabled when empty
+	EventWebhookSecret    string        // signs HTTP sink deliveries
+	EventWebhookEvents    []string      // event types sent to the HTTP sink; empty means all
+	SlackWebhookURL       string        // Slack incoming-webhook sink; disabled when empty
+	SlackEvents           []string      // event types sent to Slack; empty means all
 }
 
 // EbayConfig holds eBay API configuration
@@ -25,6 +54,13 @@ type EbayConfig struct {
 	Environment        string // PRODUCTION or SANDBOX
 	WebhookVerifyToken string // must be 32-80 chars for eBay Notification API
 	SellerUsername     string // optional override; auto-detected via Identity API if blank
+	Account            string // seller account name, see EBAY_ACCOUNTS
+}
+
+// AccountConfig is one eBay seller account the bot manages
+type AccountConfig struct {
+	EbayConfig
+	ChannelID string // where the account's notifications go; empty means NotificationChannelID
 }
 
 // Load reads configuration from environment variables
@@ -54,21 +90,165 @@ func Load() (*Config, error) {
 		webhookVerifyToken = "default_verify_token_change_me"
 	}
 
+	dataPath := os.Getenv("DATA_PATH")
+	if dataPath == "" {
+		dataPath = "data/ebaymanager.json"
+	}
+
+	tokenStorePath := os.Getenv("TOKEN_STORE_PATH")
+	if tokenStorePath == "" {
+		tokenStorePath = "data/tokens.json"
+	}
+	tokenPassphrase := os.Getenv("TOKEN_STORE_PASSPHRASE")
+	tokenKeyFile := os.Getenv("TOKEN_STORE_KEY_FILE")
+	if tokenPassphrase != "" && tokenKeyFile != "" {
+		return nil, fmt.Errorf("set TOKEN_STORE_PASSPHRASE or TOKEN_STORE_KEY_FILE, not both")
+	}
+
+	syncInterval, err := durationEnv("SYNC_INTERVAL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	pollInterval, err := durationEnv("POLL_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationChannelID := os.Getenv("NOTIFICATION_CHANNEL_ID")
+	adminChannelID := os.Getenv("ADMIN_CHANNEL_ID")
+	if adminChannelID == "" {
+		adminChannelID = notificationChannelID
+	}
+
+	tlsCert := os.Getenv("WEBHOOK_TLS_CERT")
+	tlsKey := os.Getenv("WEBHOOK_TLS_KEY")
+	if (tlsCert == "") != (tlsKey == "") {
+		return nil, fmt.Errorf("WEBHOOK_TLS_CERT and WEBHOOK_TLS_KEY must be set together")
+	}
+
+	eventWebhookURL := os.Getenv("EVENT_WEBHOOK_URL")
+	eventWebhookSecret := os.Getenv("EVENT_WEBHOOK_SECRET")
+	if eventWebhookURL != "" && eventWebhookSecret == "" {
+		return nil, fmt.Errorf("EVENT_WEBHOOK_SECRET must be set when EVENT_WEBHOOK_URL is")
+	}
+
+	accounts, err := loadAccounts(EbayConfig{
+		AppID:              ebayAppID,
+		CertID:             os.Getenv("EBAY_CERT_ID"),
+		DevID:              os.Getenv("EBAY_DEV_ID"),
+		RedirectURI:        os.Getenv("EBAY_REDIRECT_URI"),
+		AccessToken:        os.Getenv("EBAY_ACCESS_TOKEN"),
+		RefreshToken:       os.Getenv("EBAY_REFRESH_TOKEN"),
+		Environment:        ebayEnvironment,
+		WebhookVerifyToken: webhookVerifyToken,
+		SellerUsername:     os.Getenv("EBAY_SELLER_USERNAME"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		DiscordToken: discordToken,
-		EbayConfig: EbayConfig{
-			AppID:              ebayAppID,
-			CertID:             os.Getenv("EBAY_CERT_ID"),
-			DevID:              os.Getenv("EBAY_DEV_ID"),
-			RedirectURI:        os.Getenv("EBAY_REDIRECT_URI"),
-			AccessToken:        os.Getenv("EBAY_ACCESS_TOKEN"),
-			RefreshToken:       os.Getenv("EBAY_REFRESH_TOKEN"),
-			Environment:        ebayEnvironment,
-			WebhookVerifyToken: webhookVerifyToken,
-			SellerUsername:     os.Getenv("EBAY_SELLER_USERNAME"),
-		},
+		DiscordToken:          discordToken,
+		EbayConfig:            accounts[0].EbayConfig,
+		Accounts:              accounts,
 		WebhookPort:           webhookPort,
 		WebhookVerifyToken:    webhookVerifyToken,
-		NotificationChannelID: os.Getenv("NOTIFICATION_CHANNEL_ID"),
+		WebhookStrictSigs:     os.Getenv("WEBHOOK_STRICT_SIGNATURES") == "true",
+		WebhookTLSCert:        tlsCert,
+		WebhookTLSKey:         tlsKey,
+		WebhookSimulatorKey:   os.Getenv("WEBHOOK_SIMULATOR_KEY"),
+		WebhookPublicURL:      os.Getenv("WEBHOOK_PUBLIC_URL"),
+		WebhookTopics:         listEnv("WEBHOOK_TOPICS"),
+		NotificationChannelID: notificationChannelID,
+		AdminChannelID:        adminChannelID,
+		DataPath:              dataPath,
+		TokenStorePath:        tokenStorePath,
+		TokenStorePassphrase:  tokenPassphrase,
+		TokenStoreKeyFile:     tokenKeyFile,
+		SyncInterval:          syncInterval,
+		PollInterval:          pollInterval,
+		DiscordEvents:         listEnv("DISCORD_EVENTS"),
+		EventWebhookURL:       eventWebhookURL,
+		EventWebhookSecret:    eventWebhookSecret,
+		EventWebhookEvents:    listEnv("EVENT_WEBHOOK_EVENTS"),
+		SlackWebhookURL:       os.Getenv("SLACK_WEBHOOK_URL"),
+		SlackEvents:           listEnv("SLACK_EVENTS"),
 	}, nil
 }
+
+// loadAccounts builds the seller accounts named in EBAY_ACCOUNTS from the shared app
+// credentials in base. Each account may override its environment, seller username and
+// notification channel with EBAY_<NAME>_ENVIRONMENT, EBAY_<NAME>_SELLER_USERNAME and
+// EBAY_<NAME>_CHANNEL_ID. Tokens from .env only seed the first account; the others are
+// connected with /ebay-authorize.
+func loadAccounts(base EbayConfig) ([]AccountConfig, error) {
+	names := listEnv("EBAY_ACCOUNTS")
+	if len(names) == 0 {
+		names = []string{DefaultAccount}
+	}
+
+	accounts := make([]AccountConfig, 0, len(names))
+	prefixes := make(map[string]string) // account names by variable prefix
+	for n, name := range names {
+		name = strings.ToLower(name)
+		if !accountName.MatchString(name) {
+			return nil, fmt.Errorf("invalid account name %q in EBAY_ACCOUNTS (use up to 32 letters, digits, - and _)", name)
+		}
+		prefix := AccountEnvPrefix(name)
+		if other, ok := prefixes[prefix]; ok {
+			if other == name {
+				return nil, fmt.Errorf("account %q is listed twice in EBAY_ACCOUNTS", name)
+			}
+			return nil, fmt.Errorf("accounts %q and %q in EBAY_ACCOUNTS would both be configured by %s* variables", other, name, prefix)
+		}
+		prefixes[prefix] = name
+
+		account := AccountConfig{EbayConfig: base}
+		account.Account = name
+		if n > 0 {
+			account.AccessToken = ""
+			account.RefreshToken = ""
+			account.SellerUsername = ""
+		}
+
+		if env := os.Getenv(prefix + "ENVIRONMENT"); env != "" {
+			account.Environment = strings.ToUpper(env)
+		}
+		if username := os.Getenv(prefix + "SELLER_USERNAME"); username != "" {
+			account.SellerUsername = username
+		}
+		account.ChannelID = os.Getenv(prefix + "CHANNEL_ID")
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// AccountEnvPrefix returns the prefix of an account's own variables, e.g. EBAY_VINTAGE_
+func AccountEnvPrefix(name string) string {
+	return "EBAY_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+// durationEnv parses a Go duration from the environment, returning def when unset
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q (use a duration like 15m, or 0 to disable)", key, v)
+	}
+	return d, nil
+}
+
+// listEnv splits a comma-separated environment variable, dropping empty entries
+func listEnv(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
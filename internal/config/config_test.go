@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -103,6 +104,8 @@ func TestDefaultValues(t *testing.T) {
 	os.Setenv("EBAY_ENVIRONMENT", "SANDBOX")
 	os.Unsetenv("WEBHOOK_PORT")
 	os.Unsetenv("NOTIFICATION_CHANNEL_ID")
+	os.Unsetenv("DATA_PATH")
+	os.Unsetenv("SYNC_INTERVAL")
 
 	cfg, err := Load()
 	if err != nil {
@@ -112,4 +115,102 @@ func TestDefaultValues(t *testing.T) {
 	if cfg.WebhookPort != "8081" {
 		t.Errorf("Expected default webhook port 8081, got %s", cfg.WebhookPort)
 	}
+
+	if cfg.DataPath != "data/ebaymanager.json" {
+		t.Errorf("Expected default data path data/ebaymanager.json, got %s", cfg.DataPath)
+	}
+
+	if cfg.SyncInterval != 15*time.Minute {
+		t.Errorf("Expected default sync interval 15m, got %s", cfg.SyncInterval)
+	}
+}
+
+func TestInvalidSyncInterval(t *testing.T) {
+	os.Setenv("DISCORD_BOT_TOKEN", "test_token")
+	os.Setenv("EBAY_APP_ID", "test_app_id")
+	os.Setenv("SYNC_INTERVAL", "often")
+	defer os.Unsetenv("SYNC_INTERVAL")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid SYNC_INTERVAL")
+	}
+}
+
+func TestTLSRequiresCertAndKey(t *testing.T) {
+	os.Setenv("DISCORD_BOT_TOKEN", "test_token")
+	os.Setenv("EBAY_APP_ID", "test_app_id")
+	os.Setenv("WEBHOOK_TLS_CERT", "/etc/ssl/bot.crt")
+	defer os.Unsetenv("WEBHOOK_TLS_CERT")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected error when WEBHOOK_TLS_KEY is missing")
+	}
+}
+
+func TestEventWebhookRequiresSecret(t *testing.T) {
+	os.Setenv("DISCORD_BOT_TOKEN", "test_token")
+	os.Setenv("EBAY_APP_ID", "test_app_id")
+	os.Setenv("EVENT_WEBHOOK_URL", "https://example.com/hooks/ebay")
+	defer os.Unsetenv("EVENT_WEBHOOK_URL")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected error when EVENT_WEBHOOK_SECRET is missing")
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	os.Setenv("DISCORD_BOT_TOKEN", "test_token")
+	os.Setenv("EBAY_APP_ID", "test_app_id")
+	os.Setenv("EBAY_ENVIRONMENT", "PRODUCTION")
+	os.Setenv("EBAY_REFRESH_TOKEN", "env_refresh")
+	os.Setenv("EBAY_ACCOUNTS", "Main, vintage-finds")
+	os.Setenv("EBAY_VINTAGE_FINDS_ENVIRONMENT", "sandbox")
+	os.Setenv("EBAY_VINTAGE_FINDS_CHANNEL_ID", "222")
+	defer func() {
+		for _, key := range []string{"EBAY_ENVIRONMENT", "EBAY_REFRESH_TOKEN", "EBAY_ACCOUNTS",
+			"EBAY_VINTAGE_FINDS_ENVIRONMENT", "EBAY_VINTAGE_FINDS_CHANNEL_ID"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Accounts) != 2 {
+		t.Fatalf("Expected 2 accounts, got %d", len(cfg.Accounts))
+	}
+
+	first, vintage := cfg.Accounts[0], cfg.Accounts[1]
+	if first.Account != "main" || first.RefreshToken != "env_refresh" || first.Environment != "PRODUCTION" {
+		t.Errorf("Unexpected default account %+v", first)
+	}
+	if cfg.EbayConfig.Account != "main" {
+		t.Errorf("Expected EbayConfig to be the default account, got %q", cfg.EbayConfig.Account)
+	}
+	if vintage.Account != "vintage-finds" || vintage.RefreshToken != "" || vintage.Environment != "SANDBOX" || vintage.ChannelID != "222" {
+		t.Errorf("Unexpected second account %+v", vintage)
+	}
+	if vintage.AppID != "test_app_id" {
+		t.Errorf("Expected accounts to share the app credentials, got %q", vintage.AppID)
+	}
+}
+
+func TestInvalidAccounts(t *testing.T) {
+	os.Setenv("DISCORD_BOT_TOKEN", "test_token")
+	os.Setenv("EBAY_APP_ID", "test_app_id")
+	defer os.Unsetenv("EBAY_ACCOUNTS")
+
+	for _, accounts := range []string{"main,Main", "main,my shop", "-main", "vintage-finds,vintage_finds"} {
+		os.Setenv("EBAY_ACCOUNTS", accounts)
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected EBAY_ACCOUNTS=%q to be rejected", accounts)
+		}
+	}
+
+	os.Unsetenv("EBAY_ACCOUNTS")
+	cfg, err := Load()
+	if err != nil || len(cfg.Accounts) != 1 || cfg.Accounts[0].Account != DefaultAccount {
+		t.Errorf("Expected a single %q account without EBAY_ACCOUNTS, got %+v, %v", DefaultAccount, cfg, err)
+	}
 }
@@ -0,0 +1,208 @@
+package ebay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"ebaymanager-bot/internal/config"
+)
+
+// DefaultAccount names the seller account when only one is configured
+const DefaultAccount = config.DefaultAccount
+
+// ErrUnknownAccount is returned for an account name that isn't configured
+var ErrUnknownAccount = errors.New("unknown eBay account")
+
+// Accounts are the seller accounts the bot manages, in configuration order. The first
+// account is the default, used when a command or notification doesn't name one.
+type Accounts struct {
+	names   []string
+	clients map[string]*Client
+
+	environments []string           // environments the accounts use, in configuration order
+	appClients   map[string]*Client // by environment: the app's credentials without a seller's tokens
+}
+
+// NewAccounts registers clients by their account names
+func NewAccounts(clients ...*Client) (*Accounts, error) {
+	accounts := &Accounts{clients: make(map[string]*Client), appClients: make(map[string]*Client)}
+	for _, client := range clients {
+		if err := accounts.Add(client); err != nil {
+			return nil, err
+		}
+	}
+	return accounts, nil
+}
+
+// Add registers client under its account name
+func (a *Accounts) Add(client *Client) error {
+	name := client.Account()
+	if _, ok := a.clients[name]; ok {
+		return fmt.Errorf("account %q is configured twice", name)
+	}
+	a.names = append(a.names, name)
+	a.clients[name] = client
+
+	env := client.environment()
+	if _, ok := a.appClients[env]; !ok {
+		a.environments = append(a.environments, env)
+		a.appClients[env] = NewClient(config.EbayConfig{
+			AppID:       client.config.AppID,
+			CertID:      client.config.CertID,
+			DevID:       client.config.DevID,
+			Environment: env,
+		})
+	}
+	return nil
+}
+
+// GetNotificationPublicKey fetches a notification public key with the app's credentials
+// rather than a seller's, asking each environment the accounts use in turn, so
+// notifications for every account can be verified
+func (a *Accounts) GetNotificationPublicKey(kid string) (*NotificationPublicKey, error) {
+	var errs []error
+	for _, env := range a.environments {
+		key, err := a.appClients[env].GetNotificationPublicKey(kid)
+		if err == nil {
+			return key, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", strings.ToLower(env), err))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w: no accounts are configured", ErrUnknownAccount)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Get returns the client for the named account, or the default account when name is empty
+func (a *Accounts) Get(name string) (*Client, error) {
+	if name == "" {
+		if client := a.Default(); client != nil {
+			return client, nil
+		}
+		return nil, fmt.Errorf("%w: no accounts are configured", ErrUnknownAccount)
+	}
+	client, ok := a.clients[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (configured: %s)", ErrUnknownAccount, name, strings.Join(a.names, ", "))
+	}
+	return client, nil
+}
+
+// Default returns the first configured account
+func (a *Accounts) Default() *Client {
+	if len(a.names) == 0 {
+		return nil
+	}
+	return a.clients[a.names[0]]
+}
+
+// Names returns the account names in configuration order
+func (a *Accounts) Names() []string {
+	return append([]string(nil), a.names...)
+}
+
+// All returns every account's client in configuration order
+func (a *Accounts) All() []*Client {
+	clients := make([]*Client, len(a.names))
+	for n, name := range a.names {
+		clients[n] = a.clients[name]
+	}
+	return clients
+}
+
+// Len returns how many accounts are configured
+func (a *Accounts) Len() int {
+	return len(a.names)
+}
+
+// ForUser returns the account whose eBay user ID is userID, or nil if none matches
+func (a *Accounts) ForUser(userID string) *Client {
+	if userID == "" {
+		return nil
+	}
+	for _, name := range a.names {
+		if client := a.clients[name]; client.UserID() == userID {
+			return client
+		}
+	}
+	return nil
+}
+
+// environment is PRODUCTION or SANDBOX, as the client treats anything else as the sandbox
+func (c *Client) environment() string {
+	if c.config.Environment == "PRODUCTION" {
+		return "PRODUCTION"
+	}
+	return "SANDBOX"
+}
+
+// Account returns the name of the seller account the client acts for
+func (c *Client) Account() string {
+	if c.config.Account == "" {
+		return DefaultAccount
+	}
+	return c.config.Account
+}
+
+// UserID returns the eBay user ID of the authorized seller, or "" until Identify succeeds
+func (c *Client) UserID() string {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.userID
+}
+
+// Username returns the seller's eBay username: the configured override, else the one
+// found by Identify
+func (c *Client) Username() string {
+	if c.config.SellerUsername != "" {
+		return c.config.SellerUsername
+	}
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.username
+}
+
+// Identify looks up the authorized seller's user ID and username with the Identity API
+// and saves them with the tokens, so notifications can be matched to the account
+func (c *Client) Identify() error {
+	if err := c.requireScopes("Identify"); err != nil {
+		return err
+	}
+	respData, err := c.makeRequest("GET", "/commerce/identity/v1/user/", nil)
+	if err != nil {
+		return fmt.Errorf("identity API error: %w", err)
+	}
+	var result struct {
+		UserID   string `json:"userId"`
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(respData, &result); err != nil {
+		return fmt.Errorf("failed to parse identity response: %w", err)
+	}
+	if result.UserID == "" {
+		return fmt.Errorf("no user ID returned by Identity API")
+	}
+
+	c.statusMu.Lock()
+	c.userID = result.UserID
+	c.username = result.Username
+	c.statusMu.Unlock()
+	log.Printf("🪪 Account %s is eBay seller %s", c.Account(), result.Username)
+
+	if c.tokenStore == nil {
+		return nil
+	}
+	return c.SaveTokens()
+}
+
+// forgetSeller clears the identified seller after a new authorization
+func (c *Client) forgetSeller() {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.userID = ""
+	c.username = ""
+}
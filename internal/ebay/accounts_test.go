@@ -0,0 +1,166 @@
+package ebay
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/config"
+)
+
+func accountClient(name string) *Client {
+	return NewClient(config.EbayConfig{Account: name, AccessToken: name + "-token"})
+}
+
+func TestAccounts(t *testing.T) {
+	main, vintage := accountClient("main"), accountClient("vintage")
+	accounts, err := NewAccounts(main, vintage)
+	if err != nil {
+		t.Fatalf("NewAccounts: %v", err)
+	}
+	if err := accounts.Add(accountClient("vintage")); err == nil {
+		t.Error("Expected a duplicate account to be refused")
+	}
+
+	tests := []struct {
+		name string
+		want *Client
+	}{
+		{"", main},
+		{"main", main},
+		{"Vintage", vintage},
+	}
+	for _, tt := range tests {
+		if got, err := accounts.Get(tt.name); err != nil || got != tt.want {
+			t.Errorf("Get(%q) = %v, %v", tt.name, got.Account(), err)
+		}
+	}
+	if _, err := accounts.Get("outlet"); !errors.Is(err, ErrUnknownAccount) {
+		t.Errorf("Get(outlet) = %v, want ErrUnknownAccount", err)
+	}
+
+	vintage.userID = "seller-2"
+	if got := accounts.ForUser("seller-2"); got != vintage {
+		t.Errorf("ForUser(seller-2) = %v, want vintage", got)
+	}
+	if got := accounts.ForUser("seller-9"); got != nil {
+		t.Errorf("ForUser(seller-9) = %v, want nil", got.Account())
+	}
+	if names := accounts.Names(); len(names) != 2 || names[0] != "main" {
+		t.Errorf("Names() = %v", names)
+	}
+	if name := NewClient(config.EbayConfig{}).Account(); name != DefaultAccount {
+		t.Errorf("Account() without a name = %q, want %q", name, DefaultAccount)
+	}
+}
+
+func TestAccountsFetchNotificationKeysWithAppCredentials(t *testing.T) {
+	sandbox := httptest.NewServer(http.NotFoundHandler())
+	defer sandbox.Close()
+	production := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer app-token" {
+			t.Errorf("Expected the app token, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"algorithm":"ECDSA","digest":"SHA1","key":"pem"}`))
+	}))
+	defer production.Close()
+
+	main := NewClient(config.EbayConfig{Account: "main", AppID: "app", AccessToken: "main-token"})
+	outlet := NewClient(config.EbayConfig{Account: "outlet", AppID: "app", AccessToken: "outlet-token"})
+	vintage := NewClient(config.EbayConfig{Account: "vintage", AppID: "app", AccessToken: "vintage-token", Environment: "PRODUCTION"})
+	accounts, _ := NewAccounts(main, outlet, vintage)
+
+	if len(accounts.appClients) != 2 {
+		t.Fatalf("Expected one app client per environment, got %d", len(accounts.appClients))
+	}
+	for env, url := range map[string]string{"SANDBOX": sandbox.URL, "PRODUCTION": production.URL} {
+		app := accounts.appClients[env]
+		if app.config.AccessToken != "" || app.config.AppID != "app" {
+			t.Errorf("%s app client should carry only the app credentials: %+v", env, app.config)
+		}
+		app.baseURL = url
+		app.appToken, app.appTokenExpiry = "app-token", time.Now().Add(time.Hour)
+	}
+
+	// The sandbox doesn't know the kid; production, used by vintage, does
+	if key, err := accounts.GetNotificationPublicKey("kid-1"); err != nil || key.Key != "pem" {
+		t.Errorf("GetNotificationPublicKey() = %+v, %v", key, err)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"userId":"seller-1","username":"vintage_finds"}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	store := NewFileTokenStore(path)
+	client := accountClient("vintage")
+	client.baseURL = srv.URL
+	client.SetTokenStore(store.Account("vintage"))
+
+	if err := client.Identify(); err != nil {
+		t.Fatalf("Identify: %v", err)
+	}
+	if client.UserID() != "seller-1" || client.Username() != "vintage_finds" {
+		t.Errorf("Identify() recorded %q / %q", client.UserID(), client.Username())
+	}
+	if saved, _ := store.LoadAccount("vintage"); saved == nil || saved.UserID != "seller-1" {
+		t.Errorf("Expected the user ID to be saved, got %+v", saved)
+	}
+}
+
+func TestTokenStoreAccounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	store := NewPassphraseTokenStore(path, "correct horse")
+
+	store.Account("main").Save(&Tokens{AccessToken: "main-access"})
+	store.Account("vintage").Save(&Tokens{AccessToken: "vintage-access"})
+	for name, want := range map[string]string{"main": "main-access", "vintage": "vintage-access"} {
+		if got, err := store.Account(name).Load(); err != nil || got.AccessToken != want {
+			t.Errorf("Load(%s) = %+v, %v", name, got, err)
+		}
+	}
+	if got, _ := store.Account("outlet").Load(); got != nil {
+		t.Errorf("Load(outlet) = %+v, want nil", got)
+	}
+}
+
+func TestLegacyTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	os.WriteFile(path, []byte(`{"accessToken":"old-access","refreshToken":"old-refresh"}`), 0600)
+	store := NewFileTokenStore(path)
+
+	if got, err := store.Load(); err != nil || got.RefreshToken != "old-refresh" {
+		t.Fatalf("Load() of a single-account file = %+v, %v", got, err)
+	}
+
+	// The first configured account adopts the tokens saved before accounts were named
+	if err := store.RenameAccount(DefaultAccount, "main"); err != nil {
+		t.Fatalf("RenameAccount: %v", err)
+	}
+	if got, _ := store.Account("main").Load(); got == nil || got.AccessToken != "old-access" {
+		t.Errorf("Expected main to have the old tokens, got %+v", got)
+	}
+	if got, _ := store.Load(); got != nil {
+		t.Errorf("Expected no default tokens after renaming, got %+v", got)
+	}
+}
+
+func TestSavedTokensForAnotherEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	NewFileTokenStore(path).Save(&Tokens{AccessToken: "sandbox-access", Environment: "SANDBOX"})
+
+	client := NewClient(config.EbayConfig{Environment: "PRODUCTION"})
+	if err := client.SetTokenStore(NewFileTokenStore(path)); err != nil {
+		t.Fatalf("SetTokenStore: %v", err)
+	}
+	if client.config.AccessToken != "" {
+		t.Errorf("Expected sandbox tokens to be ignored in production, got %q", client.config.AccessToken)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"ebaymanager-bot/internal/config"
@@ -28,6 +30,21 @@ type Client struct {
 	httpClient *http.Client
 	baseURL    string
 	authURL    string
+
+	appTokenMu     sync.Mutex
+	appToken       string    // cached client-credentials token
+	appTokenExpiry time.Time // when appToken stops being valid
+
+	statusMu      sync.Mutex
+	status        APIStatus // token and last-call state for health checks
+	refreshExpiry time.Time // when the refresh token stops working; zero if unknown
+	scope         string    // scopes granted at authorization
+	scopeAssumed  bool      // scope is what was requested, as eBay didn't list the grant
+	reminderSent  int       // re-authorization reminder already posted, in days before refreshExpiry
+	userID        string    // the seller's eBay user ID, found by Identify
+	username      string    // the seller's eBay username, found by Identify
+
+	tokenStore TokenStore // where tokens are persisted; nil keeps them in memory only
 }
 
 // NewClient creates a new eBay API client
@@ -50,37 +67,16 @@ func NewClient(cfg config.EbayConfig) *Client {
 	}
 }
 
-// SaveTokensToEnv persists the current access and refresh tokens to the .env file
-func (c *Client) SaveTokensToEnv() error {
-	envPath := ".env"
-	data, err := os.ReadFile(envPath)
-	if err != nil {
-		return fmt.Errorf("failed to read .env file: %w", err)
-	}
-
-	lines := strings.Split(string(data), "\n")
-	for i, line := range lines {
-		if strings.HasPrefix(line, "EBAY_ACCESS_TOKEN=") {
-			lines[i] = "EBAY_ACCESS_TOKEN=" + c.config.AccessToken
-		} else if strings.HasPrefix(line, "EBAY_REFRESH_TOKEN=") && c.config.RefreshToken != "" {
-			lines[i] = "EBAY_REFRESH_TOKEN=" + c.config.RefreshToken
-		}
-	}
-
-	if err := os.WriteFile(envPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
-		return fmt.Errorf("failed to write .env file: %w", err)
-	}
-
-	log.Println("✅ Tokens persisted to .env file")
-	return nil
-}
-
 // CheckConnection verifies the eBay API connection
 func (c *Client) CheckConnection() string {
 	if c.config.AccessToken == "" {
 		return "âŒ **Not authorized**\n\nNo access token found. Run `/ebay-authorize` to connect your eBay account."
 	}
 
+	if err := c.requireScopes("CheckConnection"); err != nil {
+		return fmt.Sprintf("⚠️ **Token is missing a scope**\n\nEnvironment: %s\n%v", c.config.Environment, err)
+	}
+
 	// Test with a lightweight API call
 	_, err := c.makeRequest("GET", "/sell/account/v1/privilege", nil)
 	if err != nil {
@@ -93,26 +89,34 @@ func (c *Client) CheckConnection() string {
 		c.config.AccessToken[:10])
 }
 
-// GetTokenScopes returns the OAuth scopes configured for this application
-func (c *Client) GetTokenScopes() map[string]interface{} {
-	// Return scope info with token status
-	result := make(map[string]interface{})
-	result["hasToken"] = c.config.AccessToken != ""
-	result["environment"] = c.config.Environment
-	result["requestedScopes"] = []string{
-		"https://api.ebay.com/oauth/api_scope",
-		"https://api.ebay.com/oauth/api_scope/sell.inventory",
-		"https://api.ebay.com/oauth/api_scope/sell.fulfillment",
-		"https://api.ebay.com/oauth/api_scope/sell.account",
-		"https://api.ebay.com/oauth/api_scope/sell.finances",
-		"https://api.ebay.com/oauth/api_scope/commerce.identity.readonly",
-		"https://api.ebay.com/oauth/api_scope/commerce.notification.subscription",
-	}
-	return result
+// APIError is returned by makeRequest when eBay responds with an HTTP error status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// IsRateLimited reports whether err was caused by eBay rate limiting (HTTP 429
+// or a Trading API call-limit error)
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
 }
 
 // makeRequest is a helper to make authenticated requests to eBay API
 func (c *Client) makeRequest(method, endpoint string, body interface{}) ([]byte, error) {
+	start := time.Now()
+	respBody, err := c.doRequest(method, endpoint, body)
+	observeCall(method, endpoint, start, err)
+	c.recordCall(err)
+	return respBody, err
+}
+
+// doRequest performs a REST call with the seller's access token
+func (c *Client) doRequest(method, endpoint string, body interface{}) ([]byte, error) {
 	var reqBody io.Reader
 	if body != nil {
 		jsonData, err := json.Marshal(body)
@@ -170,7 +174,7 @@ func (c *Client) makeRequest(method, endpoint string, body interface{}) ([]byte,
 		if strings.Contains(endpoint, "/finances/") {
 			log.Printf("[DEBUG] Finances Response Headers: %v", resp.Header)
 		}
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	log.Printf("[API] %s %s => HTTP %d (%d bytes)", method, fullURL, resp.StatusCode, len(respBody))
@@ -186,6 +190,9 @@ type OrdersResponse struct {
 
 // GetOrders fetches recent orders from eBay Fulfillment API
 func (c *Client) GetOrders(limit int) ([]Order, error) {
+	if err := c.requireScopes("GetOrders"); err != nil {
+		return nil, err
+	}
 	if c.config.AccessToken == "" {
 		return nil, fmt.Errorf("no access token available")
 	}
@@ -204,32 +211,7 @@ func (c *Client) GetOrders(limit int) ([]Order, error) {
 
 	// Process orders to populate computed fields
 	for i := range ordersResp.Orders {
-		order := &ordersResp.Orders[i]
-
-		// Extract buyer username
-		order.BuyerUsername = order.Buyer.Username
-
-		// Extract price and currency
-		fmt.Sscanf(order.PricingSummary.Total.Value, "%f", &order.TotalPrice)
-		order.Currency = order.PricingSummary.Total.Currency
-
-		// Extract fulfillment status
-		order.FulfillmentStatus = order.OrderFulfillmentStatus
-
-		// Process line items to get images and prices
-		for j := range order.LineItems {
-			lineItem := &order.LineItems[j]
-
-			// Extract line item price
-			fmt.Sscanf(lineItem.LineItemCost.Value, "%f", &lineItem.Price)
-
-			// eBay Fulfillment API doesn't include images, so fetch from Inventory API
-			if lineItem.LegacyItemId != "" {
-				if img := c.getItemImage(lineItem.LegacyItemId); img != "" {
-					lineItem.ImageUrl = img
-				}
-			}
-		}
+		c.populateOrder(&ordersResp.Orders[i], true)
 	}
 
 	return ordersResp.Orders, nil
@@ -237,6 +219,9 @@ func (c *Client) GetOrders(limit int) ([]Order, error) {
 
 // GetOrderByID fetches a specific order by ID
 func (c *Client) GetOrderByID(orderID string) (*Order, error) {
+	if err := c.requireScopes("GetOrderByID"); err != nil {
+		return nil, err
+	}
 	if c.config.AccessToken == "" {
 		return nil, fmt.Errorf("no access token available")
 	}
@@ -253,9 +238,78 @@ func (c *Client) GetOrderByID(orderID string) (*Order, error) {
 		return nil, fmt.Errorf("failed to parse order response: %w", err)
 	}
 
+	c.populateOrder(&order, true)
+
 	return &order, nil
 }
 
+// GetOrdersModifiedSince fetches one page of orders whose lastmodifieddate is at or
+// after since, using the Fulfillment API filter. It returns the page and the total
+// number of matching orders. Line item images are not looked up; use GetItemImage.
+func (c *Client) GetOrdersModifiedSince(since time.Time, limit, offset int) ([]Order, int, error) {
+	if err := c.requireScopes("GetOrdersModifiedSince"); err != nil {
+		return nil, 0, err
+	}
+	if c.config.AccessToken == "" {
+		return nil, 0, fmt.Errorf("no access token available")
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 200
+	}
+
+	filter := fmt.Sprintf("lastmodifieddate:[%s..]", since.UTC().Format("2006-01-02T15:04:05.000Z"))
+	endpoint := fmt.Sprintf("/sell/fulfillment/v1/order?filter=%s&limit=%d&offset=%d", url.QueryEscape(filter), limit, offset)
+
+	respBody, err := c.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get modified orders: %w", err)
+	}
+
+	var ordersResp OrdersResponse
+	if err := json.Unmarshal(respBody, &ordersResp); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse orders response: %w", err)
+	}
+
+	for i := range ordersResp.Orders {
+		c.populateOrder(&ordersResp.Orders[i], false)
+	}
+
+	return ordersResp.Orders, ordersResp.Total, nil
+}
+
+// populateOrder fills in the computed fields of an order returned by the Fulfillment API
+func (c *Client) populateOrder(order *Order, withImages bool) {
+	// Extract buyer username
+	order.BuyerUsername = order.Buyer.Username
+
+	// Extract price and currency
+	fmt.Sscanf(order.PricingSummary.Total.Value, "%f", &order.TotalPrice)
+	order.Currency = order.PricingSummary.Total.Currency
+
+	// Extract fulfillment status
+	order.FulfillmentStatus = order.OrderFulfillmentStatus
+
+	// Process line items to get images and prices
+	for j := range order.LineItems {
+		lineItem := &order.LineItems[j]
+
+		// Extract line item price
+		fmt.Sscanf(lineItem.LineItemCost.Value, "%f", &lineItem.Price)
+
+		// eBay Fulfillment API doesn't include images, so fetch from Inventory API
+		if withImages && lineItem.LegacyItemId != "" {
+			if img := c.getItemImage(lineItem.LegacyItemId); img != "" {
+				lineItem.ImageUrl = img
+			}
+		}
+	}
+}
+
+// GetItemImage returns an image URL for a legacy item ID
+func (c *Client) GetItemImage(legacyItemId string) string {
+	return c.getItemImage(legacyItemId)
+}
+
 // getItemImage fetches the image URL for a specific item using Browse API
 func (c *Client) getItemImage(legacyItemId string) string {
 	// Use eBay's Browse API to get item details with images
@@ -294,6 +348,9 @@ type OffersResponse struct {
 
 // GetOffers fetches pending buyer offers (best offers) from eBay
 func (c *Client) GetOffers() ([]Offer, error) {
+	if err := c.requireScopes("GetOffers"); err != nil {
+		return nil, err
+	}
 	if c.config.AccessToken == "" {
 		return nil, fmt.Errorf("no access token available")
 	}
@@ -328,6 +385,9 @@ func (c *Client) GetOffers() ([]Offer, error) {
 // GetSellerUsername retrieves the authenticated seller's eBay username via the Identity API.
 // Requires commerce.identity.readonly scope (granted after re-authorizing with /ebay-authorize).
 func (c *Client) GetSellerUsername() (string, error) {
+	if err := c.requireScopes("GetSellerUsername"); err != nil {
+		return "", err
+	}
 	respData, err := c.makeRequest("GET", "/commerce/identity/v1/user/", nil)
 	if err != nil {
 		return "", fmt.Errorf("identity API error: %w", err)
@@ -347,12 +407,27 @@ func (c *Client) GetSellerUsername() (string, error) {
 // GetListings retrieves active listings via the Trading API GetMyeBaySelling.
 // Uses the seller's OAuth access token — works for all traditionally-listed items.
 func (c *Client) GetListings(limit int) ([]Listing, error) {
-	if c.config.AccessToken == "" {
-		return nil, fmt.Errorf("no access token - run /ebay-authorize first")
-	}
 	if limit <= 0 || limit > 200 {
 		limit = 10
 	}
+	listings, _, err := c.GetListingsPage(1, limit)
+	return listings, err
+}
+
+// GetListingsPage retrieves one page of active listings and the total number of pages
+func (c *Client) GetListingsPage(page, perPage int) ([]Listing, int, error) {
+	if err := c.requireScopes("GetListingsPage"); err != nil {
+		return nil, 0, err
+	}
+	if c.config.AccessToken == "" {
+		return nil, 0, fmt.Errorf("no access token - run /ebay-authorize first")
+	}
+	if perPage <= 0 || perPage > 200 {
+		perPage = 200
+	}
+	if page <= 0 {
+		page = 1
+	}
 
 	// Trading API — GetMyeBaySelling returns all active listings for the authenticated seller
 	tradingURL := "https://api.ebay.com/ws/api.dll"
@@ -368,15 +443,15 @@ func (c *Client) GetListings(limit int) ([]Listing, error) {
     <Include>true</Include>
     <Pagination>
       <EntriesPerPage>%d</EntriesPerPage>
-      <PageNumber>1</PageNumber>
+      <PageNumber>%d</PageNumber>
     </Pagination>
   </ActiveList>
   <DetailLevel>ReturnAll</DetailLevel>
-</GetMyeBaySellingRequest>`, limit)
+</GetMyeBaySellingRequest>`, perPage, page)
 
 	req, err := http.NewRequest("POST", tradingURL, strings.NewReader(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to build Trading API request: %w", err)
+		return nil, 0, fmt.Errorf("failed to build Trading API request: %w", err)
 	}
 	req.Header.Set("Content-Type", "text/xml")
 	req.Header.Set("X-EBAY-API-SITEID", "0")
@@ -387,13 +462,13 @@ func (c *Client) GetListings(limit int) ([]Listing, error) {
 	log.Printf("[API] POST %s (Trading API: GetMyeBaySelling)", tradingURL)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Trading API request failed: %w", err)
+		return nil, 0, fmt.Errorf("Trading API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Trading API response: %w", err)
+		return nil, 0, fmt.Errorf("failed to read Trading API response: %w", err)
 	}
 	log.Printf("[API] POST Trading API => HTTP %d (%d bytes)", resp.StatusCode, len(body))
 	log.Printf("[DEBUG] Trading API response: %s", string(body))
@@ -402,9 +477,13 @@ func (c *Client) GetListings(limit int) ([]Listing, error) {
 		XMLName xml.Name `xml:"GetMyeBaySellingResponse"`
 		Ack     string   `xml:"Ack"`
 		Errors  []struct {
+			ErrorCode   string `xml:"ErrorCode"`
 			LongMessage string `xml:"LongMessage"`
 		} `xml:"Errors"`
 		ActiveList struct {
+			PaginationResult struct {
+				TotalNumberOfPages int `xml:"TotalNumberOfPages"`
+			} `xml:"PaginationResult"`
 			ItemArray struct {
 				Items []struct {
 					ItemID        string `xml:"ItemID"`
@@ -439,14 +518,18 @@ func (c *Client) GetListings(limit int) ([]Listing, error) {
 	}
 
 	if err := xml.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse Trading API response: %w", err)
+		return nil, 0, fmt.Errorf("failed to parse Trading API response: %w", err)
 	}
 	if result.Ack != "Success" && result.Ack != "Warning" {
 		msg := "unknown error"
 		if len(result.Errors) > 0 {
 			msg = result.Errors[0].LongMessage
+			// 518 = call usage limit reached
+			if result.Errors[0].ErrorCode == "518" {
+				return nil, 0, &APIError{StatusCode: http.StatusTooManyRequests, Body: msg}
+			}
 		}
-		return nil, fmt.Errorf("Trading API error: %s", msg)
+		return nil, 0, fmt.Errorf("Trading API error: %s", msg)
 	}
 
 	items := result.ActiveList.ItemArray.Items
@@ -485,6 +568,7 @@ func (c *Client) GetListings(limit int) ([]Listing, error) {
 		imageURL = strings.Replace(imageURL, "s-l96.jpg", "s-l500.jpg", 1)
 
 		listings = append(listings, Listing{
+			ListingID:  item.ItemID,
 			Title:      item.Title,
 			Price:      price,
 			Currency:   currency,
@@ -496,12 +580,15 @@ func (c *Client) GetListings(limit int) ([]Listing, error) {
 		})
 	}
 
-	return listings, nil
+	return listings, result.ActiveList.PaginationResult.TotalNumberOfPages, nil
 }
 
 // RespondToOffer accepts, declines, or counters a buyer offer
 // action can be: "ACCEPT", "DECLINE", or "COUNTER"
 func (c *Client) RespondToOffer(offerID string, action string, counterPrice float64) error {
+	if err := c.requireScopes("RespondToOffer"); err != nil {
+		return err
+	}
 	if c.config.AccessToken == "" {
 		return fmt.Errorf("no access token available")
 	}
@@ -545,6 +632,9 @@ func (c *Client) RespondToOffer(offerID string, action string, counterPrice floa
 
 // GetSellerBalance retrieves seller account balance information
 func (c *Client) GetSellerBalance() (map[string]float64, error) {
+	if err := c.requireScopes("GetSellerBalance"); err != nil {
+		return nil, err
+	}
 	// Use seller_funds_summary endpoint to get pending payout amount
 	respData, err := c.makeRequest("GET", "/sell/finances/v1/seller_funds_summary", nil)
 	if err != nil {
@@ -590,6 +680,9 @@ func (c *Client) GetSellerBalance() (map[string]float64, error) {
 
 // GetPayouts retrieves recent payout transactions
 func (c *Client) GetPayouts(limit int) ([]map[string]interface{}, error) {
+	if err := c.requireScopes("GetPayouts"); err != nil {
+		return nil, err
+	}
 	// Get payouts with succeeded status
 	endpoint := fmt.Sprintf("/sell/finances/v1/payout?limit=%d&payoutStatus=SUCCEEDED", limit)
 	respData, err := c.makeRequest("GET", endpoint, nil)
@@ -0,0 +1,74 @@
+package ebay
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"ebaymanager-bot/internal/metrics"
+)
+
+var (
+	apiRequests = metrics.NewCounterVec("ebay_api_requests_total",
+		"eBay REST API calls by endpoint, method and HTTP status (error when no response)", "endpoint", "method", "status")
+	apiLatency = metrics.NewHistogramVec("ebay_api_request_duration_seconds",
+		"eBay REST API call latency", metrics.DefaultBuckets, "endpoint", "method")
+	tokenRefreshes = metrics.NewCounterVec("ebay_token_refreshes_total",
+		"Access token refreshes by result", "result")
+)
+
+// observeCall records the outcome and latency of an API call
+func observeCall(method, endpoint string, start time.Time, err error) {
+	label := endpointLabel(endpoint)
+	apiLatency.Observe(time.Since(start).Seconds(), label, method)
+	apiRequests.Inc(label, method, statusLabel(err))
+}
+
+// observeTokenRefresh records a token refresh attempt
+func observeTokenRefresh(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	tokenRefreshes.Inc(result)
+}
+
+// endpointLabel strips the query and replaces IDs in endpoint with :id, keeping the
+// label's cardinality bounded (/sell/fulfillment/v1/order/12-345 -> /sell/fulfillment/v1/order/:id)
+func endpointLabel(endpoint string) string {
+	if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	segments := strings.Split(endpoint, "/")
+	for i, seg := range segments {
+		if isIDSegment(seg) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isIDSegment reports whether a path segment looks like an identifier rather than a
+// resource name: it contains a digit and isn't an API version such as v1
+func isIDSegment(seg string) bool {
+	if len(seg) > 1 && seg[0] == 'v' {
+		if _, err := strconv.Atoi(seg[1:]); err == nil {
+			return false
+		}
+	}
+	return strings.ContainsAny(seg, "0123456789")
+}
+
+// statusLabel is the HTTP status for failed calls, 2xx for successful ones and error
+// when eBay never answered
+func statusLabel(err error) string {
+	if err == nil {
+		return "2xx"
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return strconv.Itoa(apiErr.StatusCode)
+	}
+	return "error"
+}
@@ -0,0 +1,42 @@
+package ebay
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEndpointLabel(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"/sell/fulfillment/v1/order?limit=50&filter=creationdate", "/sell/fulfillment/v1/order"},
+		{"/sell/fulfillment/v1/order/12-34567-89012", "/sell/fulfillment/v1/order/:id"},
+		{"/commerce/notification/v1/subscription/8f3c2a/enable", "/commerce/notification/v1/subscription/:id/enable"},
+		{"/commerce/notification/v1/topic/MARKETPLACE_ORDER", "/commerce/notification/v1/topic/MARKETPLACE_ORDER"},
+		{"/sell/finances/v1/payout", "/sell/finances/v1/payout"},
+	}
+
+	for _, tt := range tests {
+		if got := endpointLabel(tt.endpoint); got != tt.want {
+			t.Errorf("endpointLabel(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestStatusLabel(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "2xx"},
+		{&APIError{StatusCode: 429}, "429"},
+		{errors.New("dial tcp: timeout"), "error"},
+	}
+
+	for _, tt := range tests {
+		if got := statusLabel(tt.err); got != tt.want {
+			t.Errorf("statusLabel(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
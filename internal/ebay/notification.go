@@ -0,0 +1,80 @@
+package ebay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// NotificationPublicKey is a key eBay uses to sign webhook notifications
+type NotificationPublicKey struct {
+	Algorithm string `json:"algorithm"` // e.g. ECDSA
+	Digest    string `json:"digest"`    // e.g. SHA1
+	Key       string `json:"key"`       // PEM-encoded public key
+}
+
+// applicationToken returns a cached client-credentials token, fetching a new one when
+// the cached token is missing or about to expire
+func (c *Client) applicationToken() (string, error) {
+	c.appTokenMu.Lock()
+	defer c.appTokenMu.Unlock()
+
+	if c.appToken != "" && time.Now().Add(5*time.Minute).Before(c.appTokenExpiry) {
+		return c.appToken, nil
+	}
+
+	tokenResp, err := c.GetApplicationToken()
+	if err != nil {
+		return "", err
+	}
+
+	c.appToken = tokenResp.AccessToken
+	c.appTokenExpiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	return c.appToken, nil
+}
+
+// GetNotificationPublicKey fetches the public key identified by kid from the Notification API.
+// The call is made with an application token, so it works before any user has authorized.
+func (c *Client) GetNotificationPublicKey(kid string) (*NotificationPublicKey, error) {
+	token, err := c.applicationToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get application token: %w", err)
+	}
+
+	fullURL := c.baseURL + "/commerce/notification/v1/public_key/" + url.PathEscape(kid)
+	req, err := http.NewRequest("GET", fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	log.Printf("[API] GET %s", fullURL)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var key NotificationPublicKey
+	if err := json.Unmarshal(body, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse public key response: %w", err)
+	}
+	if key.Key == "" {
+		return nil, fmt.Errorf("no key returned for kid %s", kid)
+	}
+
+	return &key, nil
+}
@@ -24,6 +24,8 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token,omitempty"`
 	TokenType    string `json:"token_type"`
 	Scope        string `json:"scope,omitempty"` // Space-separated list of granted scopes
+
+	RefreshTokenExpiresIn int `json:"refresh_token_expires_in,omitempty"`
 }
 
 // GetApplicationToken gets an application token using client credentials
@@ -41,7 +43,7 @@ func (c *Client) GetApplicationToken() (*TokenResponse, error) {
 	// Prepare the request body
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
-	data.Set("scope", "https://api.ebay.com/oauth/api_scope")
+	data.Set("scope", ScopeBasic)
 
 	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
@@ -76,6 +78,13 @@ func (c *Client) GetApplicationToken() (*TokenResponse, error) {
 
 // RefreshAccessToken refreshes an expired access token using the refresh token
 func (c *Client) RefreshAccessToken(refreshToken string) (*TokenResponse, error) {
+	tokenResp, err := c.refreshAccessToken(refreshToken)
+	observeTokenRefresh(err)
+	return tokenResp, err
+}
+
+// refreshAccessToken performs the refresh_token grant
+func (c *Client) refreshAccessToken(refreshToken string) (*TokenResponse, error) {
 	tokenURL := sandboxTokenURL
 	if c.config.Environment == "PRODUCTION" {
 		tokenURL = productionTokenURL
@@ -87,7 +96,7 @@ func (c *Client) RefreshAccessToken(refreshToken string) (*TokenResponse, error)
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", refreshToken)
-	data.Set("scope", "https://api.ebay.com/oauth/api_scope https://api.ebay.com/oauth/api_scope/sell.inventory https://api.ebay.com/oauth/api_scope/sell.fulfillment https://api.ebay.com/oauth/api_scope/sell.account https://api.ebay.com/oauth/api_scope/sell.finances https://api.ebay.com/oauth/api_scope/commerce.identity.readonly https://api.ebay.com/oauth/api_scope/commerce.notification.subscription")
+	data.Set("scope", c.refreshScopes())
 
 	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
@@ -119,6 +128,7 @@ func (c *Client) RefreshAccessToken(refreshToken string) (*TokenResponse, error)
 
 	// Update the client's access token
 	c.config.AccessToken = tokenResp.AccessToken
+	c.recordToken(tokenResp.ExpiresIn)
 
 	return &tokenResp, nil
 }
@@ -126,7 +136,12 @@ func (c *Client) RefreshAccessToken(refreshToken string) (*TokenResponse, error)
 // GetUserAuthorizationURL generates the URL for user authorization
 // Users need to visit this URL to grant your application access to their eBay account
 // Note: eBay requires the RuName as the redirect_uri parameter, not the actual callback URL
-func (c *Client) GetUserAuthorizationURL(state string) string {
+// Requests scopes, or every scope the bot uses when none are given.
+func (c *Client) GetUserAuthorizationURL(state string, scopes ...string) string {
+	if len(scopes) == 0 {
+		scopes = Scopes
+	}
+
 	authURL := "https://auth.sandbox.ebay.com/oauth2/authorize"
 	if c.config.Environment == "PRODUCTION" {
 		authURL = "https://auth.ebay.com/oauth2/authorize"
@@ -136,7 +151,7 @@ func (c *Client) GetUserAuthorizationURL(state string) string {
 	params.Set("client_id", c.config.AppID)
 	params.Set("response_type", "code")
 	params.Set("redirect_uri", c.config.RedirectURI) // This should be the RuName
-	params.Set("scope", "https://api.ebay.com/oauth/api_scope https://api.ebay.com/oauth/api_scope/sell.inventory https://api.ebay.com/oauth/api_scope/sell.fulfillment https://api.ebay.com/oauth/api_scope/sell.account https://api.ebay.com/oauth/api_scope/sell.finances https://api.ebay.com/oauth/api_scope/commerce.identity.readonly https://api.ebay.com/oauth/api_scope/commerce.notification.subscription")
+	params.Set("scope", strings.Join(scopes, " "))
 	if state != "" {
 		params.Set("state", state)
 	}
@@ -144,8 +159,10 @@ func (c *Client) GetUserAuthorizationURL(state string) string {
 	return authURL + "?" + params.Encode()
 }
 
-// ExchangeCodeForToken exchanges an authorization code for access and refresh tokens
-func (c *Client) ExchangeCodeForToken(code string) (*TokenResponse, error) {
+// ExchangeCodeForToken exchanges an authorization code for access and refresh tokens.
+// requested are the scopes the code was authorized for; when eBay's response doesn't
+// list the granted scopes they are recorded, marked as assumed.
+func (c *Client) ExchangeCodeForToken(code string, requested ...string) (*TokenResponse, error) {
 	tokenURL := sandboxTokenURL
 	if c.config.Environment == "PRODUCTION" {
 		tokenURL = productionTokenURL
@@ -190,13 +207,23 @@ func (c *Client) ExchangeCodeForToken(code string) (*TokenResponse, error) {
 		return nil, fmt.Errorf("failed to parse token exchange response: %w", err)
 	}
 
-	// Log the granted scopes for debugging
-	log.Printf("🔑 OAuth tokens obtained - Granted scopes: %s", tokenResp.Scope)
+	// Without eBay's list, assume the seller consented to everything requested
+	scope, assumed := tokenResp.Scope, false
+	if scope == "" {
+		scope, assumed = strings.Join(requested, " "), true
+		log.Printf("🔑 OAuth tokens obtained - eBay didn't list the granted scopes, assuming: %s", scope)
+	} else {
+		log.Printf("🔑 OAuth tokens obtained - Granted scopes: %s", scope)
+	}
 
-	// Update the client's tokens
+	// Update the client's tokens. They may be for another seller than before, which
+	// Identify finds out.
 	c.config.AccessToken = tokenResp.AccessToken
+	c.recordToken(tokenResp.ExpiresIn)
+	c.forgetSeller()
 	if tokenResp.RefreshToken != "" {
 		c.config.RefreshToken = tokenResp.RefreshToken
+		c.recordRefreshToken(tokenResp.RefreshTokenExpiresIn, scope, assumed)
 	}
 
 	return &tokenResp, nil
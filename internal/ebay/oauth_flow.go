@@ -2,12 +2,12 @@ package ebay
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 )
 
@@ -27,10 +27,19 @@ func NewOAuthServer(client *Client) *OAuthServer {
 		client:    client,
 		tokenChan: make(chan *TokenResponse, 1),
 		errorChan: make(chan error, 1),
-		state:     fmt.Sprintf("state_%d", time.Now().Unix()),
+		state:     randomState(),
 	}
 }
 
+// randomState returns an unguessable OAuth state
+func randomState() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("oauth: failed to generate state: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
 // StartAuthFlow generates the authorization URL and starts the callback server
 func (s *OAuthServer) StartAuthFlow() (string, error) {
 	// Generate authorization URL
@@ -122,7 +131,7 @@ func (s *OAuthServer) handleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Exchange code for tokens
-	tokens, err := s.client.ExchangeCodeForToken(code)
+	tokens, err := s.client.ExchangeCodeForToken(code, Scopes...)
 	if err != nil {
 		s.errorChan <- fmt.Errorf("failed to exchange code: %w", err)
 		
@@ -144,9 +153,9 @@ func (s *OAuthServer) handleCallback(w http.ResponseWriter, r *http.Request) {
 
 	s.tokenChan <- tokens
 
-	// Save tokens to .env file
-	if err := s.saveTokensToEnv(tokens); err != nil {
-		log.Printf("Warning: Failed to save tokens to .env: %v", err)
+	// Persist tokens so they survive restarts
+	if err := s.client.SaveTokens(); err != nil {
+		log.Printf("Warning: Failed to save tokens: %v", err)
 	}
 
 	html := `
@@ -195,45 +204,6 @@ func (s *OAuthServer) Stop() {
 	}
 }
 
-// SaveTokensToEnv updates the .env file with new tokens (exported for manual code submission)
-func (s *OAuthServer) SaveTokensToEnv(tokens *TokenResponse) error {
-	return s.saveTokensToEnv(tokens)
-}
-
-// saveTokensToEnv updates the .env file with new tokens
-func (s *OAuthServer) saveTokensToEnv(tokens *TokenResponse) error {
-	envPath := ".env"
-	
-	// Read current .env file
-	data, err := os.ReadFile(envPath)
-	if err != nil {
-		return fmt.Errorf("failed to read .env file: %w", err)
-	}
-
-	content := string(data)
-	lines := strings.Split(content, "\n")
-	
-	// Update token lines
-	for i, line := range lines {
-		if strings.HasPrefix(line, "EBAY_ACCESS_TOKEN=") {
-			lines[i] = "EBAY_ACCESS_TOKEN=" + tokens.AccessToken
-		} else if strings.HasPrefix(line, "EBAY_REFRESH_TOKEN=") {
-			if tokens.RefreshToken != "" {
-				lines[i] = "EBAY_REFRESH_TOKEN=" + tokens.RefreshToken
-			}
-		}
-	}
-
-	// Write back to file
-	newContent := strings.Join(lines, "\n")
-	if err := os.WriteFile(envPath, []byte(newContent), 0644); err != nil {
-		return fmt.Errorf("failed to write .env file: %w", err)
-	}
-
-	log.Println("Tokens saved to .env file")
-	return nil
-}
-
 // AutoRefreshToken automatically refreshes the access token when it expires
 func (c *Client) AutoRefreshToken(refreshToken string, interval time.Duration) {
 	ticker := time.NewTicker(interval)
@@ -242,36 +212,14 @@ func (c *Client) AutoRefreshToken(refreshToken string, interval time.Duration) {
 	for range ticker.C {
 		log.Println("Refreshing eBay access token...")
 		
-		tokens, err := c.RefreshAccessToken(refreshToken)
-		if err != nil {
+		if _, err := c.RefreshAccessToken(refreshToken); err != nil {
 			log.Printf("Failed to refresh token: %v", err)
 			continue
 		}
 
 		log.Println("Access token refreshed successfully")
 
-		// Update tokens in .env file
-		envPath := ".env"
-		data, err := os.ReadFile(envPath)
-		if err != nil {
-			log.Printf("Failed to read .env for token update: %v", err)
-			continue
-		}
-
-		content := string(data)
-		lines := strings.Split(content, "\n")
-		
-		for i, line := range lines {
-			if strings.HasPrefix(line, "EBAY_ACCESS_TOKEN=") {
-				lines[i] = "EBAY_ACCESS_TOKEN=" + tokens.AccessToken
-			}
-			if tokens.RefreshToken != "" && strings.HasPrefix(line, "EBAY_REFRESH_TOKEN=") {
-				lines[i] = "EBAY_REFRESH_TOKEN=" + tokens.RefreshToken
-			}
-		}
-
-		newContent := strings.Join(lines, "\n")
-		if err := os.WriteFile(envPath, []byte(newContent), 0644); err != nil {
+		if err := c.SaveTokens(); err != nil {
 			log.Printf("Failed to save refreshed tokens: %v", err)
 		}
 	}
@@ -279,6 +227,9 @@ func (c *Client) AutoRefreshToken(refreshToken string, interval time.Duration) {
 
 // GetTokenInfo returns information about the current token
 func (c *Client) GetTokenInfo() (map[string]interface{}, error) {
+	if err := c.requireScopes("GetTokenInfo"); err != nil {
+		return nil, err
+	}
 	endpoint := "/commerce/identity/v1/user"
 	
 	respBody, err := c.makeRequest("GET", endpoint, nil)
@@ -0,0 +1,137 @@
+package ebay
+
+import "ebaymanager-bot/internal/store"
+
+// ToRecord converts an order into its local store representation
+func (o Order) ToRecord() store.Order {
+	record := store.Order{
+		OrderID:           o.OrderID,
+		CreationDate:      o.CreationDate,
+		LastModifiedDate:  o.LastModifiedDate,
+		BuyerUsername:     o.BuyerUsername,
+		BuyerUserID:       o.Buyer.UserID,
+		Total:             o.TotalPrice,
+		Currency:          o.Currency,
+		FulfillmentStatus: o.FulfillmentStatus,
+		PaymentStatus:     o.OrderPaymentStatus,
+	}
+
+	if len(o.FulfillmentStartInstructions) > 0 {
+		shipTo := o.FulfillmentStartInstructions[0].ShippingStep.ShipTo
+		record.ShipTo = store.Address{
+			Name:       shipTo.Name,
+			Street1:    shipTo.Street1,
+			Street2:    shipTo.Street2,
+			City:       shipTo.City,
+			State:      shipTo.State,
+			PostalCode: shipTo.PostalCode,
+			Country:    shipTo.Country,
+		}
+	}
+
+	for _, li := range o.LineItems {
+		record.LineItems = append(record.LineItems, store.LineItem{
+			LineItemID:   li.LineItemID,
+			LegacyItemID: li.LegacyItemId,
+			Title:        li.Title,
+			SKU:          li.SKU,
+			Quantity:     li.Quantity,
+			Price:        li.Price,
+			ImageURL:     li.ImageUrl,
+		})
+	}
+
+	return record
+}
+
+// OrderFromRecord converts a stored order back into an Order with its computed fields set
+func OrderFromRecord(r store.Order) Order {
+	order := Order{
+		OrderID:                r.OrderID,
+		CreationDate:           r.CreationDate,
+		LastModifiedDate:       r.LastModifiedDate,
+		Buyer:                  Buyer{Username: r.BuyerUsername, UserID: r.BuyerUserID},
+		BuyerUsername:          r.BuyerUsername,
+		TotalPrice:             r.Total,
+		Currency:               r.Currency,
+		OrderFulfillmentStatus: r.FulfillmentStatus,
+		FulfillmentStatus:      r.FulfillmentStatus,
+		OrderPaymentStatus:     r.PaymentStatus,
+	}
+
+	var instruction FulfillmentInstruction
+	instruction.ShippingStep.ShipTo = Address{
+		Name:       r.ShipTo.Name,
+		Street1:    r.ShipTo.Street1,
+		Street2:    r.ShipTo.Street2,
+		City:       r.ShipTo.City,
+		State:      r.ShipTo.State,
+		PostalCode: r.ShipTo.PostalCode,
+		Country:    r.ShipTo.Country,
+	}
+	order.FulfillmentStartInstructions = []FulfillmentInstruction{instruction}
+
+	for _, li := range r.LineItems {
+		item := LineItem{
+			LineItemID:   li.LineItemID,
+			LegacyItemId: li.LegacyItemID,
+			Title:        li.Title,
+			SKU:          li.SKU,
+			Quantity:     li.Quantity,
+			Price:        li.Price,
+			ImageUrl:     li.ImageURL,
+		}
+		item.Image.ImageUrl = li.ImageURL
+		order.LineItems = append(order.LineItems, item)
+	}
+
+	return order
+}
+
+// ToRecord converts a listing into its local store representation
+func (l Listing) ToRecord() store.Listing {
+	return store.Listing{
+		ListingID:  l.ListingID,
+		SKU:        l.SKU,
+		Title:      l.Title,
+		Price:      l.Price,
+		Currency:   l.Currency,
+		Shipping:   l.Shipping,
+		Quantity:   l.Quantity,
+		Condition:  l.Condition,
+		ImageURL:   l.ImageURL,
+		ListingURL: l.ListingURL,
+	}
+}
+
+// ListingFromRecord converts a stored listing back into a Listing
+func ListingFromRecord(r store.Listing) Listing {
+	return Listing{
+		ListingID:  r.ListingID,
+		SKU:        r.SKU,
+		Title:      r.Title,
+		Price:      r.Price,
+		Currency:   r.Currency,
+		Shipping:   r.Shipping,
+		Quantity:   r.Quantity,
+		Condition:  r.Condition,
+		ImageURL:   r.ImageURL,
+		ListingURL: r.ListingURL,
+	}
+}
+
+// ToRecord converts an offer into its local store representation
+func (o Offer) ToRecord() store.Offer {
+	return store.Offer{
+		OfferID:       o.OfferID,
+		ItemID:        o.ItemID,
+		ItemTitle:     o.ItemTitle,
+		BuyerUsername: o.BuyerUsername,
+		BuyerUserID:   o.BuyerUserID,
+		OfferPrice:    o.OfferPrice,
+		ListPrice:     o.ListPrice,
+		Currency:      o.Currency,
+		Status:        o.Status,
+		CreatedDate:   o.CreatedDate,
+	}
+}
@@ -0,0 +1,218 @@
+package ebay
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OAuth scopes the bot uses
+const (
+	ScopeBasic         = "https://api.ebay.com/oauth/api_scope"
+	ScopeInventory     = ScopeBasic + "/sell.inventory"
+	ScopeFulfillment   = ScopeBasic + "/sell.fulfillment"
+	ScopeAccount       = ScopeBasic + "/sell.account"
+	ScopeFinances      = ScopeBasic + "/sell.finances"
+	ScopeIdentity      = ScopeBasic + "/commerce.identity.readonly"
+	ScopeNotifications = ScopeBasic + "/commerce.notification.subscription"
+)
+
+// Scopes are all the scopes the bot requests, in the order they are requested
+var Scopes = []string{
+	ScopeBasic,
+	ScopeInventory,
+	ScopeFulfillment,
+	ScopeAccount,
+	ScopeFinances,
+	ScopeIdentity,
+	ScopeNotifications,
+}
+
+// scopeDescriptions say what each scope lets the bot do
+var scopeDescriptions = map[string]string{
+	ScopeBasic:         "Basic API access (listings, item images)",
+	ScopeInventory:     "Offers from buyers",
+	ScopeFulfillment:   "View & manage orders",
+	ScopeAccount:       "Account settings & connection check",
+	ScopeFinances:      "Balance & payouts",
+	ScopeIdentity:      "Seller username",
+	ScopeNotifications: "Webhook destinations & subscriptions",
+}
+
+// methodScopes are the user-token scopes each client method needs
+var methodScopes = map[string][]string{
+	"CheckConnection":          {ScopeAccount},
+	"GetOrders":                {ScopeFulfillment},
+	"GetOrderByID":             {ScopeFulfillment},
+	"GetOrdersModifiedSince":   {ScopeFulfillment},
+	"GetOffers":                {ScopeInventory},
+	"RespondToOffer":           {ScopeInventory},
+	"GetSellerUsername":        {ScopeIdentity},
+	"GetTokenInfo":             {ScopeIdentity},
+	"Identify":                 {ScopeIdentity},
+	"GetListingsPage":          {ScopeBasic},
+	"GetSellerBalance":         {ScopeFinances},
+	"GetPayouts":               {ScopeFinances},
+	"GetDestinations":          {ScopeNotifications},
+	"CreateDestination":        {ScopeNotifications},
+	"UpdateDestination":        {ScopeNotifications},
+	"DeleteDestination":        {ScopeNotifications},
+	"GetSubscriptions":         {ScopeNotifications},
+	"GetSubscription":          {ScopeNotifications},
+	"CreateSubscription":       {ScopeNotifications},
+	"UpdateSubscription":       {ScopeNotifications},
+	"DeleteSubscription":       {ScopeNotifications},
+	"EnableSubscription":       {ScopeNotifications},
+	"DisableSubscription":      {ScopeNotifications},
+	"TestSubscription":         {ScopeNotifications},
+	"CreateSubscriptionFilter": {ScopeNotifications},
+	"GetSubscriptionFilter":    {ScopeNotifications},
+	"DeleteSubscriptionFilter": {ScopeNotifications},
+}
+
+// ScopeName shortens a scope URL for display, e.g. sell.finances
+func ScopeName(scope string) string {
+	if scope == ScopeBasic {
+		return "api_scope"
+	}
+	return strings.TrimPrefix(scope, ScopeBasic+"/")
+}
+
+// ScopeDescription says what a scope lets the bot do
+func ScopeDescription(scope string) string {
+	return scopeDescriptions[scope]
+}
+
+// RequiredScopes returns the scopes a client method needs
+func RequiredScopes(method string) []string {
+	return methodScopes[method]
+}
+
+// MethodsNeeding returns the client methods that need scope, sorted
+func MethodsNeeding(scope string) []string {
+	var methods []string
+	for method, scopes := range methodScopes {
+		for _, s := range scopes {
+			if s == scope {
+				methods = append(methods, method)
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// ScopeError is returned when the seller's authorization doesn't include the scopes a
+// call needs, before the call is made
+type ScopeError struct {
+	Method  string
+	Missing []string
+}
+
+func (e *ScopeError) Error() string {
+	names := make([]string, len(e.Missing))
+	for n, scope := range e.Missing {
+		names[n] = ScopeName(scope)
+	}
+	return fmt.Sprintf("this command needs %s, which the eBay authorization doesn't include - run /ebay-authorize with missing-only to grant it",
+		strings.Join(names, " and "))
+}
+
+// TokenScopes compares the scopes granted to the seller's token with the ones the bot needs
+type TokenScopes struct {
+	HasToken    bool
+	Environment string
+	Granted     []string // nil when the grant isn't known (tokens from .env)
+	Assumed     bool     // Granted is what was requested; eBay didn't list the grant
+	Required    []string
+	Missing     []string
+}
+
+// GrantedScopes returns the scopes eBay granted at authorization, or nil if unknown
+func (c *Client) GrantedScopes() []string {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	if c.scope == "" {
+		return nil
+	}
+	return strings.Fields(c.scope)
+}
+
+// ScopesAssumed reports whether the granted scopes are the ones requested at
+// authorization rather than a list eBay returned
+func (c *Client) ScopesAssumed() bool {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.scope != "" && c.scopeAssumed
+}
+
+// MissingScopes returns which of scopes haven't been granted. Nothing is reported
+// missing when the grant isn't known.
+func (c *Client) MissingScopes(scopes []string) []string {
+	granted := c.GrantedScopes()
+	if granted == nil {
+		return nil
+	}
+
+	var missing []string
+	for _, scope := range scopes {
+		if !containsScope(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// WithMissingScopes returns the granted scopes followed by those of scopes not yet
+// granted. A new authorization replaces the old token, so it has to ask for both.
+func (c *Client) WithMissingScopes(scopes []string) []string {
+	union := c.GrantedScopes()
+	for _, scope := range scopes {
+		if !containsScope(union, scope) {
+			union = append(union, scope)
+		}
+	}
+	return union
+}
+
+// GetTokenScopes reports the granted scopes against every scope the bot uses
+func (c *Client) GetTokenScopes() TokenScopes {
+	return TokenScopes{
+		HasToken:    c.config.AccessToken != "",
+		Environment: c.config.Environment,
+		Granted:     c.GrantedScopes(),
+		Assumed:     c.ScopesAssumed(),
+		Required:    Scopes,
+		Missing:     c.MissingScopes(Scopes),
+	}
+}
+
+// requireScopes fails with a ScopeError if method needs scopes that weren't granted.
+// An assumed grant isn't authoritative, so eBay is left to refuse the call instead.
+func (c *Client) requireScopes(method string) error {
+	if c.ScopesAssumed() {
+		return nil
+	}
+	if missing := c.MissingScopes(methodScopes[method]); len(missing) > 0 {
+		return &ScopeError{Method: method, Missing: missing}
+	}
+	return nil
+}
+
+// refreshScopes are the scopes to ask for when refreshing: eBay rejects a refresh that
+// asks for more than the refresh token was granted
+func (c *Client) refreshScopes() string {
+	if granted := c.GrantedScopes(); granted != nil {
+		return strings.Join(granted, " ")
+	}
+	return strings.Join(Scopes, " ")
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package ebay
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ebaymanager-bot/internal/config"
+)
+
+func TestMethodScopesNameClientMethods(t *testing.T) {
+	client := reflect.TypeOf(&Client{})
+	for method, scopes := range methodScopes {
+		if _, ok := client.MethodByName(method); !ok {
+			t.Errorf("methodScopes has %s, which isn't a Client method", method)
+		}
+		for _, scope := range scopes {
+			if ScopeDescription(scope) == "" {
+				t.Errorf("%s needs %s, which isn't in the scope registry", method, scope)
+			}
+		}
+	}
+}
+
+func TestScopePreflight(t *testing.T) {
+	client := NewClient(config.EbayConfig{AccessToken: "token"})
+
+	// Without a recorded grant nothing is refused
+	if err := client.requireScopes("GetSellerBalance"); err != nil {
+		t.Errorf("requireScopes() with an unknown grant = %v, want nil", err)
+	}
+
+	client.recordRefreshToken(0, ScopeBasic+" "+ScopeFulfillment, false)
+	_, err := client.GetSellerBalance()
+	var scopeErr *ScopeError
+	if !errors.As(err, &scopeErr) || scopeErr.Method != "GetSellerBalance" {
+		t.Fatalf("GetSellerBalance() = %v, want a ScopeError", err)
+	}
+	if !strings.Contains(err.Error(), "needs sell.finances") {
+		t.Errorf("Error() = %q, want it to name sell.finances", err)
+	}
+	if err := client.requireScopes("GetOrders"); err != nil {
+		t.Errorf("requireScopes(GetOrders) = %v, want nil", err)
+	}
+
+	report := client.GetTokenScopes()
+	if len(report.Granted) != 2 || len(report.Missing) != len(Scopes)-2 {
+		t.Errorf("GetTokenScopes() = %+v", report)
+	}
+	if got := client.refreshScopes(); got != ScopeBasic+" "+ScopeFulfillment {
+		t.Errorf("refreshScopes() = %q, want the granted scopes", got)
+	}
+
+	// A grant eBay didn't list is only assumed, so it doesn't refuse calls
+	client.recordRefreshToken(0, ScopeBasic, true)
+	if err := client.requireScopes("GetSellerBalance"); err != nil {
+		t.Errorf("requireScopes() with an assumed grant = %v, want nil", err)
+	}
+	if report := client.GetTokenScopes(); !report.Assumed || len(report.Granted) != 1 {
+		t.Errorf("GetTokenScopes() with an assumed grant = %+v", report)
+	}
+}
+
+func TestAuthorizationURLScopes(t *testing.T) {
+	client := NewClient(config.EbayConfig{AppID: "app", Environment: "SANDBOX"})
+	client.recordRefreshToken(0, ScopeBasic+" "+ScopeFulfillment, false)
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   []string
+	}{
+		{"all", nil, Scopes},
+		// Missing-only keeps what was granted, as the new token replaces the old one
+		{"missing only", client.WithMissingScopes([]string{ScopeFinances, ScopeIdentity}), []string{ScopeBasic, ScopeFulfillment, ScopeFinances, ScopeIdentity}},
+		{"nothing new", client.WithMissingScopes([]string{ScopeBasic}), []string{ScopeBasic, ScopeFulfillment}},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(client.GetUserAuthorizationURL("state", tt.scopes...))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := strings.Fields(u.Query().Get("scope")); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: scope = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ebay
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// APIStatus is the client's view of its connection to eBay, as reported by /readyz
+type APIStatus struct {
+	Environment   string    `json:"environment"`
+	HasToken      bool      `json:"hasToken"`
+	TokenExpiry   time.Time `json:"tokenExpiry,omitempty"`   // zero when the token came from .env and its expiry is unknown
+	TokenRejected bool      `json:"tokenRejected,omitempty"` // eBay answered 401 to the current token
+	RefreshExpiry time.Time `json:"refreshExpiry,omitempty"` // when re-authorization is needed; zero if unknown
+	LastSuccess   time.Time `json:"lastSuccess,omitempty"`   // last successful call made with the seller's token
+	LastError     string    `json:"lastError,omitempty"`
+	LastErrorAt   time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// TokenExpired reports whether the access token is known to be unusable
+func (s APIStatus) TokenExpired(now time.Time) bool {
+	return s.TokenRejected || (!s.TokenExpiry.IsZero() && now.After(s.TokenExpiry))
+}
+
+// Status returns the token and last-call state of the client
+func (c *Client) Status() APIStatus {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	status := c.status
+	status.Environment = c.config.Environment
+	status.HasToken = c.config.AccessToken != ""
+	status.RefreshExpiry = c.refreshExpiry
+	return status
+}
+
+// recordCall notes the outcome of a call made with the seller's token
+func (c *Client) recordCall(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	if err == nil {
+		c.status.LastSuccess = time.Now()
+		c.status.TokenRejected = false
+		return
+	}
+	c.status.LastError = err.Error()
+	c.status.LastErrorAt = time.Now()
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		c.status.TokenRejected = true
+	}
+}
+
+// recordToken notes a newly issued access token and when it expires
+func (c *Client) recordToken(expiresIn int) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	c.status.TokenRejected = false
+	c.status.TokenExpiry = time.Time{}
+	if expiresIn > 0 {
+		c.status.TokenExpiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+}
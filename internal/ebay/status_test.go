@@ -0,0 +1,40 @@
+package ebay
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStatusTracksCalls(t *testing.T) {
+	code := http.StatusUnauthorized
+	client := newNotificationTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		w.Write([]byte(`{}`))
+	}))
+
+	if status := client.Status(); !status.HasToken || !status.LastSuccess.IsZero() || status.TokenExpired(time.Now()) {
+		t.Fatalf("Unexpected initial status: %+v", status)
+	}
+
+	if _, err := client.makeRequest("GET", "/sell/account/v1/privilege", nil); err == nil {
+		t.Fatal("Expected the 401 to fail")
+	}
+	status := client.Status()
+	if !status.TokenRejected || !status.TokenExpired(time.Now()) || status.LastError == "" {
+		t.Errorf("Expected the rejected token to be reported, got %+v", status)
+	}
+
+	code = http.StatusOK
+	if _, err := client.makeRequest("GET", "/sell/account/v1/privilege", nil); err != nil {
+		t.Fatalf("makeRequest failed: %v", err)
+	}
+	if status := client.Status(); status.TokenRejected || status.LastSuccess.IsZero() {
+		t.Errorf("Expected a successful call to clear the rejection, got %+v", status)
+	}
+
+	client.recordToken(60)
+	if status := client.Status(); status.TokenExpired(time.Now()) || !status.TokenExpired(time.Now().Add(2*time.Minute)) {
+		t.Errorf("Expected the token to expire after 60s, got %+v", status)
+	}
+}
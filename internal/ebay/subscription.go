@@ -7,173 +7,553 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// Subscription represents an eBay notification subscription
-type Subscription struct {
-	DestinationID  string                 `json:"destinationId"`
-	Name           string                 `json:"name"`
-	Status         string                 `json:"status"`
-	DeliveryConfig map[string]interface{} `json:"deliveryConfig"`
-	Topics         []map[string]string    `json:"topics"`
+const (
+	notificationAPIPath = "/commerce/notification/v1"
+
+	// payloadMajorVersion is the payload schema the webhook server decodes
+	payloadMajorVersion = "1"
+)
+
+// WebhookTopics are the topics /webhook-subscribe subscribes the bot's endpoint to.
+// MARKETPLACE_ACCOUNT_DELETION is not included - its endpoint (/webhook/ebay/account-deletion)
+// is configured in the developer portal.
+var WebhookTopics = []string{
+	"MARKETPLACE_OFFER", // Offer events (most important for your use case)
+	"MARKETPLACE_ORDER", // All order events
+	"ITEM_INVENTORY",    // Inventory changes
 }
 
-// CreateWebhookSubscription subscribes to eBay notifications
-func (c *Client) CreateWebhookSubscription(webhookURL string) error {
-	if c.config.AccessToken == "" {
-		return fmt.Errorf("no access token - run /ebay-authorize first")
-	}
+// Subscription and destination statuses
+const (
+	StatusEnabled  = "ENABLED"
+	StatusDisabled = "DISABLED"
+)
 
-	url := c.baseURL + "/commerce/notification/v1/destination"
+// NotificationTopic is a topic that can be subscribed to
+type NotificationTopic struct {
+	TopicID             string         `json:"topicId"`
+	Description         string         `json:"description"`
+	Status              string         `json:"status"`  // ENABLED, DISABLED
+	Context             string         `json:"context"` // BUYER, SELLER, ...
+	Scope               string         `json:"scope"`   // APPLICATION or USER
+	Filterable          bool           `json:"filterable"`
+	AuthorizationScopes []string       `json:"authorizationScopes"`
+	SupportedPayloads   []TopicPayload `json:"supportedPayloads"`
+}
 
-	// Subscribe to all important notification topics
-	// Note: MARKETPLACE_ACCOUNT_DELETION removed - not required even with exemption
-	topics := []string{
-		"MARKETPLACE_OFFER", // Offer events (most important for your use case)
-		"MARKETPLACE_ORDER", // All order events
-		"ITEM_INVENTORY",    // Inventory changes
-	}
+// TopicPayload is a payload format a topic can be delivered in
+type TopicPayload struct {
+	Format           []string `json:"format"`
+	SchemaVersion    string   `json:"schemaVersion"`
+	DeliveryProtocol string   `json:"deliveryProtocol"`
+	Deprecated       bool     `json:"deprecated"`
+}
 
-	// Log verification token for debugging
-	log.Printf("[DEBUG] Creating subscription with verify token: '%s' (length: %d)", c.config.WebhookVerifyToken, len(c.config.WebhookVerifyToken))
+// SchemaVersion returns the newest non-deprecated schema version of the topic with the
+// given major version ("<major>.0" if it lists none)
+func (t NotificationTopic) SchemaVersion(major string) string {
+	version := ""
+	for _, p := range t.SupportedPayloads {
+		if !p.Deprecated && strings.HasPrefix(p.SchemaVersion, major+".") && (version == "" || compareVersions(p.SchemaVersion, version) > 0) {
+			version = p.SchemaVersion
+		}
+	}
+	if version == "" {
+		return major + ".0"
+	}
+	return version
+}
 
-	// Build subscription payload
-	payload := map[string]interface{}{
-		"name":   "Discord_Bot_Notifications",
-		"status": "ENABLED",
-		"deliveryConfig": map[string]interface{}{
-			"endpoint":    webhookURL,
-			"verifyToken": c.config.WebhookVerifyToken,
-		},
-		"topics": buildTopics(topics),
+// compareVersions orders dotted versions component by component, numerically where both
+// components are numbers, so 1.10 is newer than 1.9. Missing components count as 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case xErr == nil && yErr == nil && xn != yn:
+			if xn < yn {
+				return -1
+			}
+			return 1
+		case (xErr != nil || yErr != nil) && x != y:
+			return strings.Compare(x, y)
+		}
 	}
+	return 0
+}
+
+// Destination is an endpoint eBay delivers notifications to
+type Destination struct {
+	DestinationID  string         `json:"destinationId,omitempty"`
+	Name           string         `json:"name"`
+	Status         string         `json:"status"`
+	DeliveryConfig DeliveryConfig `json:"deliveryConfig"`
+}
+
+// DeliveryConfig is where and how a destination receives notifications
+type DeliveryConfig struct {
+	Endpoint          string `json:"endpoint"`
+	VerificationToken string `json:"verificationToken,omitempty"`
+}
+
+// NotificationSubscription delivers one topic to one destination
+type NotificationSubscription struct {
+	SubscriptionID string              `json:"subscriptionId,omitempty"`
+	TopicID        string              `json:"topicId"`
+	Status         string              `json:"status"`
+	DestinationID  string              `json:"destinationId"`
+	FilterID       string              `json:"filterId,omitempty"`
+	CreationDate   string              `json:"creationDate,omitempty"`
+	Payload        SubscriptionPayload `json:"payload"`
+}
+
+// SubscriptionPayload is the format a subscription's notifications are delivered in
+type SubscriptionPayload struct {
+	Format           string `json:"format"`
+	SchemaVersion    string `json:"schemaVersion"`
+	DeliveryProtocol string `json:"deliveryProtocol"`
+}
+
+// SubscriptionFilter narrows which notifications of a subscription are delivered,
+// using a JSON Schema matched against the payload
+type SubscriptionFilter struct {
+	FilterID       string          `json:"filterId"`
+	SubscriptionID string          `json:"subscriptionId"`
+	FilterStatus   string          `json:"filterStatus"` // PENDING, ENABLED, REJECTED, DISABLED
+	FilterSchema   json.RawMessage `json:"filterSchema"`
+	CreationDate   string          `json:"creationDate"`
+}
 
-	jsonData, err := json.Marshal(payload)
+// CreateWebhookSubscription points notifications at webhookURL. It reuses the destination
+// for the endpoint (creating it when missing), then makes sure each of WebhookTopics has an
+// enabled subscription to it. Running it again is safe.
+func (c *Client) CreateWebhookSubscription(webhookURL string) ([]NotificationSubscription, error) {
+	dest, err := c.EnsureDestination(webhookURL)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := c.GetSubscriptions()
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, err
 	}
 
-	log.Printf("[DEBUG] Subscription payload: %s", string(jsonData))
+	subs := make([]NotificationSubscription, 0, len(WebhookTopics))
+	for _, topicID := range WebhookTopics {
+		sub, err := c.ensureSubscription(topicID, dest.DestinationID, existing)
+		if err != nil {
+			return subs, fmt.Errorf("failed to subscribe to %s: %w", topicID, err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, nil
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+// EnsureDestination returns the destination for endpoint, creating an enabled one if none exists
+func (c *Client) EnsureDestination(endpoint string) (*Destination, error) {
+	destinations, err := c.GetDestinations()
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+	for i := range destinations {
+		if destinations[i].DeliveryConfig.Endpoint == endpoint {
+			return &destinations[i], nil
+		}
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	dest := Destination{
+		Name:   "Discord_Bot_Notifications",
+		Status: StatusEnabled,
+		DeliveryConfig: DeliveryConfig{
+			Endpoint:          endpoint,
+			VerificationToken: c.config.WebhookVerifyToken,
+		},
+	}
+	id, err := c.CreateDestination(dest)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	dest.DestinationID = id
+	log.Printf("✅ Created notification destination %s for %s", id, endpoint)
+	return &dest, nil
+}
+
+// ensureSubscription returns the subscription of topicID to destinationID from existing,
+// enabling it if needed, or creates one
+func (c *Client) ensureSubscription(topicID, destinationID string, existing []NotificationSubscription) (*NotificationSubscription, error) {
+	for i := range existing {
+		sub := &existing[i]
+		if sub.TopicID != topicID || sub.DestinationID != destinationID {
+			continue
+		}
+		if sub.Status != StatusEnabled {
+			if err := c.EnableSubscription(sub.SubscriptionID); err != nil {
+				return nil, err
+			}
+			sub.Status = StatusEnabled
+		}
+		return sub, nil
+	}
+	return c.SubscribeTopic(topicID, destinationID)
+}
 
-	body, _ := io.ReadAll(resp.Body)
+// SubscribeTopic subscribes destinationID to topicID using the newest payload schema the
+// webhook server can decode
+func (c *Client) SubscribeTopic(topicID, destinationID string) (*NotificationSubscription, error) {
+	schemaVersion := payloadMajorVersion + ".0"
+	if topic, err := c.GetNotificationTopic(topicID); err != nil {
+		log.Printf("⚠️ Failed to look up topic %s, using schema version %s: %v", topicID, schemaVersion, err)
+	} else {
+		schemaVersion = topic.SchemaVersion(payloadMajorVersion)
+	}
+	return c.CreateSubscription(topicID, destinationID, schemaVersion)
+}
 
-	if resp.StatusCode >= 400 {
-		log.Printf("[DEBUG] eBay API error response: %s", string(body))
-		log.Printf("[DEBUG] Response headers: %v", resp.Header)
-		// Extract rlogid from response headers if present
-		rlogid := resp.Header.Get("Rlogid")
-		if rlogid == "" {
-			rlogid = resp.Header.Get("X-Ebay-C-Request-Id")
+// WebhookVerifyToken returns the verification token eBay challenges destinations with
+func (c *Client) WebhookVerifyToken() string {
+	return c.config.WebhookVerifyToken
+}
+
+// GetNotificationTopics lists every topic the application can subscribe to
+func (c *Client) GetNotificationTopics() ([]NotificationTopic, error) {
+	var topics []NotificationTopic
+	for endpoint := notificationAPIPath + "/topic?limit=100"; endpoint != ""; {
+		var page struct {
+			Topics []NotificationTopic `json:"topics"`
+			Next   string              `json:"next"`
 		}
-		if rlogid == "" {
-			rlogid = resp.Header.Get("X-EBAY-C-REQUEST-ID")
+		if err := c.notificationGet(endpoint, true, &page); err != nil {
+			return nil, fmt.Errorf("failed to list topics: %w", err)
 		}
-		if rlogid != "" {
-			log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-			log.Printf("rlogid: %s", rlogid)
-			log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		topics = append(topics, page.Topics...)
+		endpoint = c.nextPage(page.Next)
+	}
+	return topics, nil
+}
+
+// GetNotificationTopic returns a single topic's details
+func (c *Client) GetNotificationTopic(topicID string) (*NotificationTopic, error) {
+	var topic NotificationTopic
+	if err := c.notificationGet(notificationAPIPath+"/topic/"+url.PathEscape(topicID), true, &topic); err != nil {
+		return nil, fmt.Errorf("failed to get topic %s: %w", topicID, err)
+	}
+	return &topic, nil
+}
+
+// GetDestinations lists the application's notification destinations
+func (c *Client) GetDestinations() ([]Destination, error) {
+	if err := c.requireScopes("GetDestinations"); err != nil {
+		return nil, err
+	}
+	var destinations []Destination
+	for endpoint := notificationAPIPath + "/destination?limit=100"; endpoint != ""; {
+		var page struct {
+			Destinations []Destination `json:"destinations"`
+			Next         string        `json:"next"`
 		}
-		return fmt.Errorf("failed to create subscription (status %d): %s", resp.StatusCode, string(body))
+		if err := c.notificationGet(endpoint, false, &page); err != nil {
+			return nil, fmt.Errorf("failed to list destinations: %w", err)
+		}
+		destinations = append(destinations, page.Destinations...)
+		endpoint = c.nextPage(page.Next)
 	}
+	return destinations, nil
+}
 
-	log.Printf("[DEBUG] Subscription created successfully. Response: %s", string(body))
-	return nil
+// CreateDestination registers a destination and returns its ID. eBay sends a challenge
+// to the endpoint first, so the webhook server must be reachable.
+func (c *Client) CreateDestination(dest Destination) (string, error) {
+	if err := c.requireScopes("CreateDestination"); err != nil {
+		return "", err
+	}
+	_, id, err := c.notificationRequest(http.MethodPost, notificationAPIPath+"/destination", dest, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to create destination: %w", err)
+	}
+	return id, nil
 }
 
-// ListWebhookSubscriptions returns all active webhook subscriptions
-func (c *Client) ListWebhookSubscriptions() ([]Subscription, error) {
-	if c.config.AccessToken == "" {
-		return nil, fmt.Errorf("no access token - run /ebay-authorize first")
+// UpdateDestination replaces a destination's name, status and delivery config
+func (c *Client) UpdateDestination(dest Destination) error {
+	if err := c.requireScopes("UpdateDestination"); err != nil {
+		return err
+	}
+	id := dest.DestinationID
+	dest.DestinationID = ""
+	if _, _, err := c.notificationRequest(http.MethodPut, notificationAPIPath+"/destination/"+url.PathEscape(id), dest, false); err != nil {
+		return fmt.Errorf("failed to update destination %s: %w", id, err)
 	}
+	return nil
+}
 
-	url := c.baseURL + "/commerce/notification/v1/destination"
+// DeleteDestination removes a destination. eBay refuses while subscriptions still use it.
+func (c *Client) DeleteDestination(destinationID string) error {
+	if err := c.requireScopes("DeleteDestination"); err != nil {
+		return err
+	}
+	if _, _, err := c.notificationRequest(http.MethodDelete, notificationAPIPath+"/destination/"+url.PathEscape(destinationID), nil, false); err != nil {
+		return fmt.Errorf("failed to delete destination %s: %w", destinationID, err)
+	}
+	return nil
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// GetSubscriptions lists the seller's notification subscriptions
+func (c *Client) GetSubscriptions() ([]NotificationSubscription, error) {
+	if err := c.requireScopes("GetSubscriptions"); err != nil {
+		return nil, err
+	}
+	var subs []NotificationSubscription
+	for endpoint := notificationAPIPath + "/subscription?limit=100"; endpoint != ""; {
+		var page struct {
+			Subscriptions []NotificationSubscription `json:"subscriptions"`
+			Next          string                     `json:"next"`
+		}
+		if err := c.notificationGet(endpoint, false, &page); err != nil {
+			return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+		}
+		subs = append(subs, page.Subscriptions...)
+		endpoint = c.nextPage(page.Next)
 	}
+	return subs, nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+// GetSubscription returns a single subscription
+func (c *Client) GetSubscription(subscriptionID string) (*NotificationSubscription, error) {
+	if err := c.requireScopes("GetSubscription"); err != nil {
+		return nil, err
+	}
+	var sub NotificationSubscription
+	if err := c.notificationGet(notificationAPIPath+"/subscription/"+url.PathEscape(subscriptionID), false, &sub); err != nil {
+		return nil, fmt.Errorf("failed to get subscription %s: %w", subscriptionID, err)
+	}
+	return &sub, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// CreateSubscription subscribes destinationID to topicID with JSON payloads over HTTPS
+func (c *Client) CreateSubscription(topicID, destinationID, schemaVersion string) (*NotificationSubscription, error) {
+	if err := c.requireScopes("CreateSubscription"); err != nil {
+		return nil, err
+	}
+	sub := NotificationSubscription{
+		TopicID:       topicID,
+		Status:        StatusEnabled,
+		DestinationID: destinationID,
+		Payload: SubscriptionPayload{
+			Format:           "JSON",
+			SchemaVersion:    schemaVersion,
+			DeliveryProtocol: "HTTPS",
+		},
+	}
+	_, id, err := c.notificationRequest(http.MethodPost, notificationAPIPath+"/subscription", sub, false)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("failed to create subscription to %s: %w", topicID, err)
 	}
-	defer resp.Body.Close()
+	sub.SubscriptionID = id
+	log.Printf("✅ Subscribed to %s (subscription %s)", topicID, id)
+	return &sub, nil
+}
 
-	body, _ := io.ReadAll(resp.Body)
+// UpdateSubscription changes a subscription's status, payload or destination
+func (c *Client) UpdateSubscription(sub NotificationSubscription) error {
+	if err := c.requireScopes("UpdateSubscription"); err != nil {
+		return err
+	}
+	update := struct {
+		Status        string              `json:"status"`
+		Payload       SubscriptionPayload `json:"payload"`
+		DestinationID string              `json:"destinationId"`
+	}{sub.Status, sub.Payload, sub.DestinationID}
+	if _, _, err := c.notificationRequest(http.MethodPut, notificationAPIPath+"/subscription/"+url.PathEscape(sub.SubscriptionID), update, false); err != nil {
+		return fmt.Errorf("failed to update subscription %s: %w", sub.SubscriptionID, err)
+	}
+	return nil
+}
 
-	log.Printf("[DEBUG] List subscriptions response status: %d", resp.StatusCode)
-	log.Printf("[DEBUG] List subscriptions response body: %s", string(body))
+// DeleteSubscription removes a subscription
+func (c *Client) DeleteSubscription(subscriptionID string) error {
+	if err := c.requireScopes("DeleteSubscription"); err != nil {
+		return err
+	}
+	return c.subscriptionAction(http.MethodDelete, subscriptionID, "", "delete")
+}
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("failed to list subscriptions (status %d): %s", resp.StatusCode, string(body))
+// EnableSubscription resumes delivery for a disabled subscription
+func (c *Client) EnableSubscription(subscriptionID string) error {
+	if err := c.requireScopes("EnableSubscription"); err != nil {
+		return err
 	}
+	return c.subscriptionAction(http.MethodPost, subscriptionID, "/enable", "enable")
+}
 
-	var result struct {
-		Destinations []Subscription `json:"destinations"`
+// DisableSubscription pauses delivery without deleting the subscription
+func (c *Client) DisableSubscription(subscriptionID string) error {
+	if err := c.requireScopes("DisableSubscription"); err != nil {
+		return err
 	}
+	return c.subscriptionAction(http.MethodPost, subscriptionID, "/disable", "disable")
+}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+// TestSubscription asks eBay to send a test notification to the subscription's destination
+func (c *Client) TestSubscription(subscriptionID string) error {
+	if err := c.requireScopes("TestSubscription"); err != nil {
+		return err
 	}
+	return c.subscriptionAction(http.MethodPost, subscriptionID, "/test", "test")
+}
 
-	log.Printf("[DEBUG] Parsed %d destinations from response", len(result.Destinations))
+func (c *Client) subscriptionAction(method, subscriptionID, suffix, action string) error {
+	endpoint := notificationAPIPath + "/subscription/" + url.PathEscape(subscriptionID) + suffix
+	if _, _, err := c.notificationRequest(method, endpoint, nil, false); err != nil {
+		return fmt.Errorf("failed to %s subscription %s: %w", action, subscriptionID, err)
+	}
+	return nil
+}
 
-	return result.Destinations, nil
+// CreateSubscriptionFilter attaches a filter to a subscription and returns its ID. The filter
+// starts out PENDING while eBay validates the schema; only filterable topics accept one.
+func (c *Client) CreateSubscriptionFilter(subscriptionID string, schema json.RawMessage) (string, error) {
+	if err := c.requireScopes("CreateSubscriptionFilter"); err != nil {
+		return "", err
+	}
+	body := map[string]json.RawMessage{"filterSchema": schema}
+	_, id, err := c.notificationRequest(http.MethodPost, notificationAPIPath+"/subscription/"+url.PathEscape(subscriptionID)+"/filter", body, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to create filter for subscription %s: %w", subscriptionID, err)
+	}
+	return id, nil
 }
 
-// DeleteWebhookSubscription removes a webhook subscription
-func (c *Client) DeleteWebhookSubscription(destinationID string) error {
-	if c.config.AccessToken == "" {
-		return fmt.Errorf("no access token - run /ebay-authorize first")
+// GetSubscriptionFilter returns a subscription's filter
+func (c *Client) GetSubscriptionFilter(subscriptionID, filterID string) (*SubscriptionFilter, error) {
+	if err := c.requireScopes("GetSubscriptionFilter"); err != nil {
+		return nil, err
 	}
+	var filter SubscriptionFilter
+	endpoint := notificationAPIPath + "/subscription/" + url.PathEscape(subscriptionID) + "/filter/" + url.PathEscape(filterID)
+	if err := c.notificationGet(endpoint, false, &filter); err != nil {
+		return nil, fmt.Errorf("failed to get filter %s: %w", filterID, err)
+	}
+	return &filter, nil
+}
 
-	url := c.baseURL + "/commerce/notification/v1/destination/" + destinationID
+// DeleteSubscriptionFilter removes a subscription's filter so every notification is delivered again
+func (c *Client) DeleteSubscriptionFilter(subscriptionID, filterID string) error {
+	if err := c.requireScopes("DeleteSubscriptionFilter"); err != nil {
+		return err
+	}
+	endpoint := notificationAPIPath + "/subscription/" + url.PathEscape(subscriptionID) + "/filter/" + url.PathEscape(filterID)
+	if _, _, err := c.notificationRequest(http.MethodDelete, endpoint, nil, false); err != nil {
+		return fmt.Errorf("failed to delete filter %s: %w", filterID, err)
+	}
+	return nil
+}
 
-	req, err := http.NewRequest("DELETE", url, nil)
+// notificationGet fetches endpoint and decodes the JSON response into out
+func (c *Client) notificationGet(endpoint string, appToken bool, out interface{}) error {
+	body, _, err := c.notificationRequest(http.MethodGet, endpoint, nil, appToken)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+// notificationRequest calls the Notification API. Topics are read with an application
+// token; destinations and subscriptions belong to the seller and use their token. It
+// returns the response body and, for create calls, the ID from the Location header.
+func (c *Client) notificationRequest(method, endpoint string, payload interface{}, appToken bool) ([]byte, string, error) {
+	start := time.Now()
+	body, id, err := c.doNotificationRequest(method, endpoint, payload, appToken)
+	observeCall(method, endpoint, start, err)
+	if !appToken {
+		c.recordCall(err)
+	}
+	return body, id, err
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// doNotificationRequest performs a Notification API call with the chosen token
+func (c *Client) doNotificationRequest(method, endpoint string, payload interface{}, appToken bool) ([]byte, string, error) {
+	var token string
+	if appToken {
+		t, err := c.applicationToken()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get application token: %w", err)
+		}
+		token = t
+	} else {
+		if c.config.AccessToken == "" {
+			return nil, "", fmt.Errorf("no access token - run /ebay-authorize first")
+		}
+		token = c.config.AccessToken
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	fullURL := c.baseURL + endpoint
+	req, err := http.NewRequest(method, fullURL, reqBody)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	log.Printf("[API] %s %s", method, fullURL)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
 	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete subscription (status %d): %s", resp.StatusCode, string(body))
+		log.Printf("[API ERROR] %s %s => HTTP %d: %s", method, fullURL, resp.StatusCode, string(body))
+		return nil, "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	return nil
+	var id string
+	if location := resp.Header.Get("Location"); location != "" {
+		id = path.Base(location)
+	}
+	return body, id, nil
 }
 
-// buildTopics creates the topics array for subscription
-func buildTopics(topicNames []string) []map[string]string {
-	topics := make([]map[string]string, len(topicNames))
-	for i, name := range topicNames {
-		topics[i] = map[string]string{"topicName": name}
+// nextPage turns a paginated response's next URL into an endpoint ("" on the last page)
+func (c *Client) nextPage(next string) string {
+	if next == "" {
+		return ""
+	}
+	if u, err := url.Parse(next); err == nil && u.IsAbs() {
+		return u.RequestURI()
 	}
-	return topics
+	return strings.TrimPrefix(next, c.baseURL)
 }
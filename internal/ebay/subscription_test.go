@@ -0,0 +1,179 @@
+package ebay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/config"
+)
+
+// fakeNotificationAPI serves the parts of the Notification API used by CreateWebhookSubscription
+type fakeNotificationAPI struct {
+	mu            sync.Mutex
+	destinations  []Destination
+	subscriptions []NotificationSubscription
+	calls         []string
+}
+
+func (f *fakeNotificationAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, notificationAPIPath))
+
+	switch path := strings.TrimPrefix(r.URL.Path, notificationAPIPath); {
+	case r.Method == http.MethodGet && path == "/destination":
+		json.NewEncoder(w).Encode(map[string]interface{}{"destinations": f.destinations})
+	case r.Method == http.MethodPost && path == "/destination":
+		var d Destination
+		json.NewDecoder(r.Body).Decode(&d)
+		d.DestinationID = fmt.Sprintf("dest-%d", len(f.destinations)+1)
+		f.destinations = append(f.destinations, d)
+		w.Header().Set("Location", "https://api.ebay.com"+notificationAPIPath+"/destination/"+d.DestinationID)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && path == "/subscription":
+		json.NewEncoder(w).Encode(map[string]interface{}{"subscriptions": f.subscriptions})
+	case r.Method == http.MethodPost && path == "/subscription":
+		var s NotificationSubscription
+		json.NewDecoder(r.Body).Decode(&s)
+		s.SubscriptionID = fmt.Sprintf("sub-%d", len(f.subscriptions)+1)
+		f.subscriptions = append(f.subscriptions, s)
+		w.Header().Set("Location", "https://api.ebay.com"+notificationAPIPath+"/subscription/"+s.SubscriptionID)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/enable"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/subscription/"), "/enable")
+		for i := range f.subscriptions {
+			if f.subscriptions[i].SubscriptionID == id {
+				f.subscriptions[i].Status = StatusEnabled
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/topic/"):
+		json.NewEncoder(w).Encode(NotificationTopic{
+			TopicID: strings.TrimPrefix(path, "/topic/"),
+			SupportedPayloads: []TopicPayload{
+				{SchemaVersion: "1.0", Deprecated: true},
+				{SchemaVersion: "1.1"},
+				{SchemaVersion: "2.0"},
+			},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newNotificationTestClient(t *testing.T, api http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	client := NewClient(config.EbayConfig{AccessToken: "user-token", WebhookVerifyToken: "verify-me"})
+	client.baseURL = srv.URL
+	client.appToken = "app-token"
+	client.appTokenExpiry = time.Now().Add(time.Hour)
+	return client
+}
+
+func TestTopicSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		payloads []TopicPayload
+		want     string
+	}{
+		{"none listed", nil, "1.0"},
+		{"newest minor", []TopicPayload{{SchemaVersion: "1.1"}, {SchemaVersion: "1.2"}}, "1.2"},
+		{"two-digit minor", []TopicPayload{{SchemaVersion: "1.9"}, {SchemaVersion: "1.10"}, {SchemaVersion: "1.2"}}, "1.10"},
+		{"patch versions", []TopicPayload{{SchemaVersion: "1.2.10"}, {SchemaVersion: "1.2.9"}}, "1.2.10"},
+		{"deprecated skipped", []TopicPayload{{SchemaVersion: "1.1"}, {SchemaVersion: "1.3", Deprecated: true}}, "1.1"},
+		{"other majors ignored", []TopicPayload{{SchemaVersion: "1.1"}, {SchemaVersion: "10.0"}, {SchemaVersion: "2.0"}}, "1.1"},
+	}
+	for _, tt := range tests {
+		if got := (NotificationTopic{SupportedPayloads: tt.payloads}).SchemaVersion("1"); got != tt.want {
+			t.Errorf("%s: SchemaVersion() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCreateWebhookSubscription(t *testing.T) {
+	api := &fakeNotificationAPI{
+		destinations: []Destination{
+			{DestinationID: "old", DeliveryConfig: DeliveryConfig{Endpoint: "https://old.example.com/hook"}},
+		},
+		subscriptions: []NotificationSubscription{
+			{SubscriptionID: "existing", TopicID: "MARKETPLACE_ORDER", DestinationID: "dest-2", Status: StatusDisabled},
+		},
+	}
+	client := newNotificationTestClient(t, api)
+
+	subs, err := client.CreateWebhookSubscription("https://bot.example.com/webhook/ebay/notification")
+	if err != nil {
+		t.Fatalf("CreateWebhookSubscription failed: %v", err)
+	}
+	if len(subs) != len(WebhookTopics) {
+		t.Fatalf("Expected %d subscriptions, got %+v", len(WebhookTopics), subs)
+	}
+
+	dest := api.destinations[1]
+	if dest.DestinationID != "dest-2" || dest.DeliveryConfig.VerificationToken != "verify-me" || dest.Status != StatusEnabled {
+		t.Errorf("Unexpected destination: %+v", dest)
+	}
+	for _, sub := range subs {
+		if sub.DestinationID != "dest-2" || sub.Status != StatusEnabled {
+			t.Errorf("Expected an enabled subscription to dest-2, got %+v", sub)
+		}
+		if sub.TopicID == "MARKETPLACE_ORDER" && sub.SubscriptionID != "existing" {
+			t.Errorf("Expected the existing order subscription to be reused, got %+v", sub)
+		}
+		if sub.TopicID != "MARKETPLACE_ORDER" && sub.Payload.SchemaVersion != "1.1" {
+			t.Errorf("Expected the newest 1.x schema, got %+v", sub.Payload)
+		}
+	}
+
+	// A second run reuses everything
+	api.calls = nil
+	if _, err := client.CreateWebhookSubscription("https://bot.example.com/webhook/ebay/notification"); err != nil {
+		t.Fatalf("Second CreateWebhookSubscription failed: %v", err)
+	}
+	for _, call := range api.calls {
+		if strings.HasPrefix(call, http.MethodPost) {
+			t.Errorf("Expected no changes on the second run, got %v", api.calls)
+			break
+		}
+	}
+}
+
+func TestGetNotificationTopicsPaginates(t *testing.T) {
+	var tokens []string
+	var client *Client
+	client = newNotificationTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		if r.URL.Query().Get("continuation_token") == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"topics": []NotificationTopic{{TopicID: "MARKETPLACE_ORDER"}},
+				"next":   client.baseURL + notificationAPIPath + "/topic?limit=100&continuation_token=abc",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"topics": []NotificationTopic{{TopicID: "ITEM_INVENTORY"}},
+		})
+	}))
+
+	topics, err := client.GetNotificationTopics()
+	if err != nil {
+		t.Fatalf("GetNotificationTopics failed: %v", err)
+	}
+	if len(topics) != 2 || topics[1].TopicID != "ITEM_INVENTORY" {
+		t.Errorf("Expected both pages of topics, got %+v", topics)
+	}
+	for _, token := range tokens {
+		if token != "Bearer app-token" {
+			t.Errorf("Expected topics to be read with the application token, got %q", token)
+		}
+	}
+}
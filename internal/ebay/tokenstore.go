@@ -0,0 +1,468 @@
+package ebay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Tokens are the seller's OAuth tokens as persisted between runs
+type Tokens struct {
+	AccessToken        string    `json:"accessToken"`
+	AccessTokenExpiry  time.Time `json:"accessTokenExpiry,omitempty"`
+	RefreshToken       string    `json:"refreshToken,omitempty"`
+	RefreshTokenExpiry time.Time `json:"refreshTokenExpiry,omitempty"`
+	Scope              string    `json:"scope,omitempty"`        // space-separated scopes granted at authorization
+	ScopeAssumed       bool      `json:"scopeAssumed,omitempty"` // Scope is the requested set; eBay didn't list the grant
+	Environment        string    `json:"environment,omitempty"`  // PRODUCTION or SANDBOX; tokens only work in one
+	UserID             string    `json:"userId,omitempty"`       // the seller's eBay user ID
+	Username           string    `json:"username,omitempty"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+
+	// ReminderSentDays is the most urgent re-authorization reminder (days before the
+	// refresh token expires) already posted for this refresh token
+	ReminderSentDays int `json:"reminderSentDays,omitempty"`
+}
+
+// TokenStore persists the seller's tokens
+type TokenStore interface {
+	// Load returns the saved tokens, or nil if none have been saved
+	Load() (*Tokens, error)
+	Save(tokens *Tokens) error
+}
+
+// Encrypted token file format
+const (
+	tokenFileVersion = 1
+	kdfScrypt        = "scrypt" // key derived from a passphrase with a per-file salt
+	kdfKeyFile       = "keyfile"
+)
+
+// encryptedTokens is the on-disk form of an encrypted token file
+type encryptedTokens struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileTokenStore keeps every account's tokens in a JSON file readable only by the bot's user, written
+// atomically so a crash mid-write never leaves a truncated file. Tokens are encrypted
+// with AES-256-GCM when the store has a key.
+type FileTokenStore struct {
+	mu   sync.Mutex // serializes read-modify-write of the file across accounts
+	path string
+	kdf  string                            // empty for plaintext
+	key  func(salt []byte) ([]byte, error) // returns the AES-256 key for salt
+}
+
+// NewFileTokenStore stores tokens in plaintext at path
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// NewPassphraseTokenStore encrypts tokens at path with a key derived from passphrase
+func NewPassphraseTokenStore(path, passphrase string) *FileTokenStore {
+	return &FileTokenStore{path: path, kdf: kdfScrypt, key: func(salt []byte) ([]byte, error) {
+		return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	}}
+}
+
+// NewKeyFileTokenStore encrypts tokens at path with the 32-byte key in keyFile, stored
+// as hex, base64 or raw bytes
+func NewKeyFileTokenStore(path, keyFile string) (*FileTokenStore, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token key file: %w", err)
+	}
+	key, err := parseTokenKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token key file %s: %w", keyFile, err)
+	}
+	return &FileTokenStore{path: path, kdf: kdfKeyFile, key: func([]byte) ([]byte, error) {
+		return key, nil
+	}}, nil
+}
+
+// parseTokenKey decodes a 32-byte key
+func parseTokenKey(data []byte) ([]byte, error) {
+	if len(data) == 32 {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("expected a 32-byte key (generate one with: openssl rand -hex 32)")
+}
+
+// Path returns the file backing the store
+func (s *FileTokenStore) Path() string {
+	return s.path
+}
+
+// Encrypted reports whether tokens are encrypted at rest
+func (s *FileTokenStore) Encrypted() bool {
+	return s.kdf != ""
+}
+
+// tokenFile is what a token file holds: each seller account's tokens by account name.
+// Files written before accounts existed hold a single Tokens, read as DefaultAccount's.
+type tokenFile struct {
+	Accounts map[string]*Tokens `json:"accounts"`
+}
+
+// Load returns DefaultAccount's tokens
+func (s *FileTokenStore) Load() (*Tokens, error) {
+	return s.LoadAccount(DefaultAccount)
+}
+
+// Save replaces DefaultAccount's tokens
+func (s *FileTokenStore) Save(tokens *Tokens) error {
+	return s.SaveAccount(DefaultAccount, tokens)
+}
+
+// LoadAccount returns the named account's tokens, or nil if none have been saved
+func (s *FileTokenStore) LoadAccount(name string) (*Tokens, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return accounts[name], nil
+}
+
+// SaveAccount replaces the named account's tokens, keeping every other account's
+func (s *FileTokenStore) SaveAccount(name string, tokens *Tokens) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts, err := s.read()
+	if err != nil {
+		return err
+	}
+	accounts[name] = tokens
+	return s.write(accounts)
+}
+
+// RenameAccount moves the tokens saved for from to to, unless to already has tokens.
+// It lets the first account in EBAY_ACCOUNTS keep the tokens saved before it was named.
+func (s *FileTokenStore) RenameAccount(from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens, ok := accounts[from]
+	if !ok || accounts[to] != nil {
+		return nil
+	}
+	accounts[to] = tokens
+	delete(accounts, from)
+	log.Printf("🔑 Moving saved eBay tokens from account %s to %s", from, to)
+	return s.write(accounts)
+}
+
+// Account returns a TokenStore for one account's tokens in the file
+func (s *FileTokenStore) Account(name string) TokenStore {
+	return &accountTokenStore{file: s, name: name}
+}
+
+// accountTokenStore is one account's view of a FileTokenStore
+type accountTokenStore struct {
+	file *FileTokenStore
+	name string
+}
+
+func (a *accountTokenStore) Load() (*Tokens, error) {
+	return a.file.LoadAccount(a.name)
+}
+
+func (a *accountTokenStore) Save(tokens *Tokens) error {
+	return a.file.SaveAccount(a.name, tokens)
+}
+
+// read parses the token file. A plaintext file is still read by an encrypting store,
+// so existing files are encrypted on the next save.
+func (s *FileTokenStore) read() (map[string]*Tokens, error) {
+	accounts := make(map[string]*Tokens)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return accounts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var envelope encryptedTokens
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", s.path, err)
+	}
+	if envelope.KDF != "" {
+		if data, err = s.decrypt(&envelope); err != nil {
+			return nil, err
+		}
+	}
+
+	var file tokenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", s.path, err)
+	}
+	if file.Accounts != nil {
+		for name, tokens := range file.Accounts {
+			if tokens != nil {
+				accounts[name] = tokens
+			}
+		}
+		return accounts, nil
+	}
+
+	var legacy Tokens
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", s.path, err)
+	}
+	if legacy.AccessToken != "" || legacy.RefreshToken != "" {
+		accounts[DefaultAccount] = &legacy
+	}
+	return accounts, nil
+}
+
+// write replaces the token file with accounts
+func (s *FileTokenStore) write(accounts map[string]*Tokens) error {
+	data, err := json.MarshalIndent(tokenFile{Accounts: accounts}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+	if s.Encrypted() {
+		envelope, err := s.encrypt(data)
+		if err != nil {
+			return err
+		}
+		if data, err = json.MarshalIndent(envelope, "", "  "); err != nil {
+			return fmt.Errorf("failed to encode tokens: %w", err)
+		}
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+func (s *FileTokenStore) encrypt(plaintext []byte) (*encryptedTokens, error) {
+	envelope := &encryptedTokens{Version: tokenFileVersion, KDF: s.kdf}
+	if s.kdf == kdfScrypt {
+		envelope.Salt = make([]byte, 16)
+		if _, err := rand.Read(envelope.Salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+	aead, err := s.aead(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(envelope.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, nil)
+	return envelope, nil
+}
+
+func (s *FileTokenStore) decrypt(envelope *encryptedTokens) ([]byte, error) {
+	if !s.Encrypted() {
+		return nil, fmt.Errorf("token file %s is encrypted: set TOKEN_STORE_PASSPHRASE or TOKEN_STORE_KEY_FILE", s.path)
+	}
+	if envelope.KDF != s.kdf {
+		return nil, fmt.Errorf("token file %s was encrypted with a %s key, not a %s key", s.path, envelope.KDF, s.kdf)
+	}
+	aead, err := s.aead(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt token file: wrong passphrase or key")
+	}
+	return plaintext, nil
+}
+
+func (s *FileTokenStore) aead(salt []byte) (cipher.AEAD, error) {
+	key, err := s.key(salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFileAtomic replaces path with data through a 0600 temp file in the same directory
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set token file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync tokens: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close token file: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace token file: %w", err)
+	}
+	return nil
+}
+
+// OpenTokenStore returns the token store for path: encrypted with passphrase or the key in
+// keyFile when either is set, plaintext otherwise
+func OpenTokenStore(path, passphrase, keyFile string) (*FileTokenStore, error) {
+	switch {
+	case passphrase != "" && keyFile != "":
+		return nil, fmt.Errorf("set a token passphrase or a key file, not both")
+	case passphrase != "":
+		return NewPassphraseTokenStore(path, passphrase), nil
+	case keyFile != "":
+		return NewKeyFileTokenStore(path, keyFile)
+	}
+	return NewFileTokenStore(path), nil
+}
+
+// SetTokenStore makes store the place tokens are persisted. Tokens saved there replace
+// the ones the client was configured with; if it is empty, the configured tokens (from
+// .env) are saved to it.
+func (c *Client) SetTokenStore(store TokenStore) error {
+	c.tokenStore = store
+
+	saved, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if saved != nil && saved.Environment != "" && saved.Environment != c.config.Environment {
+		log.Printf("⚠️ Saved tokens for account %s are for %s, not %s - ignoring them until /ebay-authorize is run",
+			c.Account(), saved.Environment, c.config.Environment)
+		return nil
+	}
+	if saved == nil {
+		if c.config.AccessToken == "" && c.config.RefreshToken == "" {
+			return nil
+		}
+		log.Println("🔑 Moving eBay tokens from the environment to the token store")
+		return c.SaveTokens()
+	}
+
+	c.config.AccessToken = saved.AccessToken
+	if saved.RefreshToken != "" {
+		c.config.RefreshToken = saved.RefreshToken
+	}
+
+	c.statusMu.Lock()
+	c.status.TokenExpiry = saved.AccessTokenExpiry
+	c.refreshExpiry = saved.RefreshTokenExpiry
+	c.scope = saved.Scope
+	c.scopeAssumed = saved.ScopeAssumed
+	c.reminderSent = saved.ReminderSentDays
+	c.userID = saved.UserID
+	c.username = saved.Username
+	c.statusMu.Unlock()
+	return nil
+}
+
+// SaveTokens persists the client's current tokens and their expiry times
+func (c *Client) SaveTokens() error {
+	if c.tokenStore == nil {
+		return fmt.Errorf("no token store configured")
+	}
+
+	c.statusMu.Lock()
+	tokens := &Tokens{
+		AccessToken:        c.config.AccessToken,
+		AccessTokenExpiry:  c.status.TokenExpiry,
+		RefreshToken:       c.config.RefreshToken,
+		RefreshTokenExpiry: c.refreshExpiry,
+		Scope:              c.scope,
+		ScopeAssumed:       c.scopeAssumed,
+		Environment:        c.config.Environment,
+		UserID:             c.userID,
+		Username:           c.username,
+		UpdatedAt:          time.Now().UTC(),
+		ReminderSentDays:   c.reminderSent,
+	}
+	c.statusMu.Unlock()
+
+	if err := c.tokenStore.Save(tokens); err != nil {
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+	log.Printf("✅ eBay tokens saved for account %s", c.Account())
+	return nil
+}
+
+// recordRefreshToken notes a newly issued refresh token's expiry and granted scopes.
+// assumed marks scope as the requested set rather than one eBay listed.
+func (c *Client) recordRefreshToken(expiresIn int, scope string, assumed bool) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	c.refreshExpiry = time.Time{}
+	if expiresIn > 0 {
+		c.refreshExpiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+	if scope != "" {
+		c.scope = scope
+		c.scopeAssumed = assumed
+	}
+	c.reminderSent = 0
+}
+
+// ReminderSent returns the most urgent re-authorization reminder, in days before the
+// refresh token expires, already posted for the current refresh token (0 for none)
+func (c *Client) ReminderSent() int {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.reminderSent
+}
+
+// RecordReminder notes that the reminder for days was posted and saves it with the tokens
+func (c *Client) RecordReminder(days int) error {
+	c.statusMu.Lock()
+	c.reminderSent = days
+	c.statusMu.Unlock()
+	return c.SaveTokens()
+}
@@ -0,0 +1,148 @@
+package ebay
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/config"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "tokens.key")
+	os.WriteFile(keyFile, []byte(strings.Repeat("ab", 32)+"\n"), 0600)
+	keyStore, err := NewKeyFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"), keyFile)
+	if err != nil {
+		t.Fatalf("NewKeyFileTokenStore: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		store     *FileTokenStore
+		encrypted bool
+	}{
+		{"plaintext", NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json")), false},
+		{"passphrase", NewPassphraseTokenStore(filepath.Join(t.TempDir(), "tokens.json"), "correct horse"), true},
+		{"key file", keyStore, true},
+	}
+
+	expiry := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tokens, err := tt.store.Load(); tokens != nil || err != nil {
+				t.Fatalf("Load() before saving = %v, %v", tokens, err)
+			}
+
+			want := &Tokens{AccessToken: "v^1.1#access", RefreshToken: "v^1.1#refresh", RefreshTokenExpiry: expiry}
+			if err := tt.store.Save(want); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			got, err := tt.store.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if got.AccessToken != want.AccessToken || got.RefreshToken != want.RefreshToken || !got.RefreshTokenExpiry.Equal(expiry) {
+				t.Errorf("Load() = %+v, want %+v", got, want)
+			}
+
+			info, err := os.Stat(tt.store.Path())
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if perm := info.Mode().Perm(); perm != 0600 {
+				t.Errorf("Token file mode = %o, want 600", perm)
+			}
+			data, _ := os.ReadFile(tt.store.Path())
+			if leaked := strings.Contains(string(data), "v^1.1#refresh"); leaked == tt.encrypted {
+				t.Errorf("Refresh token in file = %v, encrypted = %v", leaked, tt.encrypted)
+			}
+		})
+	}
+}
+
+func TestEncryptedTokenStoreRejectsWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := NewPassphraseTokenStore(path, "correct horse").Save(&Tokens{AccessToken: "secret"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if _, err := NewPassphraseTokenStore(path, "battery staple").Load(); err == nil {
+		t.Error("Expected a wrong passphrase to fail")
+	}
+	if _, err := NewFileTokenStore(path).Load(); err == nil {
+		t.Error("Expected an encrypted file to need a key")
+	}
+}
+
+func TestPlaintextTokensAreEncryptedOnSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	NewFileTokenStore(path).Save(&Tokens{AccessToken: "secret"})
+
+	store := NewPassphraseTokenStore(path, "correct horse")
+	tokens, err := store.Load()
+	if err != nil || tokens.AccessToken != "secret" {
+		t.Fatalf("Expected the plaintext file to load, got %+v, %v", tokens, err)
+	}
+	store.Save(tokens)
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "secret") {
+		t.Error("Expected the token file to be encrypted after saving")
+	}
+}
+
+func TestSetTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	// An empty store is seeded with the tokens from the environment
+	client := NewClient(config.EbayConfig{AccessToken: "env-access", RefreshToken: "env-refresh"})
+	if err := client.SetTokenStore(NewFileTokenStore(path)); err != nil {
+		t.Fatalf("SetTokenStore: %v", err)
+	}
+	if saved, _ := NewFileTokenStore(path).Load(); saved == nil || saved.RefreshToken != "env-refresh" {
+		t.Fatalf("Expected the environment tokens to be saved, got %+v", saved)
+	}
+
+	// Saved tokens win over the environment
+	client.config.AccessToken = "new-access"
+	client.recordToken(7200)
+	client.recordRefreshToken(3600*24*30, "scope-a scope-b", false)
+	if err := client.SaveTokens(); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+
+	restarted := NewClient(config.EbayConfig{AccessToken: "env-access", RefreshToken: "env-refresh"})
+	if err := restarted.SetTokenStore(NewFileTokenStore(path)); err != nil {
+		t.Fatalf("SetTokenStore: %v", err)
+	}
+	if restarted.config.AccessToken != "new-access" || restarted.scope != "scope-a scope-b" {
+		t.Errorf("Expected the saved tokens to be loaded, got %q / %q", restarted.config.AccessToken, restarted.scope)
+	}
+	if status := restarted.Status(); status.TokenExpiry.IsZero() || restarted.refreshExpiry.Before(time.Now().Add(29*24*time.Hour)) {
+		t.Errorf("Expected the expiry times to be loaded, got %v / %v", status.TokenExpiry, restarted.refreshExpiry)
+	}
+}
+
+func TestReminderStateFollowsRefreshToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	client := NewClient(config.EbayConfig{})
+	client.SetTokenStore(NewFileTokenStore(path))
+	client.config.RefreshToken = "first"
+	client.recordRefreshToken(3600*24*20, "", false)
+
+	if err := client.RecordReminder(30); err != nil {
+		t.Fatalf("RecordReminder: %v", err)
+	}
+	if saved, _ := NewFileTokenStore(path).Load(); saved.ReminderSentDays != 30 || saved.RefreshTokenExpiry.IsZero() {
+		t.Errorf("Expected the reminder and expiry to be saved, got %+v", saved)
+	}
+
+	// A new refresh token has had no reminders
+	client.recordRefreshToken(3600*24*540, "", false)
+	if sent := client.ReminderSent(); sent != 0 {
+		t.Errorf("ReminderSent() after re-authorizing = %d, want 0", sent)
+	}
+	if expiry := client.Status().RefreshExpiry; time.Until(expiry) < 500*24*time.Hour {
+		t.Errorf("Expected the new refresh expiry in Status, got %v", expiry)
+	}
+}
@@ -1,11 +1,15 @@
 package ebay
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Order represents an eBay order
 type Order struct {
 	OrderID                      string                   `json:"orderId"`
 	CreationDate                 time.Time                `json:"creationDate"`
+	LastModifiedDate             time.Time                `json:"lastModifiedDate"`
 	Buyer                        Buyer                    `json:"buyer"`
 	BuyerUsername                string                   `json:"buyerUsername"` // Computed field
 	PricingSummary               PricingSummary           `json:"pricingSummary"`
@@ -13,6 +17,7 @@ type Order struct {
 	Currency                     string                   `json:"currency"`   // Computed field
 	FulfillmentStartInstructions []FulfillmentInstruction `json:"fulfillmentStartInstructions"`
 	OrderFulfillmentStatus       string                   `json:"orderFulfillmentStatus"`
+	OrderPaymentStatus           string                   `json:"orderPaymentStatus"`
 	FulfillmentStatus            string                   `json:"fulfillmentStatus"` // Computed field
 	LineItems                    []LineItem               `json:"lineItems"`
 }
@@ -20,6 +25,7 @@ type Order struct {
 // Buyer represents the buyer information
 type Buyer struct {
 	Username string `json:"username"`
+	UserID   string `json:"userId,omitempty"` // immutable eBay user ID, when eBay includes it
 }
 
 // PricingSummary contains order pricing details
@@ -53,6 +59,30 @@ type LineItem struct {
 	} `json:"image"`
 	ImageUrl     string `json:"imageUrl"` // Computed field
 	LegacyItemId string `json:"legacyItemId"`
+
+	LineItemFulfillmentInstructions struct {
+		ShipByDate time.Time `json:"shipByDate"`
+	} `json:"lineItemFulfillmentInstructions"`
+}
+
+// ShipTo returns the order's shipping address (zero if it has none)
+func (o Order) ShipTo() Address {
+	if len(o.FulfillmentStartInstructions) == 0 {
+		return Address{}
+	}
+	return o.FulfillmentStartInstructions[0].ShippingStep.ShipTo
+}
+
+// ShipByDate returns the earliest ship-by date of the order's line items (zero if unknown)
+func (o Order) ShipByDate() time.Time {
+	var earliest time.Time
+	for _, li := range o.LineItems {
+		d := li.LineItemFulfillmentInstructions.ShipByDate
+		if !d.IsZero() && (earliest.IsZero() || d.Before(earliest)) {
+			earliest = d
+		}
+	}
+	return earliest
 }
 
 // Address represents a shipping address
@@ -66,6 +96,41 @@ type Address struct {
 	Country    string `json:"country"`
 }
 
+// UnmarshalJSON reads the Fulfillment API shipTo shape (fullName plus a nested
+// contactAddress), falling back to the flat field names
+func (a *Address) UnmarshalJSON(data []byte) error {
+	type flat Address
+	var wire struct {
+		flat
+		FullName       string `json:"fullName"`
+		ContactAddress *struct {
+			AddressLine1    string `json:"addressLine1"`
+			AddressLine2    string `json:"addressLine2"`
+			City            string `json:"city"`
+			StateOrProvince string `json:"stateOrProvince"`
+			PostalCode      string `json:"postalCode"`
+			CountryCode     string `json:"countryCode"`
+		} `json:"contactAddress"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	*a = Address(wire.flat)
+	if wire.FullName != "" {
+		a.Name = wire.FullName
+	}
+	if c := wire.ContactAddress; c != nil {
+		a.Street1 = c.AddressLine1
+		a.Street2 = c.AddressLine2
+		a.City = c.City
+		a.State = c.StateOrProvince
+		a.PostalCode = c.PostalCode
+		a.Country = c.CountryCode
+	}
+	return nil
+}
+
 // Listing represents an active eBay inventory listing
 type Listing struct {
 	SKU        string
@@ -86,6 +151,7 @@ type Offer struct {
 	ItemID        string    `json:"itemId"`
 	ItemTitle     string    `json:"itemTitle"`
 	BuyerUsername string    `json:"buyerUsername"`
+	BuyerUserID   string    `json:"buyerUserId,omitempty"`
 	OfferPrice    float64   `json:"offerPrice"`
 	ListPrice     float64   `json:"listPrice"`
 	Currency      string    `json:"currency"`
@@ -1,6 +1,7 @@
 package ebay
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -124,3 +125,41 @@ func TestAddressValidation(t *testing.T) {
 		t.Error("PostalCode should not be empty")
 	}
 }
+
+func TestOrderShippingFromFulfillmentAPI(t *testing.T) {
+	body := `{
+		"orderId": "12-34567-89012",
+		"fulfillmentStartInstructions": [{
+			"shippingStep": {
+				"shipTo": {
+					"fullName": "Jane Buyer",
+					"contactAddress": {
+						"addressLine1": "1 Market St",
+						"city": "San Jose",
+						"stateOrProvince": "CA",
+						"postalCode": "95113",
+						"countryCode": "US"
+					}
+				}
+			}
+		}],
+		"lineItems": [
+			{"lineItemId": "1", "lineItemFulfillmentInstructions": {"shipByDate": "2024-05-04T06:59:59.000Z"}},
+			{"lineItemId": "2", "lineItemFulfillmentInstructions": {"shipByDate": "2024-05-03T06:59:59.000Z"}}
+		]
+	}`
+
+	var order Order
+	if err := json.Unmarshal([]byte(body), &order); err != nil {
+		t.Fatalf("Failed to parse order: %v", err)
+	}
+
+	want := Address{Name: "Jane Buyer", Street1: "1 Market St", City: "San Jose", State: "CA", PostalCode: "95113", Country: "US"}
+	if got := order.ShipTo(); got != want {
+		t.Errorf("ShipTo() = %+v, want %+v", got, want)
+	}
+
+	if got := order.ShipByDate(); !got.Equal(time.Date(2024, 5, 3, 6, 59, 59, 0, time.UTC)) {
+		t.Errorf("ShipByDate() = %v, want the earliest line item date", got)
+	}
+}
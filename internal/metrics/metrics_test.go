@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	c := NewCounterVec("test_requests_total", "Test requests", "method", "path")
+	c.Inc("GET", "/a")
+	c.Inc("GET", "/a")
+	c.Add(3, "POST", `/b"c`)
+
+	out := scrape(t)
+	for _, want := range []string{
+		"# HELP test_requests_total Test requests\n# TYPE test_requests_total counter\n",
+		`test_requests_total{method="GET",path="/a"} 2` + "\n",
+		`test_requests_total{method="POST",path="/b\"c"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "Test latency", []float64{0.1, 1}, "op")
+	h.Observe(0.05, "read")
+	h.Observe(0.5, "read")
+	h.Observe(5, "read")
+
+	out := scrape(t)
+	for _, want := range []string{
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{op="read",le="0.1"} 1` + "\n",
+		`test_duration_seconds_bucket{op="read",le="1"} 2` + "\n",
+		`test_duration_seconds_bucket{op="read",le="+Inf"} 3` + "\n",
+		`test_duration_seconds_sum{op="read"} 5.55` + "\n",
+		`test_duration_seconds_count{op="read"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestGaugeFuncReplaces(t *testing.T) {
+	NewGaugeFunc("test_queue_depth", "Test depth", "status", func() map[string]float64 { return map[string]float64{"pending": 1} })
+	NewGaugeFunc("test_queue_depth", "Test depth", "status", func() map[string]float64 { return map[string]float64{"pending": 4, "dead": 2} })
+
+	out := scrape(t)
+	if strings.Count(out, "# TYPE test_queue_depth gauge") != 1 {
+		t.Errorf("Expected one test_queue_depth family in:\n%s", out)
+	}
+	for _, want := range []string{`test_queue_depth{status="dead"} 2`, `test_queue_depth{status="pending"} 4`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
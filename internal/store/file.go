@@ -0,0 +1,990 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxNotifications bounds how many raw notifications are kept on disk
+	maxNotifications = 5000
+	// maxSeenNotifications bounds the dedup key set
+	maxSeenNotifications = 10000
+	// flushDelay is how long history and dedup changes wait to share a write with others.
+	// They are lost if the process dies first, or if the write they wait for fails.
+	flushDelay = time.Second
+)
+
+// database is the document persisted to disk
+type database struct {
+	SchemaVersion int                      `json:"schemaVersion"`
+	Orders        map[string]*Order        `json:"orders"`
+	Offers        map[string]*Offer        `json:"offers"`
+	Listings      map[string]*Listing      `json:"listings"`
+	Payouts       map[string]*Payout       `json:"payouts"`
+	Notifications map[string]*Notification `json:"notifications"`
+	SyncCursors   map[string]*SyncCursor   `json:"syncCursors"`
+	PollMarks     map[string]*PollMark     `json:"pollMarks"`
+	// DeletionAudits is append-only
+	DeletionAudits []*DeletionAudit `json:"deletionAudits"`
+	// SeenNotifications maps dedup keys to when they expire
+	SeenNotifications map[string]time.Time `json:"seenNotifications"`
+	// Outbox holds Discord messages until they are delivered
+	Outbox map[string]*OutboxMessage `json:"outbox"`
+	Routes map[string]*Route         `json:"routes"`
+	// OrderTrackers are keyed by order ID
+	OrderTrackers map[string]*OrderTracker `json:"orderTrackers"`
+}
+
+// FileStore is a Repository backed by a single JSON file on disk.
+// Every write is flushed atomically (temp file + rename), and a change whose write fails
+// is rolled back. Notification history and dedup keys are flushed shortly after they
+// change, together with whatever else changed by then.
+type FileStore struct {
+	path string
+	mu   sync.RWMutex
+	db   *database
+
+	saved      []byte      // the database as last written
+	dirty      bool        // changes are waiting for flushTimer
+	flushTimer *time.Timer // pending deferred flush
+
+	lastWrite  time.Time // last successful flush
+	writeErr   error     // error from the last flush, nil once one succeeds
+	writeErrAt time.Time
+}
+
+var _ Repository = (*FileStore)(nil)
+
+// Open loads (or creates) the store at path and applies pending migrations
+func Open(path string) (*FileStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+
+	db := &database{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, db); err != nil {
+			return nil, fmt.Errorf("failed to parse store %s: %w", path, err)
+		}
+	}
+
+	from := db.SchemaVersion
+	applied, err := migrate(db)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{path: path, db: db, saved: data}
+	if applied || len(data) == 0 {
+		if err := s.flush(); err != nil {
+			return nil, err
+		}
+		log.Printf("🗄️ Store migrated from schema v%d to v%d (%s)", from, db.SchemaVersion, path)
+	}
+
+	return s, nil
+}
+
+// Path returns the file backing the store
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// flush writes the database to disk and records the outcome for Health. If the write
+// fails, the database is rolled back to what was last written, so a later flush can't
+// persist a change its caller was told had failed. Callers must hold s.mu.
+func (s *FileStore) flush() error {
+	data, err := s.write()
+	if err != nil {
+		s.writeErr, s.writeErrAt = err, time.Now()
+		s.rollback()
+		return err
+	}
+	s.saved, s.dirty = data, false
+	s.lastWrite, s.writeErr = time.Now(), nil
+	return nil
+}
+
+// flushSoon schedules a flush for changes that can wait a moment, so a burst of them
+// shares one write - or none, when a flush comes first. Callers must hold s.mu.
+func (s *FileStore) flushSoon() error {
+	s.dirty = true
+	if s.flushTimer == nil {
+		s.flushTimer = time.AfterFunc(flushDelay, s.flushPending)
+	}
+	return nil
+}
+
+// flushPending writes changes scheduled by flushSoon
+func (s *FileStore) flushPending() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flushTimer = nil
+	if !s.dirty {
+		return
+	}
+	if err := s.flush(); err != nil {
+		log.Printf("⚠️ Failed to write store changes, history and dedup changes since the last write are lost: %v", err)
+	}
+}
+
+// rollback restores the database as it was last written. Callers must hold s.mu.
+func (s *FileStore) rollback() {
+	db := &database{}
+	if err := json.Unmarshal(s.saved, db); err != nil {
+		log.Printf("⚠️ Failed to roll back store changes: %v", err)
+		return
+	}
+	s.db, s.dirty = db, false
+}
+
+// write atomically replaces the store file with the encoded database and returns what
+// was written
+func (s *FileStore) write() ([]byte, error) {
+	data, err := json.MarshalIndent(s.db, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to sync store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close store: %w", err)
+	}
+	if err := os.Chmod(tmpName, 0600); err != nil {
+		return nil, fmt.Errorf("failed to set store permissions: %w", err)
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		return nil, fmt.Errorf("failed to replace store: %w", err)
+	}
+
+	return data, nil
+}
+
+// Health reports whether the store is persisting writes
+func (s *FileStore) Health() StoreHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h := StoreHealth{Path: s.path, LastWrite: s.lastWrite}
+	if s.writeErr != nil {
+		h.LastError = s.writeErr.Error()
+		h.LastErrorAt = s.writeErrAt
+	}
+	return h
+}
+
+// Close flushes the store to disk, including changes waiting for a deferred flush
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	return s.flush()
+}
+
+// UpsertOrder inserts or replaces an order
+func (s *FileStore) UpsertOrder(order *Order) error {
+	if order == nil || order.OrderID == "" {
+		return fmt.Errorf("order ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := *order
+	o.UpdatedAt = time.Now()
+	s.db.Orders[o.OrderID] = &o
+	return s.flush()
+}
+
+// UpsertOrders inserts or replaces a batch of orders with a single write. Nothing is
+// stored if any order lacks an ID.
+func (s *FileStore) UpsertOrders(orders []Order) error {
+	for _, o := range orders {
+		if o.OrderID == "" {
+			return fmt.Errorf("order ID is required")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range orders {
+		o := orders[i]
+		o.UpdatedAt = now
+		s.db.Orders[o.OrderID] = &o
+	}
+	return s.flush()
+}
+
+// GetOrder returns the order with the given ID
+func (s *FileStore) GetOrder(orderID string) (*Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.db.Orders[orderID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := *o
+	return &out, nil
+}
+
+// ListOrders returns orders newest first. A limit of 0 returns all orders.
+func (s *FileStore) ListOrders(limit int) ([]Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := make([]Order, 0, len(s.db.Orders))
+	for _, o := range s.db.Orders {
+		orders = append(orders, *o)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreationDate.After(orders[j].CreationDate)
+	})
+
+	return truncate(orders, limit), nil
+}
+
+// UpsertOffer inserts or replaces an offer
+func (s *FileStore) UpsertOffer(offer *Offer) error {
+	if offer == nil || offer.OfferID == "" {
+		return fmt.Errorf("offer ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := *offer
+	o.UpdatedAt = time.Now()
+	s.db.Offers[o.OfferID] = &o
+	return s.flush()
+}
+
+// GetOffer returns the offer with the given ID
+func (s *FileStore) GetOffer(offerID string) (*Offer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.db.Offers[offerID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := *o
+	return &out, nil
+}
+
+// ListOffers returns offers newest first, optionally filtered by status
+func (s *FileStore) ListOffers(status string, limit int) ([]Offer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	offers := make([]Offer, 0, len(s.db.Offers))
+	for _, o := range s.db.Offers {
+		if status != "" && o.Status != status {
+			continue
+		}
+		offers = append(offers, *o)
+	}
+	sort.Slice(offers, func(i, j int) bool {
+		return offers[i].CreatedDate.After(offers[j].CreatedDate)
+	})
+
+	return truncate(offers, limit), nil
+}
+
+// UpsertListing inserts or replaces a listing
+func (s *FileStore) UpsertListing(listing *Listing) error {
+	if listing == nil || listing.ListingID == "" {
+		return fmt.Errorf("listing ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := *listing
+	l.UpdatedAt = time.Now()
+	s.db.Listings[l.ListingID] = &l
+	return s.flush()
+}
+
+// UpsertListings inserts or replaces a batch of listings with a single write. Nothing
+// is stored if any listing lacks an ID.
+func (s *FileStore) UpsertListings(listings []Listing) error {
+	for _, l := range listings {
+		if l.ListingID == "" {
+			return fmt.Errorf("listing ID is required")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range listings {
+		l := listings[i]
+		l.UpdatedAt = now
+		s.db.Listings[l.ListingID] = &l
+	}
+	return s.flush()
+}
+
+// DeleteListing removes a listing (e.g. once it has ended)
+func (s *FileStore) DeleteListing(listingID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.db.Listings[listingID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.db.Listings, listingID)
+	return s.flush()
+}
+
+// DeleteListings removes the listings that exist among listingIDs with a single write
+// and returns how many were removed
+func (s *FileStore) DeleteListings(listingIDs []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, id := range listingIDs {
+		if _, ok := s.db.Listings[id]; ok {
+			delete(s.db.Listings, id)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.flush()
+}
+
+// ListListings returns listings newest first by item ID. The order doesn't depend on
+// anything a seller can edit, so a limited page keeps the same listings between syncs.
+func (s *FileStore) ListListings(limit int) ([]Listing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	listings := make([]Listing, 0, len(s.db.Listings))
+	for _, l := range s.db.Listings {
+		listings = append(listings, *l)
+	}
+	sort.Slice(listings, func(i, j int) bool {
+		a, b := listings[i].ListingID, listings[j].ListingID
+		if len(a) != len(b) {
+			return len(a) > len(b) // numeric item IDs: longer is newer
+		}
+		return a > b
+	})
+
+	return truncate(listings, limit), nil
+}
+
+// UpsertPayout inserts or replaces a payout
+func (s *FileStore) UpsertPayout(payout *Payout) error {
+	if payout == nil || payout.PayoutID == "" {
+		return fmt.Errorf("payout ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := *payout
+	p.UpdatedAt = time.Now()
+	s.db.Payouts[p.PayoutID] = &p
+	return s.flush()
+}
+
+// ListPayouts returns payouts newest first
+func (s *FileStore) ListPayouts(limit int) ([]Payout, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	payouts := make([]Payout, 0, len(s.db.Payouts))
+	for _, p := range s.db.Payouts {
+		payouts = append(payouts, *p)
+	}
+	sort.Slice(payouts, func(i, j int) bool {
+		return payouts[i].PayoutDate.After(payouts[j].PayoutDate)
+	})
+
+	return truncate(payouts, limit), nil
+}
+
+// SaveNotification stores a received notification, assigning an ID if it has none.
+// The oldest notifications are dropped once maxNotifications is exceeded.
+func (s *FileStore) SaveNotification(notification *Notification) error {
+	if notification == nil {
+		return fmt.Errorf("notification is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if notification.ID == "" {
+		notification.ID = newID()
+	}
+	if notification.ReceivedAt.IsZero() {
+		notification.ReceivedAt = time.Now()
+	}
+
+	n := *notification
+	s.db.Notifications[n.ID] = &n
+
+	for len(s.db.Notifications) > maxNotifications {
+		oldestID := ""
+		var oldest time.Time
+		for id, existing := range s.db.Notifications {
+			if oldestID == "" || existing.ReceivedAt.Before(oldest) {
+				oldestID, oldest = id, existing.ReceivedAt
+			}
+		}
+		delete(s.db.Notifications, oldestID)
+	}
+
+	return s.flushSoon()
+}
+
+// GetNotification returns the stored notification with the given ID
+func (s *FileStore) GetNotification(id string) (*Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n, ok := s.db.Notifications[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := *n
+	return &out, nil
+}
+
+// ListNotifications returns notifications most recently received first
+func (s *FileStore) ListNotifications(limit int) ([]Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notifications := make([]Notification, 0, len(s.db.Notifications))
+	for _, n := range s.db.Notifications {
+		notifications = append(notifications, *n)
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].ReceivedAt.After(notifications[j].ReceivedAt)
+	})
+
+	return truncate(notifications, limit), nil
+}
+
+// SetNotificationOutcome records what was done with a stored notification
+func (s *FileStore) SetNotificationOutcome(id, outcome, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.db.Notifications[id]
+	if !ok {
+		return ErrNotFound
+	}
+	n.Outcome = outcome
+	n.Error = errMsg
+	return s.flushSoon()
+}
+
+// RecordDelivery sets a notification's delivery status in one channel
+func (s *FileStore) RecordDelivery(notificationID string, delivery Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.db.Notifications[notificationID]
+	if !ok {
+		return ErrNotFound
+	}
+	if delivery.UpdatedAt.IsZero() {
+		delivery.UpdatedAt = time.Now()
+	}
+
+	for i := range n.Deliveries {
+		if n.Deliveries[i].ChannelID == delivery.ChannelID {
+			n.Deliveries[i] = delivery
+			return s.flushSoon()
+		}
+	}
+	n.Deliveries = append(n.Deliveries, delivery)
+	return s.flushSoon()
+}
+
+// GetSyncCursor returns the named sync cursor
+func (s *FileStore) GetSyncCursor(name string) (*SyncCursor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.db.SyncCursors[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := *c
+	return &out, nil
+}
+
+// SaveSyncCursor inserts or replaces a sync cursor
+func (s *FileStore) SaveSyncCursor(cursor *SyncCursor) error {
+	if cursor == nil || cursor.Name == "" {
+		return fmt.Errorf("cursor name is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *cursor
+	s.db.SyncCursors[c.Name] = &c
+	return s.flush()
+}
+
+// GetPollMark returns the poller state stored under key
+func (s *FileStore) GetPollMark(key string) (*PollMark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.db.PollMarks[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := *m
+	return &out, nil
+}
+
+// SavePollMark records value under key with the current time
+func (s *FileStore) SavePollMark(key, value string) error {
+	if key == "" {
+		return fmt.Errorf("poll mark key is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.db.PollMarks[key] = &PollMark{Value: value, SeenAt: time.Now()}
+	return s.flush()
+}
+
+// PrunePollMarks removes poller state last updated before the given time
+func (s *FileStore) PrunePollMarks(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, m := range s.db.PollMarks {
+		if m.SeenAt.Before(before) {
+			delete(s.db.PollMarks, key)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return nil
+	}
+	return s.flush()
+}
+
+// MarkNotificationSeen records key as processed for ttl. It reports duplicate=true
+// (and changes nothing) if key was already recorded and has not expired.
+func (s *FileStore) MarkNotificationSeen(key string, ttl time.Duration) (bool, error) {
+	if key == "" {
+		return false, fmt.Errorf("dedup key is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if expires, ok := s.db.SeenNotifications[key]; ok && now.Before(expires) {
+		return true, nil
+	}
+
+	for k, expires := range s.db.SeenNotifications {
+		if !now.Before(expires) {
+			delete(s.db.SeenNotifications, k)
+		}
+	}
+	s.db.SeenNotifications[key] = now.Add(ttl)
+
+	// Drop the keys closest to expiry when over capacity
+	if over := len(s.db.SeenNotifications) - maxSeenNotifications; over > 0 {
+		keys := make([]string, 0, len(s.db.SeenNotifications))
+		for k := range s.db.SeenNotifications {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return s.db.SeenNotifications[keys[i]].Before(s.db.SeenNotifications[keys[j]])
+		})
+		for _, k := range keys[:over] {
+			delete(s.db.SeenNotifications, k)
+		}
+	}
+
+	return false, s.flushSoon()
+}
+
+// ForgetNotificationSeen removes key, so the next delivery with it is processed again
+func (s *FileStore) ForgetNotificationSeen(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.db.SeenNotifications[key]; !ok {
+		return nil
+	}
+	delete(s.db.SeenNotifications, key)
+	return s.flush()
+}
+
+// PurgeUser removes personal data for a deleted eBay user: buyer names and shipping
+// addresses are cleared from orders and offers, and stored notifications that mention
+// the user and routing rules for their username are deleted. Order IDs and amounts are
+// kept for the seller's records. A rule is deleted rather than cleared, which would
+// route every buyer's notifications.
+func (s *FileStore) PurgeUser(username, userID string) (*PurgeResult, error) {
+	if username == "" && userID == "" {
+		return nil, fmt.Errorf("username or user ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &PurgeResult{}
+
+	for _, o := range s.db.Orders {
+		if isBuyer(o.BuyerUsername, o.BuyerUserID, username, userID) {
+			o.BuyerUsername = ""
+			o.BuyerUserID = ""
+			o.ShipTo = Address{}
+			o.UpdatedAt = time.Now()
+			result.OrdersScrubbed++
+		}
+	}
+
+	for _, o := range s.db.Offers {
+		if isBuyer(o.BuyerUsername, o.BuyerUserID, username, userID) {
+			o.BuyerUsername = ""
+			o.BuyerUserID = ""
+			o.UpdatedAt = time.Now()
+			result.OffersScrubbed++
+		}
+	}
+
+	for id, n := range s.db.Notifications {
+		if mentions(n.Payload, username) || mentions(n.Payload, userID) {
+			delete(s.db.Notifications, id)
+			result.NotificationsDeleted++
+		}
+	}
+
+	for id, m := range s.db.Outbox {
+		if mentions(m.Embed, username) || mentions(m.Embed, userID) {
+			delete(s.db.Outbox, id)
+			result.QueuedMessagesDeleted++
+		}
+	}
+
+	for id, r := range s.db.Routes {
+		if username != "" && strings.EqualFold(r.Buyer, username) {
+			delete(s.db.Routes, id)
+			result.RoutesDeleted++
+		}
+	}
+
+	return result, s.flush()
+}
+
+// isBuyer reports whether a record's buyer is the user with username or userID. Either
+// identifier is enough: usernames can change, and older records have no user ID.
+func isBuyer(buyerUsername, buyerUserID, username, userID string) bool {
+	return (username != "" && strings.EqualFold(buyerUsername, username)) ||
+		(userID != "" && buyerUserID == userID)
+}
+
+// mentions reports whether a JSON payload contains value as a complete string
+func mentions(payload []byte, value string) bool {
+	if value == "" {
+		return false
+	}
+	quoted, _ := json.Marshal(value)
+	return strings.Contains(strings.ToLower(string(payload)), strings.ToLower(string(quoted)))
+}
+
+// SaveDeletionAudit appends an account deletion audit record
+func (s *FileStore) SaveDeletionAudit(audit *DeletionAudit) error {
+	if audit == nil {
+		return fmt.Errorf("audit is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if audit.ID == "" {
+		audit.ID = newID()
+	}
+	a := *audit
+	s.db.DeletionAudits = append(s.db.DeletionAudits, &a)
+	return s.flush()
+}
+
+// ListDeletionAudits returns audit records newest first
+func (s *FileStore) ListDeletionAudits(limit int) ([]DeletionAudit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	audits := make([]DeletionAudit, 0, len(s.db.DeletionAudits))
+	for i := len(s.db.DeletionAudits) - 1; i >= 0; i-- {
+		audits = append(audits, *s.db.DeletionAudits[i])
+	}
+
+	return truncate(audits, limit), nil
+}
+
+// EnqueueOutbox adds a pending message to the outbound queue, assigning an ID if it has none
+func (s *FileStore) EnqueueOutbox(msg *OutboxMessage) error {
+	if msg == nil {
+		return fmt.Errorf("outbox message is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if msg.ID == "" {
+		msg.ID = newID()
+	}
+	if msg.Status == "" {
+		msg.Status = OutboxPending
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = now
+	}
+	if msg.NextAttemptAt.IsZero() {
+		msg.NextAttemptAt = now
+	}
+	msg.UpdatedAt = now
+
+	m := *msg
+	s.db.Outbox[m.ID] = &m
+	return s.flush()
+}
+
+// GetOutbox returns the queued message with the given ID
+func (s *FileStore) GetOutbox(id string) (*OutboxMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.db.Outbox[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := *m
+	return &out, nil
+}
+
+// ListOutbox returns queued messages with the given status (all if empty), oldest first
+func (s *FileStore) ListOutbox(status string, limit int) ([]OutboxMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := make([]OutboxMessage, 0, len(s.db.Outbox))
+	for _, m := range s.db.Outbox {
+		if status == "" || m.Status == status {
+			messages = append(messages, *m)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	return truncate(messages, limit), nil
+}
+
+// UpdateOutbox saves the delivery state of a queued message
+func (s *FileStore) UpdateOutbox(msg *OutboxMessage) error {
+	if msg == nil || msg.ID == "" {
+		return fmt.Errorf("outbox message ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.db.Outbox[msg.ID]; !ok {
+		return ErrNotFound
+	}
+	msg.UpdatedAt = time.Now()
+	m := *msg
+	s.db.Outbox[m.ID] = &m
+	return s.flush()
+}
+
+// DeleteOutbox removes a message from the queue once it has been delivered
+func (s *FileStore) DeleteOutbox(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.db.Outbox[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.db.Outbox, id)
+	return s.flush()
+}
+
+// SaveRoute creates or replaces a routing rule, assigning an ID if it has none
+func (s *FileStore) SaveRoute(route *Route) error {
+	if route == nil || route.ChannelID == "" {
+		return fmt.Errorf("route channel is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if route.ID == "" {
+		route.ID = newID()
+	}
+	if route.CreatedAt.IsZero() {
+		route.CreatedAt = time.Now()
+	}
+	r := *route
+	s.db.Routes[r.ID] = &r
+	return s.flush()
+}
+
+// ListRoutes returns routing rules in the order they were created
+func (s *FileStore) ListRoutes() ([]Route, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routes := make([]Route, 0, len(s.db.Routes))
+	for _, r := range s.db.Routes {
+		routes = append(routes, *r)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].CreatedAt.Before(routes[j].CreatedAt)
+	})
+	return routes, nil
+}
+
+// DeleteRoute removes a routing rule
+func (s *FileStore) DeleteRoute(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.db.Routes[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.db.Routes, id)
+	return s.flush()
+}
+
+// AppendOrderStatus adds status to an order's timeline (unless it is already the latest
+// status) and returns the whole timeline
+func (s *FileStore) AppendOrderStatus(orderID, status string, at time.Time) ([]OrderStatus, error) {
+	if orderID == "" || status == "" {
+		return nil, fmt.Errorf("order ID and status are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tracker(orderID)
+	if n := len(t.Timeline); n == 0 || t.Timeline[n-1].Status != status {
+		t.Timeline = append(t.Timeline, OrderStatus{Status: status, At: at})
+		t.UpdatedAt = time.Now()
+		if err := s.flush(); err != nil {
+			return nil, err
+		}
+	}
+	return append([]OrderStatus(nil), t.Timeline...), nil
+}
+
+// GetOrderMessage returns the message showing an order in a channel
+func (s *FileStore) GetOrderMessage(orderID, channelID string) (*OrderMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.db.OrderTrackers[orderID]
+	if !ok || t.Messages[channelID] == nil {
+		return nil, ErrNotFound
+	}
+	m := *t.Messages[channelID]
+	return &m, nil
+}
+
+// SaveOrderMessage records the message (and thread) showing an order in msg.ChannelID
+func (s *FileStore) SaveOrderMessage(orderID string, msg *OrderMessage) error {
+	if orderID == "" || msg == nil || msg.ChannelID == "" || msg.MessageID == "" {
+		return fmt.Errorf("order ID, channel and message are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tracker(orderID)
+	m := *msg
+	t.Messages[m.ChannelID] = &m
+	t.UpdatedAt = time.Now()
+	return s.flush()
+}
+
+// tracker returns the tracker for orderID, creating it if needed. Callers must hold s.mu.
+func (s *FileStore) tracker(orderID string) *OrderTracker {
+	t, ok := s.db.OrderTrackers[orderID]
+	if !ok {
+		t = &OrderTracker{OrderID: orderID}
+		s.db.OrderTrackers[orderID] = t
+	}
+	if t.Messages == nil {
+		t.Messages = make(map[string]*OrderMessage)
+	}
+	return t
+}
+
+// truncate returns at most limit items; a limit of 0 or less returns everything
+func truncate[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+// newID returns a short random hex identifier
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
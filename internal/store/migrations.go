@@ -0,0 +1,112 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// migration upgrades the on-disk database to the given schema version
+type migration struct {
+	version     int
+	description string
+	up          func(db *database) error
+}
+
+// migrations lists every schema change in order. Append new entries; never edit old ones.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "initial schema: orders, offers, listings, payouts, notifications",
+		up: func(db *database) error {
+			db.Orders = make(map[string]*Order)
+			db.Offers = make(map[string]*Offer)
+			db.Listings = make(map[string]*Listing)
+			db.Payouts = make(map[string]*Payout)
+			db.Notifications = make(map[string]*Notification)
+			return nil
+		},
+	},
+	{
+		version:     2,
+		description: "add sync cursors",
+		up: func(db *database) error {
+			db.SyncCursors = make(map[string]*SyncCursor)
+			return nil
+		},
+	},
+	{
+		version:     3,
+		description: "add poller seen state",
+		up: func(db *database) error {
+			db.PollMarks = make(map[string]*PollMark)
+			return nil
+		},
+	},
+	{
+		version:     4,
+		description: "add account deletion audit log",
+		up: func(db *database) error {
+			db.DeletionAudits = []*DeletionAudit{}
+			return nil
+		},
+	},
+	{
+		version:     5,
+		description: "add notification dedup keys",
+		up: func(db *database) error {
+			db.SeenNotifications = make(map[string]time.Time)
+			return nil
+		},
+	},
+	{
+		version:     6,
+		description: "add outbound Discord message queue",
+		up: func(db *database) error {
+			db.Outbox = make(map[string]*OutboxMessage)
+			return nil
+		},
+	},
+	{
+		version:     7,
+		description: "add notification routing rules",
+		up: func(db *database) error {
+			db.Routes = make(map[string]*Route)
+			return nil
+		},
+	},
+	{
+		version:     8,
+		description: "add live order message tracking",
+		up: func(db *database) error {
+			db.OrderTrackers = make(map[string]*OrderTracker)
+			return nil
+		},
+	},
+}
+
+// currentSchemaVersion is the version a fully migrated database reports
+func currentSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate applies every migration newer than the database's schema version.
+// It returns true if anything was applied.
+func migrate(db *database) (bool, error) {
+	if db.SchemaVersion > currentSchemaVersion() {
+		return false, fmt.Errorf("database schema version %d is newer than supported version %d", db.SchemaVersion, currentSchemaVersion())
+	}
+
+	applied := false
+	for _, m := range migrations {
+		if m.version <= db.SchemaVersion {
+			continue
+		}
+		if err := m.up(db); err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+		db.SchemaVersion = m.version
+		applied = true
+	}
+
+	return applied, nil
+}
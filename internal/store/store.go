@@ -0,0 +1,301 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a record does not exist in the store
+var ErrNotFound = errors.New("record not found")
+
+// Repository is the persistence interface shared by the ebay, webhook and bot packages
+type Repository interface {
+	// Orders (line items are stored with their order)
+	UpsertOrder(order *Order) error
+	UpsertOrders(orders []Order) error // one write for the whole batch
+	GetOrder(orderID string) (*Order, error)
+	ListOrders(limit int) ([]Order, error)
+
+	// Offers
+	UpsertOffer(offer *Offer) error
+	GetOffer(offerID string) (*Offer, error)
+	ListOffers(status string, limit int) ([]Offer, error)
+
+	// Listings
+	UpsertListing(listing *Listing) error
+	UpsertListings(listings []Listing) error // one write for the whole batch
+	DeleteListing(listingID string) error
+	DeleteListings(listingIDs []string) (int, error)
+	ListListings(limit int) ([]Listing, error)
+
+	// Payouts
+	UpsertPayout(payout *Payout) error
+	ListPayouts(limit int) ([]Payout, error)
+
+	// Received webhook notifications
+	SaveNotification(notification *Notification) error
+	GetNotification(id string) (*Notification, error)
+	ListNotifications(limit int) ([]Notification, error)
+	SetNotificationOutcome(id, outcome, errMsg string) error
+	RecordDelivery(notificationID string, delivery Delivery) error
+
+	// Background sync progress
+	GetSyncCursor(name string) (*SyncCursor, error)
+	SaveSyncCursor(cursor *SyncCursor) error
+
+	// Poller "already seen" state
+	GetPollMark(key string) (*PollMark, error)
+	SavePollMark(key, value string) error
+	PrunePollMarks(before time.Time) error
+
+	// Notification dedup
+	MarkNotificationSeen(key string, ttl time.Duration) (duplicate bool, err error)
+	ForgetNotificationSeen(key string) error
+
+	// Outbound Discord message queue
+	EnqueueOutbox(msg *OutboxMessage) error
+	GetOutbox(id string) (*OutboxMessage, error)
+	ListOutbox(status string, limit int) ([]OutboxMessage, error)
+	UpdateOutbox(msg *OutboxMessage) error
+	DeleteOutbox(id string) error
+
+	// Notification routing rules
+	SaveRoute(route *Route) error
+	ListRoutes() ([]Route, error)
+	DeleteRoute(id string) error
+
+	// Live order messages
+	AppendOrderStatus(orderID, status string, at time.Time) ([]OrderStatus, error)
+	GetOrderMessage(orderID, channelID string) (*OrderMessage, error)
+	SaveOrderMessage(orderID string, msg *OrderMessage) error
+
+	// Marketplace account deletion
+	PurgeUser(username, userID string) (*PurgeResult, error)
+	SaveDeletionAudit(audit *DeletionAudit) error
+	ListDeletionAudits(limit int) ([]DeletionAudit, error)
+
+	// Close flushes pending writes and releases the store
+	Close() error
+}
+
+// StoreHealth is the write state of a store, as reported by /readyz
+type StoreHealth struct {
+	Path        string    `json:"path"`
+	LastWrite   time.Time `json:"lastWrite"`
+	LastError   string    `json:"lastError,omitempty"` // set while writes are failing
+	LastErrorAt time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Order is the stored form of an eBay order
+type Order struct {
+	OrderID           string     `json:"orderId"`
+	CreationDate      time.Time  `json:"creationDate"`
+	LastModifiedDate  time.Time  `json:"lastModifiedDate"`
+	BuyerUsername     string     `json:"buyerUsername"`
+	BuyerUserID       string     `json:"buyerUserId,omitempty"` // eBay user ID, which survives username changes
+	Total             float64    `json:"total"`
+	Currency          string     `json:"currency"`
+	FulfillmentStatus string     `json:"fulfillmentStatus"`
+	PaymentStatus     string     `json:"paymentStatus"`
+	ShipTo            Address    `json:"shipTo"`
+	LineItems         []LineItem `json:"lineItems"`
+	Account           string     `json:"account,omitempty"` // seller account; empty for the default
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+// LineItem is a single item within a stored order
+type LineItem struct {
+	LineItemID   string  `json:"lineItemId"`
+	LegacyItemID string  `json:"legacyItemId"`
+	Title        string  `json:"title"`
+	SKU          string  `json:"sku"`
+	Quantity     int     `json:"quantity"`
+	Price        float64 `json:"price"`
+	ImageURL     string  `json:"imageUrl"`
+}
+
+// Address is a stored shipping address
+type Address struct {
+	Name       string `json:"name"`
+	Street1    string `json:"street1"`
+	Street2    string `json:"street2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+}
+
+// Offer is the stored form of a buyer best offer
+type Offer struct {
+	OfferID       string    `json:"offerId"`
+	ItemID        string    `json:"itemId"`
+	ItemTitle     string    `json:"itemTitle"`
+	BuyerUsername string    `json:"buyerUsername"`
+	BuyerUserID   string    `json:"buyerUserId,omitempty"`
+	OfferPrice    float64   `json:"offerPrice"`
+	ListPrice     float64   `json:"listPrice"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	CreatedDate   time.Time `json:"createdDate"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Listing is the stored form of an active listing
+type Listing struct {
+	ListingID  string    `json:"listingId"`
+	SKU        string    `json:"sku"`
+	Title      string    `json:"title"`
+	Price      float64   `json:"price"`
+	Currency   string    `json:"currency"`
+	Shipping   string    `json:"shipping"`
+	Quantity   int       `json:"quantity"`
+	Condition  string    `json:"condition"`
+	ImageURL   string    `json:"imageUrl"`
+	ListingURL string    `json:"listingUrl"`
+	Account    string    `json:"account,omitempty"` // seller account; empty for the default
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Payout is the stored form of a seller payout
+type Payout struct {
+	PayoutID       string    `json:"payoutId"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	Status         string    `json:"status"`
+	InstrumentType string    `json:"instrumentType"`
+	PayoutDate     time.Time `json:"payoutDate"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Notification is a webhook notification as it was received from eBay
+type Notification struct {
+	ID             string            `json:"id"`
+	NotificationID string            `json:"notificationId"`
+	EventType      string            `json:"eventType"`
+	PublishDate    string            `json:"publishDate"`
+	ReceivedAt     time.Time         `json:"receivedAt"`
+	Payload        json.RawMessage   `json:"payload,omitempty"`
+	Body           string            `json:"body,omitempty"` // raw body when it is not valid JSON
+	Headers        map[string]string `json:"headers,omitempty"`
+	Signature      string            `json:"signature,omitempty"` // verified, unsigned, unverified or invalid
+	Outcome        string            `json:"outcome,omitempty"`   // what the webhook did with it, e.g. queued
+	Error          string            `json:"error,omitempty"`
+	Deliveries     []Delivery        `json:"deliveries,omitempty"` // Discord delivery per channel
+	ReplayedAt     time.Time         `json:"replayedAt,omitempty"`
+}
+
+// Delivery states of a notification in one Discord channel
+const (
+	DeliveryQueued   = "queued"
+	DeliveryRetrying = "retrying"
+	DeliverySent     = "sent"
+	DeliveryDead     = "dead"
+)
+
+// Delivery is the outcome of posting a notification to one Discord channel
+type Delivery struct {
+	ChannelID string    `json:"channelId"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SyncCursor records how far a background sync has progressed so it can resume after a restart
+type SyncCursor struct {
+	Name         string    `json:"name"`
+	HighWater    time.Time `json:"highWater"` // newest lastModifiedDate seen
+	LastSyncedAt time.Time `json:"lastSyncedAt"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+// PollMark records the last value the poller saw for a key (e.g. an order's payment status)
+type PollMark struct {
+	Value  string    `json:"value"`
+	SeenAt time.Time `json:"seenAt"`
+}
+
+// Outbox message states
+const (
+	OutboxPending = "pending"
+	OutboxDead    = "dead" // retries exhausted or permanent failure; replay manually
+)
+
+// OutboxMessage is a Discord message waiting to be delivered
+type OutboxMessage struct {
+	ID             string          `json:"id"`
+	ChannelID      string          `json:"channelId"`
+	EventType      string          `json:"eventType"`
+	MentionRoles   []string        `json:"mentionRoles,omitempty"`
+	Embed          json.RawMessage `json:"embed"`
+	Components     json.RawMessage `json:"components,omitempty"`     // buttons sent with the embed
+	OrderID        string          `json:"orderId,omitempty"`        // edit the order's live message instead of posting a new one
+	ThreadNote     string          `json:"threadNote,omitempty"`     // short update posted in the order's thread
+	NotificationID string          `json:"notificationId,omitempty"` // stored notification whose delivery this is
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+// Route sends matching notifications to a Discord channel. Empty criteria match everything.
+type Route struct {
+	ID            string    `json:"id"`
+	ChannelID     string    `json:"channelId"`
+	EventType     string    `json:"eventType,omitempty"` // case-insensitive substring, e.g. "OFFER"
+	MinAmount     float64   `json:"minAmount,omitempty"`
+	SKUPrefix     string    `json:"skuPrefix,omitempty"`
+	Buyer         string    `json:"buyer,omitempty"`
+	MentionRoleID string    `json:"mentionRoleId,omitempty"`
+	Account       string    `json:"account,omitempty"` // seller account the route applies to; empty for all
+	CreatedBy     string    `json:"createdBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// OrderTracker follows one order through its lifecycle: the statuses it has been
+// through and the Discord message kept up to date in each channel
+type OrderTracker struct {
+	OrderID   string                   `json:"orderId"`
+	Timeline  []OrderStatus            `json:"timeline"`
+	Messages  map[string]*OrderMessage `json:"messages"` // by channel ID
+	UpdatedAt time.Time                `json:"updatedAt"`
+}
+
+// OrderStatus is one step in an order's timeline
+type OrderStatus struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+// OrderMessage is the Discord message (and its thread) showing an order in one channel
+type OrderMessage struct {
+	ChannelID string `json:"channelId"`
+	MessageID string `json:"messageId"`
+	ThreadID  string `json:"threadId,omitempty"`
+}
+
+// PurgeResult counts what PurgeUser removed
+type PurgeResult struct {
+	OrdersScrubbed       int `json:"ordersScrubbed"`
+	OffersScrubbed       int `json:"offersScrubbed"`
+	NotificationsDeleted int `json:"notificationsDeleted"`
+	// QueuedMessagesDeleted counts undelivered or dead-lettered Discord messages
+	QueuedMessagesDeleted int `json:"queuedMessagesDeleted"`
+	// RoutesDeleted counts routing rules for the user's buyer username
+	RoutesDeleted int `json:"routesDeleted"`
+}
+
+// DeletionAudit records that a marketplace account deletion was processed.
+// The user is identified only by a hash so the audit log holds no personal data.
+type DeletionAudit struct {
+	ID             string      `json:"id"`
+	NotificationID string      `json:"notificationId"`
+	UserHash       string      `json:"userHash"`
+	EventDate      string      `json:"eventDate"`
+	ReceivedAt     time.Time   `json:"receivedAt"`
+	PurgedAt       time.Time   `json:"purgedAt"`
+	Result         PurgeResult `json:"result"`
+	Error          string      `json:"error,omitempty"`
+}
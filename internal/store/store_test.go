@@ -0,0 +1,447 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) (*FileStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	return s, path
+}
+
+func TestOpenCreatesMigratedStore(t *testing.T) {
+	s, path := openTestStore(t)
+
+	if s.db.SchemaVersion != currentSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d", currentSchemaVersion(), s.db.SchemaVersion)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Store file was not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected store permissions 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestOpenRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	data, _ := json.Marshal(map[string]int{"schemaVersion": currentSchemaVersion() + 1})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error opening a store with a newer schema")
+	}
+}
+
+func TestOrdersPersistAcrossReopen(t *testing.T) {
+	s, path := openTestStore(t)
+
+	order := &Order{
+		OrderID:       "12-34567-89012",
+		CreationDate:  time.Now(),
+		BuyerUsername: "testbuyer",
+		Total:         42.50,
+		Currency:      "USD",
+		LineItems: []LineItem{
+			{LineItemID: "li-1", Title: "Test Item", Quantity: 2, Price: 21.25, SKU: "SKU-1"},
+		},
+	}
+	if err := s.UpsertOrder(order); err != nil {
+		t.Fatalf("UpsertOrder failed: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+
+	got, err := reopened.GetOrder(order.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	if got.BuyerUsername != "testbuyer" || got.Total != 42.50 {
+		t.Errorf("Unexpected order after reopen: %+v", got)
+	}
+	if len(got.LineItems) != 1 || got.LineItems[0].Quantity != 2 {
+		t.Errorf("Line items not persisted: %+v", got.LineItems)
+	}
+	if got.UpdatedAt.IsZero() {
+		t.Error("UpdatedAt should be set on upsert")
+	}
+}
+
+func TestGetMissingRecord(t *testing.T) {
+	s, _ := openTestStore(t)
+
+	if _, err := s.GetOrder("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for order, got %v", err)
+	}
+	if _, err := s.GetOffer("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for offer, got %v", err)
+	}
+	if err := s.DeleteListing("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for listing delete, got %v", err)
+	}
+}
+
+func TestListOffersFiltersByStatus(t *testing.T) {
+	s, _ := openTestStore(t)
+
+	now := time.Now()
+	offers := []*Offer{
+		{OfferID: "a", Status: "PENDING", CreatedDate: now.Add(-2 * time.Hour)},
+		{OfferID: "b", Status: "ACCEPTED", CreatedDate: now.Add(-1 * time.Hour)},
+		{OfferID: "c", Status: "PENDING", CreatedDate: now},
+	}
+	for _, o := range offers {
+		if err := s.UpsertOffer(o); err != nil {
+			t.Fatalf("UpsertOffer failed: %v", err)
+		}
+	}
+
+	pending, err := s.ListOffers("PENDING", 0)
+	if err != nil {
+		t.Fatalf("ListOffers failed: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending offers, got %d", len(pending))
+	}
+	if pending[0].OfferID != "c" {
+		t.Errorf("Expected newest offer first, got %s", pending[0].OfferID)
+	}
+
+	limited, _ := s.ListOffers("", 1)
+	if len(limited) != 1 {
+		t.Errorf("Expected limit to be applied, got %d offers", len(limited))
+	}
+}
+
+func TestSaveNotificationAssignsID(t *testing.T) {
+	s, _ := openTestStore(t)
+
+	n := &Notification{
+		NotificationID: "notif-1",
+		EventType:      "MARKETPLACE_OFFER",
+		Payload:        json.RawMessage(`{"metadata":{"topic":"MARKETPLACE_OFFER"}}`),
+	}
+	if err := s.SaveNotification(n); err != nil {
+		t.Fatalf("SaveNotification failed: %v", err)
+	}
+	if n.ID == "" {
+		t.Fatal("Expected an ID to be assigned")
+	}
+
+	got, err := s.GetNotification(n.ID)
+	if err != nil {
+		t.Fatalf("GetNotification failed: %v", err)
+	}
+	if string(got.Payload) != string(n.Payload) {
+		t.Errorf("Payload mismatch: %s", got.Payload)
+	}
+}
+
+func TestUpsertRequiresID(t *testing.T) {
+	s, _ := openTestStore(t)
+
+	if err := s.UpsertOrder(&Order{}); err == nil {
+		t.Error("Expected error for order without ID")
+	}
+	if err := s.UpsertOffer(&Offer{}); err == nil {
+		t.Error("Expected error for offer without ID")
+	}
+	if err := s.UpsertListing(&Listing{}); err == nil {
+		t.Error("Expected error for listing without ID")
+	}
+	if err := s.UpsertPayout(&Payout{}); err == nil {
+		t.Error("Expected error for payout without ID")
+	}
+}
+
+func TestBatchUpserts(t *testing.T) {
+	s, _ := openTestStore(t)
+
+	if err := s.UpsertOrders([]Order{{OrderID: "o1"}, {OrderID: "o2"}}); err != nil {
+		t.Fatalf("UpsertOrders failed: %v", err)
+	}
+	if orders, _ := s.ListOrders(0); len(orders) != 2 || orders[0].OrderID == orders[1].OrderID {
+		t.Errorf("Expected 2 distinct orders, got %+v", orders)
+	}
+	if err := s.UpsertOrders([]Order{{OrderID: "o3"}, {}}); err == nil {
+		t.Error("Expected error for a batch with an order without ID")
+	}
+	if _, err := s.GetOrder("o3"); err != ErrNotFound {
+		t.Error("A rejected batch must store nothing")
+	}
+
+	if err := s.UpsertListings([]Listing{{ListingID: "l1"}, {ListingID: "l2"}, {ListingID: "l3"}}); err != nil {
+		t.Fatalf("UpsertListings failed: %v", err)
+	}
+	if removed, err := s.DeleteListings([]string{"l1", "l3", "missing"}); err != nil || removed != 2 {
+		t.Errorf("DeleteListings() = %d, %v, want 2", removed, err)
+	}
+	if listings, _ := s.ListListings(0); len(listings) != 1 || listings[0].ListingID != "l2" {
+		t.Errorf("Expected only l2 to remain, got %+v", listings)
+	}
+}
+
+func TestListListingsOrder(t *testing.T) {
+	s, _ := openTestStore(t)
+
+	s.UpsertListings([]Listing{
+		{ListingID: "99", Title: "A"},
+		{ListingID: "110", Title: "C"},
+		{ListingID: "105", Title: "B"},
+	})
+	want := []string{"110", "105", "99"}
+	check := func(when string) {
+		listings, _ := s.ListListings(0)
+		for i, l := range listings {
+			if i >= len(want) || l.ListingID != want[i] {
+				t.Errorf("%s: ListListings() = %+v, want IDs %v", when, listings, want)
+				return
+			}
+		}
+	}
+	check("initially")
+
+	// Renaming a listing must not move it
+	s.UpsertListing(&Listing{ListingID: "99", Title: "Z"})
+	check("after a title change")
+}
+
+func TestPurgeUser(t *testing.T) {
+	s, _ := openTestStore(t)
+
+	s.UpsertOrder(&Order{OrderID: "o1", BuyerUsername: "DeletedBuyer", Total: 10, ShipTo: Address{Name: "Jane", City: "Austin"}})
+	s.UpsertOrder(&Order{OrderID: "o2", BuyerUsername: "otherbuyer"})
+	s.UpsertOrder(&Order{OrderID: "o3", BuyerUsername: "renamedbuyer", BuyerUserID: "u-123", ShipTo: Address{City: "Dallas"}})
+	s.UpsertOffer(&Offer{OfferID: "f1", BuyerUsername: "deletedbuyer"})
+	s.UpsertOffer(&Offer{OfferID: "f2", BuyerUsername: "renamedbuyer", BuyerUserID: "u-123"})
+	s.SaveNotification(&Notification{Payload: json.RawMessage(`{"buyerUsername":"deletedbuyer"}`)})
+	s.SaveNotification(&Notification{Payload: json.RawMessage(`{"buyerUsername":"deletedbuyer2"}`)})
+	s.SaveRoute(&Route{ChannelID: "vip", Buyer: "DeletedBuyer"})
+	s.SaveRoute(&Route{ChannelID: "sales", Buyer: "otherbuyer"})
+
+	result, err := s.PurgeUser("deletedbuyer", "u-123")
+	if err != nil {
+		t.Fatalf("PurgeUser failed: %v", err)
+	}
+	if result.OrdersScrubbed != 2 || result.OffersScrubbed != 2 || result.NotificationsDeleted != 1 || result.RoutesDeleted != 1 {
+		t.Errorf("Unexpected purge result: %+v", result)
+	}
+
+	o1, _ := s.GetOrder("o1")
+	if o1.BuyerUsername != "" || o1.ShipTo.City != "" {
+		t.Errorf("Order personal data not cleared: %+v", o1)
+	}
+	if o1.Total != 10 {
+		t.Error("Order amounts should be kept")
+	}
+	// Matched by user ID after the buyer changed their username
+	if o3, _ := s.GetOrder("o3"); o3.BuyerUsername != "" || o3.BuyerUserID != "" || o3.ShipTo.City != "" {
+		t.Errorf("Order matched by user ID not cleared: %+v", o3)
+	}
+	if o2, _ := s.GetOrder("o2"); o2.BuyerUsername != "otherbuyer" {
+		t.Error("Other buyers must not be affected")
+	}
+	if remaining, _ := s.ListNotifications(0); len(remaining) != 1 {
+		t.Errorf("Expected 1 notification to remain, got %d", len(remaining))
+	}
+	if routes, _ := s.ListRoutes(); len(routes) != 1 || routes[0].Buyer != "otherbuyer" {
+		t.Errorf("Expected only the other buyer's route to remain, got %+v", routes)
+	}
+
+	if _, err := s.PurgeUser("", ""); err == nil {
+		t.Error("Expected error when no user is given")
+	}
+}
+
+func TestMarkNotificationSeen(t *testing.T) {
+	s, path := openTestStore(t)
+
+	if dup, err := s.MarkNotificationSeen("id:n-1", time.Hour); err != nil || dup {
+		t.Fatalf("First delivery should not be a duplicate (dup=%v, err=%v)", dup, err)
+	}
+	if dup, _ := s.MarkNotificationSeen("id:n-1", time.Hour); !dup {
+		t.Error("Second delivery should be a duplicate")
+	}
+
+	// Dedup keys are flushed with the next write, at the latest on Close
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if dup, _ := reopened.MarkNotificationSeen("id:n-1", time.Hour); !dup {
+		t.Error("Dedup keys should survive a restart")
+	}
+
+	if dup, _ := s.MarkNotificationSeen("id:n-2", -time.Second); dup {
+		t.Error("First delivery should not be a duplicate")
+	}
+	if dup, _ := s.MarkNotificationSeen("id:n-2", time.Hour); dup {
+		t.Error("Expired keys should not count as duplicates")
+	}
+
+	if err := s.ForgetNotificationSeen("id:n-1"); err != nil {
+		t.Fatalf("ForgetNotificationSeen failed: %v", err)
+	}
+	if dup, _ := s.MarkNotificationSeen("id:n-1", time.Hour); dup {
+		t.Error("Forgotten keys should not count as duplicates")
+	}
+}
+
+func TestOrderTracking(t *testing.T) {
+	s, path := openTestStore(t)
+	now := time.Now()
+
+	s.AppendOrderStatus("o-1", "PLACED", now)
+	s.AppendOrderStatus("o-1", "PLACED", now.Add(time.Second))
+	timeline, err := s.AppendOrderStatus("o-1", "PAID", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("AppendOrderStatus failed: %v", err)
+	}
+	if len(timeline) != 2 || timeline[0].Status != "PLACED" || timeline[1].Status != "PAID" {
+		t.Errorf("Expected PLACED then PAID without repeats, got %+v", timeline)
+	}
+
+	if _, err := s.GetOrderMessage("o-1", "chan-1"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound before a message is saved, got %v", err)
+	}
+	if err := s.SaveOrderMessage("o-1", &OrderMessage{ChannelID: "chan-1", MessageID: "m-1", ThreadID: "t-1"}); err != nil {
+		t.Fatalf("SaveOrderMessage failed: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	msg, err := reopened.GetOrderMessage("o-1", "chan-1")
+	if err != nil || msg.MessageID != "m-1" || msg.ThreadID != "t-1" {
+		t.Errorf("Expected saved message to survive a restart, got %+v (%v)", msg, err)
+	}
+}
+
+func TestHealthTracksWriteFailures(t *testing.T) {
+	s, path := openTestStore(t)
+	if h := s.Health(); h.LastError != "" || h.LastWrite.IsZero() {
+		t.Fatalf("Expected a healthy store after open, got %+v", h)
+	}
+
+	// Writes fail once the data directory is gone
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		t.Fatalf("Failed to remove data directory: %v", err)
+	}
+	if err := s.UpsertOrder(&Order{OrderID: "12-34"}); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	if h := s.Health(); h.LastError == "" || h.LastErrorAt.IsZero() {
+		t.Errorf("Expected the failed write to be reported, got %+v", h)
+	}
+
+	// The next successful write clears the error
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("Failed to recreate data directory: %v", err)
+	}
+	if err := s.UpsertOrder(&Order{OrderID: "12-34"}); err != nil {
+		t.Fatalf("UpsertOrder failed: %v", err)
+	}
+	if h := s.Health(); h.LastError != "" {
+		t.Errorf("Expected the error to clear, got %+v", h)
+	}
+}
+
+func TestFailedWritesAreRolledBack(t *testing.T) {
+	s, path := openTestStore(t)
+	if err := s.UpsertOrder(&Order{OrderID: "kept"}); err != nil {
+		t.Fatalf("UpsertOrder failed: %v", err)
+	}
+
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		t.Fatalf("Failed to remove data directory: %v", err)
+	}
+	if err := s.UpsertOrder(&Order{OrderID: "failed"}); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	if _, err := s.GetOrder("failed"); err != ErrNotFound {
+		t.Errorf("A change that failed to write should not be visible, got %v", err)
+	}
+
+	// The next successful write doesn't persist the failed change either
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("Failed to recreate data directory: %v", err)
+	}
+	if err := s.UpsertOrder(&Order{OrderID: "later"}); err != nil {
+		t.Fatalf("UpsertOrder failed: %v", err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	tests := []struct {
+		orderID string
+		want    error
+	}{
+		{"kept", nil},
+		{"failed", ErrNotFound},
+		{"later", nil},
+	}
+	for _, tt := range tests {
+		if _, err := reopened.GetOrder(tt.orderID); err != tt.want {
+			t.Errorf("GetOrder(%q) = %v, want %v", tt.orderID, err, tt.want)
+		}
+	}
+}
+
+func TestHistoryWritesAreDeferred(t *testing.T) {
+	s, path := openTestStore(t)
+	if err := s.SaveNotification(&Notification{ID: "n-1", EventType: "MARKETPLACE_ORDER"}); err != nil {
+		t.Fatalf("SaveNotification failed: %v", err)
+	}
+	if _, err := s.MarkNotificationSeen("id:n-1", time.Hour); err != nil {
+		t.Fatalf("MarkNotificationSeen failed: %v", err)
+	}
+
+	// Deferred changes are written with the next durable write
+	if err := s.UpsertOrder(&Order{OrderID: "12-34"}); err != nil {
+		t.Fatalf("UpsertOrder failed: %v", err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if _, err := reopened.GetNotification("n-1"); err != nil {
+		t.Errorf("Notification was not written with the order: %v", err)
+	}
+	if dup, _ := reopened.MarkNotificationSeen("id:n-1", time.Hour); !dup {
+		t.Error("Dedup key was not written with the order")
+	}
+
+	// ... or once flushDelay has passed
+	if err := s.SaveNotification(&Notification{ID: "n-2", EventType: "MARKETPLACE_ORDER"}); err != nil {
+		t.Fatalf("SaveNotification failed: %v", err)
+	}
+	time.Sleep(flushDelay + 500*time.Millisecond)
+	reopened, err = Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if _, err := reopened.GetNotification("n-2"); err != nil {
+		t.Errorf("Notification was not written after flushDelay: %v", err)
+	}
+}
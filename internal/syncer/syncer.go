@@ -0,0 +1,332 @@
+package syncer
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+)
+
+const (
+	// OrdersCursor and ListingsCursor name the sync cursors kept in the store
+	OrdersCursor   = "orders"
+	ListingsCursor = "listings"
+
+	// initialLookback is how far back the first order sync reaches
+	initialLookback = 90 * 24 * time.Hour
+	// cursorOverlap re-reads a little history each run so orders modified
+	// during the previous run are not missed
+	cursorOverlap = 2 * time.Minute
+
+	ordersPageSize   = 200
+	listingsPageSize = 200
+	maxListingPages  = 25
+	maxBackoff       = 2 * time.Hour
+)
+
+// Source is the subset of the eBay client the syncer needs
+type Source interface {
+	GetOrdersModifiedSince(since time.Time, limit, offset int) ([]ebay.Order, int, error)
+	GetListingsPage(page, perPage int) ([]ebay.Listing, int, error)
+	GetItemImage(legacyItemID string) string
+}
+
+// Syncer periodically copies orders and active listings from eBay into the local store
+type Syncer struct {
+	source   Source
+	store    store.Repository
+	interval time.Duration
+	account  string // tags records and names cursors; empty for the default account
+
+	mu      sync.Mutex
+	running bool
+	backoff time.Duration
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New creates a syncer that runs every interval
+func New(source Source, repo store.Repository, interval time.Duration) *Syncer {
+	return &Syncer{
+		source:   source,
+		store:    repo,
+		interval: interval,
+	}
+}
+
+// SetAccount syncs a seller account other than the default one: its records are tagged
+// with name and its cursors are kept apart from the default account's
+func (s *Syncer) SetAccount(name string) {
+	s.account = name
+}
+
+// CursorName returns the name of account's cursor for base, e.g. OrdersCursor. The
+// default account (empty) uses base itself.
+func CursorName(base, account string) string {
+	if account == "" {
+		return base
+	}
+	return base + ":" + account
+}
+
+// Start runs the sync loop in the background until Stop is called
+func (s *Syncer) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	log.Printf("🔄 Background sync started%s (every %s)", s.accountSuffix(), s.interval)
+	go s.loop(s.stop, s.done)
+}
+
+// Stop ends the sync loop and waits for an in-progress run to finish
+func (s *Syncer) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (s *Syncer) loop(stop, done chan struct{}) {
+	defer close(done)
+
+	wait := time.Duration(0) // first run immediately
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+
+		err := s.RunOnce()
+		wait = s.nextWait(err)
+		if err != nil {
+			log.Printf("⚠️ Background sync failed%s (next attempt in %s): %v", s.accountSuffix(), wait, err)
+		}
+	}
+}
+
+// nextWait returns how long to sleep after a run, backing off exponentially while rate-limited
+func (s *Syncer) nextWait(err error) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil || !ebay.IsRateLimited(err) {
+		s.backoff = 0
+		return s.interval
+	}
+
+	if s.backoff == 0 {
+		s.backoff = s.interval
+	}
+	s.backoff *= 2
+	if s.backoff > maxBackoff {
+		s.backoff = maxBackoff
+	}
+	return s.backoff
+}
+
+// RunOnce performs a single order and listing sync. Concurrent calls are skipped.
+func (s *Syncer) RunOnce() error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	if err := s.syncOrders(); err != nil {
+		return err
+	}
+	return s.syncListings()
+}
+
+// syncOrders pulls every order modified since the stored cursor
+func (s *Syncer) syncOrders() error {
+	name := CursorName(OrdersCursor, s.account)
+	cursor, err := s.store.GetSyncCursor(name)
+	if err != nil {
+		cursor = &store.SyncCursor{Name: name}
+	}
+
+	since := time.Now().Add(-initialLookback)
+	if !cursor.HighWater.IsZero() {
+		since = cursor.HighWater.Add(-cursorOverlap)
+	}
+
+	highWater := cursor.HighWater
+	synced := 0
+	for offset := 0; ; offset += ordersPageSize {
+		orders, total, err := s.source.GetOrdersModifiedSince(since, ordersPageSize, offset)
+		if err != nil {
+			return s.fail(cursor, fmt.Errorf("order sync: %w", err))
+		}
+
+		// Store each page with one write
+		records := make([]store.Order, 0, len(orders))
+		for _, order := range orders {
+			record := order.ToRecord()
+			record.Account = s.account
+			s.fillImages(&record)
+			records = append(records, record)
+		}
+		if err := s.store.UpsertOrders(records); err != nil {
+			return s.fail(cursor, fmt.Errorf("failed to store orders at offset %d: %w", offset, err))
+		}
+		for _, order := range orders {
+			if order.LastModifiedDate.After(highWater) {
+				highWater = order.LastModifiedDate
+			}
+		}
+		synced += len(records)
+
+		if len(orders) == 0 || offset+len(orders) >= total {
+			break
+		}
+	}
+
+	cursor.HighWater = highWater
+	cursor.LastSyncedAt = time.Now()
+	cursor.LastError = ""
+	if err := s.store.SaveSyncCursor(cursor); err != nil {
+		return fmt.Errorf("failed to save order cursor: %w", err)
+	}
+
+	if synced > 0 {
+		log.Printf("🔄 Synced %d orders%s", synced, s.accountSuffix())
+	}
+	return nil
+}
+
+// fillImages reuses image URLs already stored for this order and only looks up new line items
+func (s *Syncer) fillImages(record *store.Order) {
+	known := make(map[string]string)
+	if existing, err := s.store.GetOrder(record.OrderID); err == nil {
+		for _, li := range existing.LineItems {
+			if li.ImageURL != "" {
+				known[li.LineItemID] = li.ImageURL
+			}
+		}
+	}
+
+	for i := range record.LineItems {
+		li := &record.LineItems[i]
+		if li.ImageURL != "" {
+			continue
+		}
+		if img, ok := known[li.LineItemID]; ok {
+			li.ImageURL = img
+		} else if li.LegacyItemID != "" {
+			li.ImageURL = s.source.GetItemImage(li.LegacyItemID)
+		}
+	}
+}
+
+// syncListings replaces the stored active listings with what eBay currently reports
+func (s *Syncer) syncListings() error {
+	name := CursorName(ListingsCursor, s.account)
+	cursor, err := s.store.GetSyncCursor(name)
+	if err != nil {
+		cursor = &store.SyncCursor{Name: name}
+	}
+
+	seen := make(map[string]bool)
+	complete := false
+	for page := 1; page <= maxListingPages; page++ {
+		listings, totalPages, err := s.source.GetListingsPage(page, listingsPageSize)
+		if err != nil {
+			return s.fail(cursor, fmt.Errorf("listing sync: %w", err))
+		}
+
+		// Store each page with one write
+		records := make([]store.Listing, 0, len(listings))
+		for _, listing := range listings {
+			if listing.ListingID == "" {
+				continue
+			}
+			record := listing.ToRecord()
+			record.Account = s.account
+			records = append(records, record)
+			seen[listing.ListingID] = true
+		}
+		if err := s.store.UpsertListings(records); err != nil {
+			return s.fail(cursor, fmt.Errorf("failed to store listings page %d: %w", page, err))
+		}
+
+		if page >= totalPages {
+			complete = true
+			break
+		}
+	}
+
+	// Drop this account's listings that are no longer active (only safe when every page was read)
+	if complete {
+		stored, err := s.store.ListListings(0)
+		if err != nil {
+			return fmt.Errorf("failed to read stored listings: %w", err)
+		}
+		var ended []string
+		for _, l := range stored {
+			if l.Account == s.account && !seen[l.ListingID] {
+				ended = append(ended, l.ListingID)
+			}
+		}
+		if _, err := s.store.DeleteListings(ended); err != nil {
+			log.Printf("⚠️ Failed to remove %d ended listings: %v", len(ended), err)
+		}
+	}
+
+	cursor.LastSyncedAt = time.Now()
+	cursor.LastError = ""
+	if err := s.store.SaveSyncCursor(cursor); err != nil {
+		return fmt.Errorf("failed to save listing cursor: %w", err)
+	}
+	return nil
+}
+
+// accountSuffix names the account in log lines
+func (s *Syncer) accountSuffix() string {
+	if s.account == "" {
+		return ""
+	}
+	return " for account " + s.account
+}
+
+// fail records err on the cursor and returns it. The high-water mark is left
+// untouched so the next run re-reads everything the failed run may have missed.
+func (s *Syncer) fail(cursor *store.SyncCursor, err error) error {
+	cursor.LastError = err.Error()
+	if saveErr := s.store.SaveSyncCursor(cursor); saveErr != nil {
+		log.Printf("⚠️ Failed to save sync cursor %s: %v", cursor.Name, saveErr)
+	}
+	return err
+}
+
+// LastSynced returns when the named cursor last completed successfully
+func LastSynced(repo store.Repository, name string) (time.Time, bool) {
+	cursor, err := repo.GetSyncCursor(name)
+	if err != nil || cursor.LastSyncedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return cursor.LastSyncedAt, true
+}
@@ -0,0 +1,217 @@
+package syncer
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+)
+
+type fakeSource struct {
+	orders      []ebay.Order
+	listings    []ebay.Listing
+	ordersErr   error
+	sinceCalls  []time.Time
+	imageLookup int
+}
+
+func (f *fakeSource) GetOrdersModifiedSince(since time.Time, limit, offset int) ([]ebay.Order, int, error) {
+	f.sinceCalls = append(f.sinceCalls, since)
+	if f.ordersErr != nil {
+		return nil, 0, f.ordersErr
+	}
+	end := offset + limit
+	if end > len(f.orders) {
+		end = len(f.orders)
+	}
+	if offset >= len(f.orders) {
+		return nil, len(f.orders), nil
+	}
+	return f.orders[offset:end], len(f.orders), nil
+}
+
+func (f *fakeSource) GetListingsPage(page, perPage int) ([]ebay.Listing, int, error) {
+	if page > 1 {
+		return nil, 1, nil
+	}
+	return f.listings, 1, nil
+}
+
+func (f *fakeSource) GetItemImage(legacyItemID string) string {
+	f.imageLookup++
+	return "https://example.com/" + legacyItemID + ".jpg"
+}
+
+// countingStore counts the order and listing writes a sync makes
+type countingStore struct {
+	store.Repository
+	writes int
+}
+
+func (c *countingStore) UpsertOrder(order *store.Order) error {
+	c.writes++
+	return c.Repository.UpsertOrder(order)
+}
+
+func (c *countingStore) UpsertOrders(orders []store.Order) error {
+	c.writes++
+	return c.Repository.UpsertOrders(orders)
+}
+
+func (c *countingStore) UpsertListing(listing *store.Listing) error {
+	c.writes++
+	return c.Repository.UpsertListing(listing)
+}
+
+func (c *countingStore) UpsertListings(listings []store.Listing) error {
+	c.writes++
+	return c.Repository.UpsertListings(listings)
+}
+
+func openStore(t *testing.T) *store.FileStore {
+	t.Helper()
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	return repo
+}
+
+func TestRunOnceStoresOrdersAndAdvancesCursor(t *testing.T) {
+	repo := openStore(t)
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		orders: []ebay.Order{
+			{OrderID: "order-1", LastModifiedDate: modified, LineItems: []ebay.LineItem{{LineItemID: "li-1", LegacyItemId: "111"}}},
+			{OrderID: "order-2", LastModifiedDate: modified.Add(-time.Hour)},
+		},
+		listings: []ebay.Listing{{ListingID: "111", Title: "Widget"}},
+	}
+
+	s := New(source, repo, time.Minute)
+	if err := s.RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	orders, _ := repo.ListOrders(0)
+	if len(orders) != 2 {
+		t.Errorf("Expected 2 stored orders, got %d", len(orders))
+	}
+
+	cursor, err := repo.GetSyncCursor(OrdersCursor)
+	if err != nil {
+		t.Fatalf("Order cursor not saved: %v", err)
+	}
+	if !cursor.HighWater.Equal(modified) {
+		t.Errorf("Expected high water %v, got %v", modified, cursor.HighWater)
+	}
+
+	listings, _ := repo.ListListings(0)
+	if len(listings) != 1 {
+		t.Errorf("Expected 1 stored listing, got %d", len(listings))
+	}
+
+	// Second run resumes from the cursor and reuses stored images
+	if err := s.RunOnce(); err != nil {
+		t.Fatalf("Second RunOnce failed: %v", err)
+	}
+	if got := source.sinceCalls[len(source.sinceCalls)-1]; !got.Equal(modified.Add(-cursorOverlap)) {
+		t.Errorf("Expected second run to resume from cursor, got since=%v", got)
+	}
+	if source.imageLookup != 1 {
+		t.Errorf("Expected 1 image lookup across both runs, got %d", source.imageLookup)
+	}
+}
+
+func TestEndedListingsAreRemoved(t *testing.T) {
+	repo := openStore(t)
+	repo.UpsertListing(&store.Listing{ListingID: "old", Title: "Ended"})
+
+	source := &fakeSource{listings: []ebay.Listing{{ListingID: "new", Title: "Active"}}}
+	if err := New(source, repo, time.Minute).RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	listings, _ := repo.ListListings(0)
+	if len(listings) != 1 || listings[0].ListingID != "new" {
+		t.Errorf("Expected only the active listing to remain, got %+v", listings)
+	}
+}
+
+func TestAccountSyncKeepsToItself(t *testing.T) {
+	repo := openStore(t)
+	repo.UpsertListing(&store.Listing{ListingID: "main-1", Title: "Main"})
+
+	source := &fakeSource{
+		orders:   []ebay.Order{{OrderID: "order-9", LastModifiedDate: time.Now()}},
+		listings: []ebay.Listing{{ListingID: "vintage-1", Title: "Vintage"}},
+	}
+	s := New(source, repo, time.Minute)
+	s.SetAccount("vintage")
+	if err := s.RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	if order, err := repo.GetOrder("order-9"); err != nil || order.Account != "vintage" {
+		t.Errorf("Expected the order to be tagged with its account, got %+v, %v", order, err)
+	}
+	if _, err := repo.GetSyncCursor(CursorName(OrdersCursor, "vintage")); err != nil {
+		t.Errorf("Expected the account's own order cursor: %v", err)
+	}
+	if _, err := repo.GetSyncCursor(OrdersCursor); err == nil {
+		t.Error("Expected the default account's cursor to be left alone")
+	}
+
+	// Another account's listings aren't treated as ended
+	listings, _ := repo.ListListings(0)
+	if len(listings) != 2 {
+		t.Errorf("Expected both accounts' listings, got %+v", listings)
+	}
+}
+
+func TestRateLimitBacksOff(t *testing.T) {
+	repo := openStore(t)
+	source := &fakeSource{ordersErr: &ebay.APIError{StatusCode: 429, Body: "too many requests"}}
+	s := New(source, repo, time.Minute)
+
+	err := s.RunOnce()
+	if err == nil {
+		t.Fatal("Expected rate limit error")
+	}
+
+	first := s.nextWait(err)
+	second := s.nextWait(err)
+	if first != 2*time.Minute || second != 4*time.Minute {
+		t.Errorf("Expected exponential backoff 2m then 4m, got %s then %s", first, second)
+	}
+	if reset := s.nextWait(nil); reset != time.Minute {
+		t.Errorf("Expected backoff to reset to interval, got %s", reset)
+	}
+
+	cursor, _ := repo.GetSyncCursor(OrdersCursor)
+	if cursor == nil || cursor.LastError == "" {
+		t.Error("Expected the failure to be recorded on the cursor")
+	}
+}
+
+func TestSyncWritesOncePerPage(t *testing.T) {
+	repo := &countingStore{Repository: openStore(t)}
+	source := &fakeSource{listings: []ebay.Listing{{ListingID: "1"}, {ListingID: "2"}}}
+	for i := 0; i < ordersPageSize+1; i++ {
+		source.orders = append(source.orders, ebay.Order{OrderID: fmt.Sprintf("order-%d", i)})
+	}
+
+	if err := New(source, repo, time.Minute).RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	// Two order pages and one listing page
+	if repo.writes != 3 {
+		t.Errorf("Expected 3 writes, got %d", repo.writes)
+	}
+	if orders, _ := repo.ListOrders(0); len(orders) != ordersPageSize+1 {
+		t.Errorf("Expected %d stored orders, got %d", ordersPageSize+1, len(orders))
+	}
+}
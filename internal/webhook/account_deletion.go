@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"ebaymanager-bot/internal/store"
+)
+
+// accountDeletionPath is the endpoint registered for MARKETPLACE_ACCOUNT_DELETION
+// in the eBay developer portal (Alerts & Notifications)
+const accountDeletionPath = "/webhook/ebay/account-deletion"
+
+// handleAccountDeletion answers the endpoint challenge and accepts account deletion notifications
+// the same way as the notification endpoint: verified, deduplicated and, when simulated, a dry
+// run. eBay requires a quick 200 acknowledgement, so the purge runs after the response is sent.
+func (s *Server) handleAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleAccountDeletionChallenge(w, r)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	status, message := s.receiveNotification(body, r.Header, true)
+	reply(w, status, message)
+}
+
+// Account deletion errors recorded in history
+var (
+	errUnverifiedDeletion  = errors.New("account deletions must carry a verified eBay signature")
+	errDeletionWithoutUser = errors.New("account deletion data names no user")
+)
+
+// handleAccountDeletionChallenge responds to eBay's endpoint validation for account deletion.
+// Unlike topic subscriptions, this challenge expects a hex-encoded digest.
+func (s *Server) handleAccountDeletionChallenge(w http.ResponseWriter, r *http.Request) {
+	challengeCode := r.URL.Query().Get("challenge_code")
+	if challengeCode == "" {
+		http.Error(w, "Missing challenge_code parameter", http.StatusBadRequest)
+		return
+	}
+
+	endpointURL := s.challengeEndpoint(r)
+	log.Printf("📨 Received account deletion challenge for endpoint: %s", endpointURL)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"challengeResponse": accountDeletionChallengeResponse(challengeCode, s.verifyToken, endpointURL),
+	})
+}
+
+// accountDeletionChallengeResponse computes hex(SHA256(challengeCode + verifyToken + endpointURL))
+func accountDeletionChallengeResponse(challengeCode, verifyToken, endpointURL string) string {
+	hash := sha256.New()
+	hash.Write([]byte(challengeCode))
+	hash.Write([]byte(verifyToken))
+	hash.Write([]byte(endpointURL))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// acceptAccountDeletion starts the purge in the background. The payload contains
+// personal data, so it is deliberately not kept in notification history.
+func (s *Server) acceptAccountDeletion(notification *EbayNotification) {
+	log.Printf("🗑️ Received marketplace account deletion notification %s", notification.NotificationID)
+	receivedAt := time.Now()
+	s.goBackground(func() { s.purgeDeletedAccount(notification, receivedAt) })
+}
+
+// purgeDeletedAccount removes the user's data from the local store and records an audit entry
+func (s *Server) purgeDeletedAccount(notification *EbayNotification, receivedAt time.Time) {
+	data := notification.AccountDeletion
+	audit := &store.DeletionAudit{
+		NotificationID: notification.NotificationID,
+		UserHash:       hashDeletedUser(data.Username, data.UserID),
+		EventDate:      notification.PublishDate,
+		ReceivedAt:     receivedAt,
+	}
+
+	if s.store == nil {
+		log.Println("⚠️ No local store configured - nothing to purge for account deletion")
+		return
+	}
+
+	result, err := s.store.PurgeUser(data.Username, data.UserID)
+	audit.PurgedAt = time.Now()
+	if err != nil {
+		audit.Error = err.Error()
+		log.Printf("❌ Failed to purge deleted account data: %v", err)
+	} else {
+		audit.Result = *result
+		log.Printf("🗑️ Purged deleted account data (%d orders, %d offers, %d notifications, %d routes)",
+			result.OrdersScrubbed, result.OffersScrubbed, result.NotificationsDeleted, result.RoutesDeleted)
+	}
+
+	if err := s.store.SaveDeletionAudit(audit); err != nil {
+		log.Printf("❌ Failed to save account deletion audit: %v", err)
+	}
+}
+
+// hashDeletedUser identifies a deleted user in the audit log without storing their username
+func hashDeletedUser(username, userID string) string {
+	sum := sha256.Sum256([]byte(username + ":" + userID))
+	return hex.EncodeToString(sum[:])
+}
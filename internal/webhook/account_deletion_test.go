@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"ebaymanager-bot/internal/store"
+)
+
+func TestAccountDeletionChallenge(t *testing.T) {
+	server := NewServer(nil, "", "verify-token-1234567890123456789012", "0")
+
+	req := httptest.NewRequest(http.MethodGet, "https://bot.example.com/webhook/ebay/account-deletion?challenge_code=abc123", nil)
+	rec := httptest.NewRecorder()
+	server.handleAccountDeletion(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var resp map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	want := accountDeletionChallengeResponse("abc123", "verify-token-1234567890123456789012", "https://bot.example.com/webhook/ebay/account-deletion")
+	if resp["challengeResponse"] != want || len(want) != 64 {
+		t.Errorf("Unexpected challenge response %q", resp["challengeResponse"])
+	}
+}
+
+var testDeletionBody = []byte(`{"metadata":{"topic":"MARKETPLACE_ACCOUNT_DELETION"},"notification":{"notificationId":"n-1","eventDate":"2026-01-01T00:00:00.000Z","data":{"username":"gonebuyer","userId":"u-1","eiasToken":"t"}}}`)
+
+func TestAccountDeletionPurgesAndAudits(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	repo.UpsertOrder(&store.Order{OrderID: "o1", BuyerUsername: "gonebuyer", ShipTo: store.Address{Name: "Gone Buyer"}})
+
+	verifier, fetcher := newTestVerifier(t)
+	server := NewServer(nil, "", "token", "0")
+	server.SetStore(repo)
+	server.SetSignatureVerifier(verifier, false)
+
+	// eBay's retry of the same deletion, on either endpoint, is only acknowledged
+	signature := signBody(t, fetcher.key, "test-kid", testDeletionBody)
+	for _, path := range []string{accountDeletionPath, notificationPath, accountDeletionPath} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(testDeletionBody))
+		req.Header.Set("X-EBAY-SIGNATURE", signature)
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+		}
+	}
+	server.Shutdown(context.Background())
+
+	audits, _ := repo.ListDeletionAudits(0)
+	if len(audits) != 1 {
+		t.Fatalf("Expected 1 audit record, got %d", len(audits))
+	}
+	if audits[0].NotificationID != "n-1" || audits[0].Result.OrdersScrubbed != 1 {
+		t.Errorf("Unexpected audit record: %+v", audits[0])
+	}
+	if audits[0].UserHash == "" || audits[0].UserHash == "gonebuyer" {
+		t.Errorf("Audit should identify the user by hash, got %q", audits[0].UserHash)
+	}
+
+	order, _ := repo.GetOrder("o1")
+	if order.BuyerUsername != "" || order.ShipTo.Name != "" {
+		t.Errorf("Order personal data not purged: %+v", order)
+	}
+	notifications, _ := repo.ListNotifications(0)
+	if len(notifications) != 3 {
+		t.Fatalf("Expected 3 history records, got %d", len(notifications))
+	}
+	for _, n := range notifications {
+		if n.Payload != nil || n.Body != "" {
+			t.Errorf("Account deletion payload must not be kept in notification history: %+v", n)
+		}
+	}
+	if notifications[0].Outcome != OutcomeDuplicate || notifications[2].Outcome != OutcomePurged {
+		t.Errorf("Unexpected outcomes %s, %s", notifications[2].Outcome, notifications[0].Outcome)
+	}
+}
+
+func TestAccountDeletionEndpointRefusesOtherTopics(t *testing.T) {
+	server := NewServer(nil, "", "token", "0")
+	body := []byte(`{"metadata":{"topic":"MARKETPLACE_ORDER"},"notification":{"notificationId":"n-2","data":{"orderId":"1"}}}`)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, accountDeletionPath, bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUnsignedAccountDeletionPurgesNothing(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	repo.UpsertOrder(&store.Order{OrderID: "o1", BuyerUsername: "gonebuyer"})
+
+	// Strict mode is off, so other unsigned notifications would be accepted
+	verifier, _ := newTestVerifier(t)
+	server := NewServer(nil, "", "token", "0")
+	server.SetStore(repo)
+	server.SetSignatureVerifier(verifier, false)
+
+	for _, path := range []string{accountDeletionPath, notificationPath} {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(testDeletionBody)))
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("%s: got %d, want %d", path, rec.Code, http.StatusPreconditionFailed)
+		}
+	}
+	server.Shutdown(context.Background())
+
+	if order, _ := repo.GetOrder("o1"); order.BuyerUsername != "gonebuyer" {
+		t.Errorf("Unsigned deletion purged the order: %+v", order)
+	}
+	if audits, _ := repo.ListDeletionAudits(0); len(audits) != 0 {
+		t.Errorf("Unsigned deletion recorded %d audits", len(audits))
+	}
+	stored, _ := repo.ListNotifications(0)
+	for _, n := range stored {
+		if n.Outcome != OutcomeRejected || n.Payload != nil {
+			t.Errorf("Expected a rejected record without the payload, got %+v", n)
+		}
+	}
+	if len(stored) != 2 {
+		t.Errorf("Expected one record per endpoint, got %d", len(stored))
+	}
+}
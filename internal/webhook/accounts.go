@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SetAccounts lets the server attribute notifications to seller accounts by eBay user
+// ID, look up their orders with the account's own token and report each account's
+// token in /readyz
+func (s *Server) SetAccounts(accounts *ebay.Accounts) {
+	s.accounts = accounts
+}
+
+// SetAccountChannel sends an account's notifications to channelID when no routing rule
+// matches them, instead of the default notification channel
+func (s *Server) SetAccountChannel(account, channelID string) {
+	if channelID == "" {
+		return
+	}
+	if s.accountChannels == nil {
+		s.accountChannels = make(map[string]string)
+	}
+	s.accountChannels[account] = channelID
+}
+
+// defaultAccount returns the name of the account used when none is given
+func (s *Server) defaultAccount() string {
+	if s.accounts == nil || s.accounts.Len() == 0 {
+		return ebay.DefaultAccount
+	}
+	return s.accounts.Default().Account()
+}
+
+// multiAccount reports whether more than one seller account is configured, so
+// notifications need to say which one they are for
+func (s *Server) multiAccount() bool {
+	return s.accounts != nil && s.accounts.Len() > 1
+}
+
+// attribute sets the account a notification is for: the account whose eBay user ID it
+// names, or the default account. Notifications the poller built already have one.
+func (s *Server) attribute(notification *EbayNotification) {
+	if notification.Account != "" || s.accounts == nil || s.accounts.Len() == 0 {
+		return
+	}
+	if client := s.accounts.ForUser(notification.SellerID); client != nil {
+		notification.Account = client.Account()
+		return
+	}
+
+	notification.Account = s.accounts.Default().Account()
+	if notification.SellerID != "" && s.multiAccount() {
+		log.Printf("⚠️ %s is for eBay user %s, which matches no account - attributing it to %s",
+			notification.EventType(), notification.SellerID, notification.Account)
+	}
+}
+
+// accountWithSameSeller returns another account signed in as client's eBay seller, if any
+func (s *Server) accountWithSameSeller(client *ebay.Client) string {
+	if s.accounts == nil || client.UserID() == "" {
+		return ""
+	}
+	for _, other := range s.accounts.All() {
+		if other != client && other.UserID() == client.UserID() {
+			return other.Account()
+		}
+	}
+	return ""
+}
+
+// accountClient returns the client of the account a notification was attributed to
+func (s *Server) accountClient(notification *EbayNotification) *ebay.Client {
+	if s.accounts == nil {
+		return nil
+	}
+	client, err := s.accounts.Get(notification.Account)
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
+// defaultChannel is where a notification goes when no routing rule matches it
+func (s *Server) defaultChannel(notification *EbayNotification) string {
+	if channelID, ok := s.accountChannels[notification.Account]; ok {
+		return channelID
+	}
+	return s.channelID
+}
+
+// labelAccount names the account on a notification's embed when several are configured
+func (s *Server) labelAccount(notification *EbayNotification, embed *discordgo.MessageEmbed) {
+	if !s.multiAccount() || notification.Account == "" {
+		return
+	}
+	embed.Author = &discordgo.MessageEmbedAuthor{Name: "🏪 " + notification.Account}
+	if embed.Footer != nil {
+		embed.Footer.Text += " (account: " + notification.Account + ")"
+	}
+}
+
+// forAccount names a non-default account in log lines
+func forAccount(account string) string {
+	if account == "" {
+		return ""
+	}
+	return " for account " + account
+}
+
+// withUserID adds the seller's eBay user ID to a notification's data, as eBay does
+func withUserID(data interface{}, userID string) interface{} {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return data
+	}
+	fields["userId"] = userID
+	return fields
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+
+	"ebaymanager-bot/internal/config"
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+)
+
+// testAccounts returns accounts main and vintage, identified as eBay users seller-1 and seller-2
+func testAccounts(t *testing.T) *ebay.Accounts {
+	t.Helper()
+	tokens := ebay.NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	var clients []*ebay.Client
+	for name, userID := range map[string]string{"main": "seller-1", "vintage": "seller-2"} {
+		tokens.Account(name).Save(&ebay.Tokens{AccessToken: name + "-token", UserID: userID})
+	}
+	for _, name := range []string{"main", "vintage"} {
+		client := ebay.NewClient(config.EbayConfig{Account: name})
+		if err := client.SetTokenStore(tokens.Account(name)); err != nil {
+			t.Fatalf("SetTokenStore: %v", err)
+		}
+		clients = append(clients, client)
+	}
+	accounts, err := ebay.NewAccounts(clients...)
+	if err != nil {
+		t.Fatalf("NewAccounts: %v", err)
+	}
+	return accounts
+}
+
+func TestDecodeNotificationSeller(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"userId", `{"metadata":{"topic":"MARKETPLACE_ORDER"},"notification":{"data":{"eventType":"PLACED","userId":"seller-2"}}}`, "seller-2"},
+		{"sellerId", `{"metadata":{"topic":"MARKETPLACE_OFFER"},"notification":{"data":{"eventType":"CREATED","sellerId":"seller-1"}}}`, "seller-1"},
+		{"unnamed", `{"metadata":{"topic":"MARKETPLACE_ORDER"},"notification":{"data":{"eventType":"PLACED"}}}`, ""},
+		{"account deletion", `{"metadata":{"topic":"MARKETPLACE_ACCOUNT_DELETION"},"notification":{"data":{"userId":"buyer-9"}}}`, ""},
+	}
+	for _, tt := range tests {
+		n, err := DecodeNotification([]byte(tt.body))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if n.SellerID != tt.want {
+			t.Errorf("%s: SellerID = %q, want %q", tt.name, n.SellerID, tt.want)
+		}
+	}
+}
+
+func TestAttributeNotification(t *testing.T) {
+	server := NewServer(nil, "default", "token", "0")
+	server.SetAccounts(testAccounts(t))
+
+	tests := []struct {
+		sellerID string
+		account  string
+		want     string
+	}{
+		{"seller-2", "", "vintage"},
+		{"seller-1", "", "main"},
+		{"seller-9", "", "main"},
+		{"", "", "main"},
+		{"", "vintage", "vintage"}, // attributed by the poller
+	}
+	for _, tt := range tests {
+		n := &EbayNotification{Topic: TopicOrder, SellerID: tt.sellerID, Account: tt.account}
+		server.attribute(n)
+		if n.Account != tt.want {
+			t.Errorf("attribute(%q) = %q, want %q", tt.sellerID, n.Account, tt.want)
+		}
+	}
+}
+
+func TestAccountRouting(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	repo.SaveRoute(&store.Route{ChannelID: "vintage-offers", EventType: "OFFER", Account: "vintage"})
+
+	server := NewServer(nil, "default", "token", "0")
+	server.SetStore(repo)
+	server.SetAccounts(testAccounts(t))
+	server.SetAccountChannel("vintage", "vintage-all")
+
+	tests := []struct {
+		name         string
+		notification *EbayNotification
+		want         string
+	}{
+		{"routed", &EbayNotification{Topic: TopicOffer, Event: OfferCreated, Account: "vintage", Offer: &OfferEvent{}}, "vintage-offers"},
+		{"account channel", &EbayNotification{Topic: TopicOrder, Event: OrderPlaced, Account: "vintage", Order: &OrderEvent{}}, "vintage-all"},
+		{"other account", &EbayNotification{Topic: TopicOffer, Event: OfferCreated, Account: "main", Offer: &OfferEvent{}}, "default"},
+	}
+	for _, tt := range tests {
+		dests := server.destinations(tt.notification)
+		if len(dests) != 1 || dests[0].channelID != tt.want {
+			t.Errorf("%s: destinations() = %+v, want %s", tt.name, dests, tt.want)
+		}
+	}
+
+	n := &EbayNotification{Topic: TopicOrder, Event: OrderPlaced, Account: "vintage", Order: &OrderEvent{OrderID: "1"}}
+	embed := server.buildDiscordEmbed(n, nil)
+	if embed.Author == nil || embed.Author.Name != "🏪 vintage" {
+		t.Errorf("Expected the embed to name the account, got %+v", embed.Author)
+	}
+}
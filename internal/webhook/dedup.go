@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+// dedupTTL is how long a delivered notification is remembered. eBay retries
+// failed deliveries for up to a day, so this comfortably covers every retry.
+const dedupTTL = 48 * time.Hour
+
+// dedupKey identifies a notification across retries: its notificationId when
+// present, otherwise a hash of the raw payload
+func dedupKey(notification *EbayNotification, body []byte) string {
+	if notification.NotificationID != "" {
+		return "id:" + notification.NotificationID
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// isDuplicate records the notification and reports whether it was already processed.
+// Store errors are logged and treated as "not a duplicate" so no event is lost.
+func (s *Server) isDuplicate(notification *EbayNotification, body []byte) bool {
+	if s.store == nil {
+		return false
+	}
+
+	key := dedupKey(notification, body)
+	duplicate, err := s.store.MarkNotificationSeen(key, dedupTTL)
+	if err != nil {
+		log.Printf("⚠️ Failed to record notification for dedup: %v", err)
+		return false
+	}
+	if duplicate {
+		count := s.duplicates.Add(1)
+		log.Printf("🔁 Suppressed duplicate notification %s (%d suppressed since start)", key, count)
+	}
+	return duplicate
+}
+
+// forgetDelivery un-records a notification that couldn't be queued, so eBay's retry of
+// it is processed instead of being acknowledged as a duplicate
+func (s *Server) forgetDelivery(notification *EbayNotification, body []byte) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.ForgetNotificationSeen(dedupKey(notification, body)); err != nil {
+		log.Printf("⚠️ Failed to forget notification for dedup, eBay's retry will be suppressed: %v", err)
+	}
+}
+
+// DuplicatesSuppressed returns how many duplicate deliveries were acknowledged but not re-posted
+func (s *Server) DuplicatesSuppressed() uint64 {
+	return s.duplicates.Load()
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"ebaymanager-bot/internal/store"
+)
+
+func TestDuplicateDeliveriesAreSuppressed(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "", "token", "0")
+	server.SetStore(repo)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"with notification ID", `{"metadata":{"topic":"MARKETPLACE_ORDER"},"notification":{"notificationId":"n-1","data":{"eventType":"PLACED","orderId":"o-1"}}}`},
+		{"without notification ID", `{"metadata":{"topic":"MARKETPLACE_ORDER"},"notification":{"data":{"eventType":"PLACED","orderId":"o-2"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := server.DuplicatesSuppressed()
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest(http.MethodPost, "/webhook/ebay/notification", bytes.NewBufferString(tt.body))
+				rec := httptest.NewRecorder()
+				server.handleNotification(rec, req)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("Delivery %d: expected 200, got %d", i+1, rec.Code)
+				}
+			}
+			if got := server.DuplicatesSuppressed() - before; got != 2 {
+				t.Errorf("Expected 2 duplicates suppressed, got %d", got)
+			}
+		})
+	}
+
+	outcomes := make(map[string]int)
+	stored, _ := repo.ListNotifications(0)
+	for _, n := range stored {
+		outcomes[n.Outcome]++
+	}
+	if outcomes[OutcomeDuplicate] != 4 || len(stored) != 6 {
+		t.Errorf("Expected every delivery in history with 4 marked duplicate, got %v", outcomes)
+	}
+}
+
+// failingOutbox fails the first enqueue, like a store that is briefly unwritable
+type failingOutbox struct {
+	store.Repository
+	failures int
+}
+
+func (f *failingOutbox) EnqueueOutbox(msg *store.OutboxMessage) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("disk full")
+	}
+	return f.Repository.EnqueueOutbox(msg)
+}
+
+func TestRetryAfterFailedEnqueueIsDelivered(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "chan-1", "token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(&fakeSender{}, &failingOutbox{Repository: repo, failures: 1}, 1))
+
+	body := `{"metadata":{"topic":"MARKETPLACE_OFFER"},"notification":{"notificationId":"n-9","data":{"offerId":"of-1","eventType":"CREATED"}}}`
+	for i, want := range []int{http.StatusInternalServerError, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/ebay/notification", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		server.handleNotification(rec, req)
+		if rec.Code != want {
+			t.Fatalf("Delivery %d: expected %d, got %d", i+1, want, rec.Code)
+		}
+	}
+
+	if got := server.DuplicatesSuppressed(); got != 0 {
+		t.Errorf("The retry should not be suppressed as a duplicate, %d were", got)
+	}
+	if queued, _ := repo.ListOutbox(store.OutboxPending, 0); len(queued) != 1 {
+		t.Errorf("Expected the retry to be queued once, got %d messages", len(queued))
+	}
+}
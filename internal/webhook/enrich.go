@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// enrichTimeout bounds the order lookup so eBay still gets a prompt acknowledgement
+	enrichTimeout = 5 * time.Second
+
+	// maxEmbedLineItems is how many line items are listed before summarizing the rest
+	maxEmbedLineItems = 10
+)
+
+// OrderFetcher looks up full order details to enrich order notifications
+type OrderFetcher interface {
+	GetOrderByID(orderID string) (*ebay.Order, error)
+}
+
+// SetOrderFetcher enables fetching the full order when an order notification arrives.
+// Orders of an account set with SetAccounts are fetched with that account's client.
+func (s *Server) SetOrderFetcher(fetcher OrderFetcher) {
+	s.orders = fetcher
+}
+
+// orderFetcher returns what looks up the notification's order, or nil if enrichment is off
+func (s *Server) orderFetcher(notification *EbayNotification) OrderFetcher {
+	if s.orders == nil {
+		return nil
+	}
+	if client := s.accountClient(notification); client != nil {
+		return client
+	}
+	return s.orders
+}
+
+// orderDetails fetches the full order for an order notification and fills in any
+// fields the notification left out. It returns nil when the lookup is not possible
+// or fails, in which case the basic embed is sent.
+func (s *Server) orderDetails(notification *EbayNotification) *ebay.Order {
+	event := notification.Order
+	fetcher := s.orderFetcher(notification)
+	if fetcher == nil || event == nil || event.OrderID == "" {
+		return nil
+	}
+
+	type result struct {
+		order *ebay.Order
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		order, err := fetcher.GetOrderByID(event.OrderID)
+		ch <- result{order, err}
+	}()
+
+	var order *ebay.Order
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			log.Printf("⚠️ Failed to fetch order %s for notification: %v", event.OrderID, r.err)
+			return nil
+		}
+		order = r.order
+	case <-time.After(enrichTimeout):
+		log.Printf("⚠️ Timed out fetching order %s for notification, sending basic details", event.OrderID)
+		return nil
+	}
+	if order == nil {
+		return nil
+	}
+
+	if event.BuyerUsername == "" {
+		event.BuyerUsername = order.BuyerUsername
+	}
+	if event.TotalPrice.Value == "" && order.TotalPrice > 0 {
+		event.TotalPrice = Amount{Value: strconv.FormatFloat(order.TotalPrice, 'f', 2, 64), Currency: order.Currency}
+	}
+	if len(event.SKUs) == 0 {
+		for _, li := range order.LineItems {
+			if li.SKU != "" {
+				event.SKUs = append(event.SKUs, li.SKU)
+			}
+		}
+	}
+	return order
+}
+
+// addOrderDetails renders line items, shipping destination and ship-by date from the full order
+func addOrderDetails(embed *discordgo.MessageEmbed, order *ebay.Order) {
+	var items strings.Builder
+	for i, li := range order.LineItems {
+		if i == maxEmbedLineItems {
+			fmt.Fprintf(&items, "…and %d more\n", len(order.LineItems)-i)
+			break
+		}
+		fmt.Fprintf(&items, "• %d × %s", li.Quantity, li.Title)
+		if li.SKU != "" {
+			fmt.Fprintf(&items, " (`%s`)", li.SKU)
+		}
+		if li.Price > 0 {
+			fmt.Fprintf(&items, " - %s", Amount{Value: strconv.FormatFloat(li.Price, 'f', 2, 64), Currency: order.Currency})
+		}
+		items.WriteString("\n")
+
+		if embed.Thumbnail == nil && li.ImageUrl != "" {
+			embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: li.ImageUrl}
+		}
+	}
+	if items.Len() > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("🛍️ Items (%d)", len(order.LineItems)),
+			Value:  truncateField(items.String()),
+			Inline: false,
+		})
+	}
+
+	if dest := shipToSummary(order.ShipTo()); dest != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "🚚 Ship To",
+			Value:  dest,
+			Inline: true,
+		})
+	}
+	if shipBy := order.ShipByDate(); !shipBy.IsZero() {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "⏰ Ship By",
+			Value:  fmt.Sprintf("<t:%d:D> (<t:%d:R>)", shipBy.Unix(), shipBy.Unix()),
+			Inline: true,
+		})
+	}
+}
+
+// shipToSummary returns the city and state of an address, with the country outside the US
+func shipToSummary(addr ebay.Address) string {
+	var parts []string
+	for _, p := range []string{addr.City, addr.State} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if addr.Country != "" && addr.Country != "US" {
+		parts = append(parts, addr.Country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// truncateField shortens s to Discord's embed field value limit
+func truncateField(s string) string {
+	const limit = 1024
+	if r := []rune(s); len(r) > limit {
+		return string(r[:limit-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type fakeOrderFetcher struct {
+	order *ebay.Order
+	err   error
+}
+
+func (f fakeOrderFetcher) GetOrderByID(orderID string) (*ebay.Order, error) {
+	return f.order, f.err
+}
+
+func TestOrderEnrichment(t *testing.T) {
+	full := &ebay.Order{
+		OrderID:       "12-345",
+		BuyerUsername: "jane",
+		TotalPrice:    120,
+		Currency:      "USD",
+	}
+	item := ebay.LineItem{Title: "Camera", SKU: "CAM-1", Quantity: 2, Price: 60, ImageUrl: "https://i.ebayimg.com/cam.jpg"}
+	item.LineItemFulfillmentInstructions.ShipByDate = time.Date(2024, 5, 3, 7, 0, 0, 0, time.UTC)
+	full.LineItems = []ebay.LineItem{item}
+	var instruction ebay.FulfillmentInstruction
+	instruction.ShippingStep.ShipTo = ebay.Address{City: "San Jose", State: "CA", Country: "US"}
+	full.FulfillmentStartInstructions = []ebay.FulfillmentInstruction{instruction}
+
+	tests := []struct {
+		name       string
+		fetcher    OrderFetcher
+		wantFields []string
+		wantThumb  bool
+	}{
+		{"enriched", fakeOrderFetcher{order: full}, []string{"📦 Order ID", "👤 Buyer", "💰 Total", "🛍️ Items (1)", "🚚 Ship To", "⏰ Ship By"}, true},
+		{"lookup fails", fakeOrderFetcher{err: errors.New("boom")}, []string{"📦 Order ID", "📦 Item"}, false},
+		{"no fetcher", nil, []string{"📦 Order ID", "📦 Item"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(nil, "default", "token", "0")
+			if tt.fetcher != nil {
+				server.SetOrderFetcher(tt.fetcher)
+			}
+			n := &EbayNotification{Topic: TopicOrder, Event: OrderPlaced, Order: &OrderEvent{OrderID: "12-345", ItemTitle: "Camera"}}
+
+			embed := server.buildDiscordEmbed(n, server.orderDetails(n))
+
+			if got := fieldNames(embed); strings.Join(got, "|") != strings.Join(tt.wantFields, "|") {
+				t.Errorf("Fields = %v, want %v", got, tt.wantFields)
+			}
+			if (embed.Thumbnail != nil) != tt.wantThumb {
+				t.Errorf("Thumbnail = %v, want set: %v", embed.Thumbnail, tt.wantThumb)
+			}
+		})
+	}
+}
+
+func TestOrderEnrichmentFillsRoutingFacts(t *testing.T) {
+	server := NewServer(nil, "default", "token", "0")
+	server.SetOrderFetcher(fakeOrderFetcher{order: &ebay.Order{
+		BuyerUsername: "jane",
+		TotalPrice:    612.5,
+		LineItems:     []ebay.LineItem{{SKU: "CAM-1"}},
+	}})
+
+	n := &EbayNotification{Topic: TopicOrder, Event: OrderPaid, Order: &OrderEvent{OrderID: "12-345"}}
+	server.orderDetails(n)
+
+	facts := factsFor(n)
+	if facts.buyer != "jane" || facts.amount != 612.5 || len(facts.skus) != 1 {
+		t.Errorf("Unexpected facts after enrichment: %+v", facts)
+	}
+}
+
+func TestShipToSummary(t *testing.T) {
+	tests := []struct {
+		addr ebay.Address
+		want string
+	}{
+		{ebay.Address{City: "San Jose", State: "CA", Country: "US"}, "San Jose, CA"},
+		{ebay.Address{City: "Toronto", State: "ON", Country: "CA"}, "Toronto, ON, CA"},
+		{ebay.Address{}, ""},
+	}
+	for _, tt := range tests {
+		if got := shipToSummary(tt.addr); got != tt.want {
+			t.Errorf("shipToSummary(%+v) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func fieldNames(embed *discordgo.MessageEmbed) []string {
+	var names []string
+	for _, f := range embed.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Event types published to sinks
+const (
+	EventOrderPlaced     = "OrderPlaced"
+	EventPaymentReceived = "PaymentReceived"
+	EventOrderShipped    = "OrderShipped"
+	EventOrderFulfilled  = "OrderFulfilled"
+	EventOrderDelivered  = "OrderDelivered"
+	EventOrderRefunded   = "OrderRefunded"
+	EventOrderCancelled  = "OrderCancelled"
+	EventOfferReceived   = "OfferReceived"
+	EventOfferCountered  = "OfferCountered"
+	EventOfferUpdated    = "OfferUpdated"
+	EventOfferAccepted   = "OfferAccepted"
+	EventOfferDeclined   = "OfferDeclined"
+	EventOfferExpired    = "OfferExpired"
+	EventQuantityChanged = "QuantityChanged"
+	EventListingEnded    = "ListingEnded"
+	EventOther           = "Other" // a notification the bot has no event type for
+)
+
+// Event sources
+const (
+	SourceWebhook   = "webhook"
+	SourcePoller    = "poller"
+	SourceReplay    = "replay"
+	SourceSimulator = "simulator"
+)
+
+// eventTypes maps eBay topics and events to event types
+var eventTypes = map[string]string{
+	TopicOrder + "." + OrderPlaced:                  EventOrderPlaced,
+	TopicOrder + "." + OrderPaid:                    EventPaymentReceived,
+	TopicOrder + "." + OrderShipped:                 EventOrderShipped,
+	TopicOrder + "." + OrderFulfilled:               EventOrderFulfilled,
+	TopicOrder + "." + OrderDelivered:               EventOrderDelivered,
+	TopicOrder + "." + OrderRefunded:                EventOrderRefunded,
+	TopicOrder + "." + OrderCancelled:               EventOrderCancelled,
+	TopicOffer + "." + OfferCreated:                 EventOfferReceived,
+	TopicOffer + "." + OfferCountered:               EventOfferCountered,
+	TopicOffer + "." + OfferUpdated:                 EventOfferUpdated,
+	TopicOffer + "." + OfferAccepted:                EventOfferAccepted,
+	TopicOffer + "." + OfferDeclined:                EventOfferDeclined,
+	TopicOffer + "." + OfferExpired:                 EventOfferExpired,
+	TopicInventory + "." + InventoryQuantityChanged: EventQuantityChanged,
+	TopicInventory + "." + InventoryListingEnded:    EventListingEnded,
+}
+
+// Event is a seller event, independent of how it was detected, as published to sinks
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Source     string    `json:"source"`
+	Account    string    `json:"account,omitempty"` // seller account the event is for
+
+	// The eBay notification the event came from
+	Topic          string `json:"topic"`
+	EbayEvent      string `json:"ebayEvent,omitempty"`
+	NotificationID string `json:"notificationId,omitempty"`
+
+	Order *OrderEvent     `json:"order,omitempty"`
+	Offer *OfferEvent     `json:"offer,omitempty"`
+	Item  *InventoryEvent `json:"item,omitempty"`
+}
+
+// NewEvent converts a notification into an event. Account deletions are not events
+// (they carry personal data) and return nil.
+func NewEvent(notification *EbayNotification, source string) *Event {
+	if notification.IsAccountDeletion() {
+		return nil
+	}
+
+	event := &Event{
+		ID:             notification.NotificationID,
+		Type:           eventTypes[notification.EventType()],
+		OccurredAt:     time.Now().UTC(),
+		Source:         source,
+		Account:        notification.Account,
+		Topic:          notification.Topic,
+		EbayEvent:      notification.Event,
+		NotificationID: notification.NotificationID,
+		Order:          notification.Order,
+		Offer:          notification.Offer,
+		Item:           notification.Inventory,
+	}
+	if event.Type == "" {
+		event.Type = EventOther
+	}
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if t, err := time.Parse(time.RFC3339, notification.PublishDate); err == nil {
+		event.OccurredAt = t.UTC()
+	}
+	return event
+}
+
+// newEventID returns a random ID for events that have no eBay notification ID
+func newEventID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
+
+// EventFilter selects event types by name, case-insensitively. Patterns may end in *
+// to match a prefix (Order*); an empty filter matches every event.
+type EventFilter []string
+
+// Match reports whether eventType is selected by the filter
+func (f EventFilter) Match(eventType string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, p := range f {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(strings.ToLower(eventType), strings.ToLower(prefix)) {
+				return true
+			}
+		} else if strings.EqualFold(p, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the filter as it would be configured
+func (f EventFilter) String() string {
+	if len(f) == 0 {
+		return "all events"
+	}
+	return strings.Join(f, ",")
+}
+
+// AddSink publishes every event the sink accepts to it, after the notification has
+// been queued for Discord
+func (s *Server) AddSink(sink Sink) {
+	s.sinks = append(s.sinks, sink)
+}
+
+// SetDiscordEvents limits which events are posted to Discord; empty posts all of them
+func (s *Server) SetDiscordEvents(filter EventFilter) {
+	s.discordEvents = filter
+}
+
+// publish hands event to every sink that accepts it
+func (s *Server) publish(event *Event) {
+	for _, sink := range s.sinks {
+		if sink.Accepts(event.Type) {
+			sink.Publish(event)
+		}
+	}
+}
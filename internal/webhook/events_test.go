@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"ebaymanager-bot/internal/store"
+)
+
+func TestNewEvent(t *testing.T) {
+	tests := []struct {
+		topic, event string
+		want         string
+	}{
+		{TopicOrder, OrderPlaced, EventOrderPlaced},
+		{TopicOrder, OrderPaid, EventPaymentReceived},
+		{TopicOffer, OfferCreated, EventOfferReceived},
+		{TopicOffer, OfferCountered, EventOfferCountered},
+		{TopicInventory, InventoryListingEnded, EventListingEnded},
+		{"MARKETPLACE_SOMETHING_NEW", "", EventOther},
+	}
+	for _, tt := range tests {
+		event := NewEvent(&EbayNotification{Topic: tt.topic, Event: tt.event}, SourcePoller)
+		if event.Type != tt.want {
+			t.Errorf("NewEvent(%s.%s).Type = %q, want %q", tt.topic, tt.event, event.Type, tt.want)
+		}
+	}
+
+	event := NewEvent(&EbayNotification{
+		Topic:          TopicOrder,
+		Event:          OrderPlaced,
+		NotificationID: "n-1",
+		PublishDate:    "2024-03-01T12:00:00.000Z",
+		Order:          &OrderEvent{OrderID: "12-345"},
+	}, SourceWebhook)
+	if event.ID != "n-1" || event.Source != SourceWebhook || event.Order.OrderID != "12-345" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.OccurredAt.Format("2006-01-02T15:04") != "2024-03-01T12:00" {
+		t.Errorf("Expected the publish date as OccurredAt, got %v", event.OccurredAt)
+	}
+
+	if generated := NewEvent(&EbayNotification{Topic: TopicOrder}, SourcePoller); generated.ID == "" {
+		t.Error("Expected an event ID to be generated")
+	}
+	if NewEvent(&EbayNotification{Topic: TopicAccountDeletion, AccountDeletion: &AccountDeletionEvent{}}, SourceWebhook) != nil {
+		t.Error("Expected account deletions not to be events")
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	tests := []struct {
+		filter    EventFilter
+		eventType string
+		want      bool
+	}{
+		{nil, EventOrderPlaced, true},
+		{EventFilter{"OrderPlaced"}, EventOrderPlaced, true},
+		{EventFilter{"orderplaced"}, EventOrderPlaced, true},
+		{EventFilter{"OrderPlaced"}, EventOrderShipped, false},
+		{EventFilter{"Order*"}, EventOrderShipped, true},
+		{EventFilter{"Order*"}, EventPaymentReceived, false},
+		{EventFilter{"offer*", "PaymentReceived"}, EventPaymentReceived, true},
+		{EventFilter{"*"}, EventOther, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.eventType); got != tt.want {
+			t.Errorf("%v.Match(%q) = %v, want %v", tt.filter, tt.eventType, got, tt.want)
+		}
+	}
+}
+
+// fakeSink records the events published to it
+type fakeSink struct {
+	filter EventFilter
+
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (f *fakeSink) Name() string                  { return "fake" }
+func (f *fakeSink) Accepts(eventType string) bool { return f.filter.Match(eventType) }
+
+func (f *fakeSink) Publish(event *Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+func TestEventsFanOutToSinks(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "sales", "token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(&fakeSender{}, repo, 1))
+	server.SetDiscordEvents(EventFilter{"Order*"})
+
+	all := &fakeSink{}
+	offers := &fakeSink{filter: EventFilter{"Offer*"}}
+	server.AddSink(all)
+	server.AddSink(offers)
+
+	post := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/ebay/notification", bytes.NewBufferString(body))
+		server.handleNotification(httptest.NewRecorder(), req)
+	}
+	post(`{"metadata":{"topic":"MARKETPLACE_ORDER"},"notification":{"notificationId":"n-1","data":{"eventType":"PLACED","orderId":"12-345"}}}`)
+	post(`{"metadata":{"topic":"MARKETPLACE_OFFER"},"notification":{"notificationId":"n-2","data":{"eventType":"CREATED","offerId":"o-1"}}}`)
+
+	if len(all.events) != 2 || all.events[0].Type != EventOrderPlaced || all.events[0].Source != SourceWebhook {
+		t.Errorf("Expected both events in the unfiltered sink, got %+v", all.events)
+	}
+	if len(offers.events) != 1 || offers.events[0].Type != EventOfferReceived {
+		t.Errorf("Expected only the offer in the offer sink, got %+v", offers.events)
+	}
+
+	// DISCORD_EVENTS only lets the order through to Discord
+	if pending, _ := repo.ListOutbox(store.OutboxPending, 0); len(pending) != 1 || pending[0].EventType != "MARKETPLACE_ORDER.PLACED" {
+		t.Errorf("Expected only the order to be queued for Discord, got %+v", pending)
+	}
+	if stored, _ := repo.ListNotifications(1); len(stored) != 1 || stored[0].Outcome != OutcomeNoChannel {
+		t.Errorf("Expected the filtered offer to be recorded as not posted, got %+v", stored)
+	}
+}
@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Health thresholds
+const (
+	staleNotificationAfter = 72 * time.Hour // no notification for this long is worth a look
+	staleHeartbeatAfter    = 2 * time.Minute
+	queueBacklogWarning    = 100 // pending Discord messages
+)
+
+// Component and overall health states
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // working, but something needs attention
+	HealthDown     = "down"
+)
+
+// ComponentHealth is the state of one dependency in a health report
+type ComponentHealth struct {
+	Status   string                 `json:"status"`
+	Critical bool                   `json:"critical"` // a critical component that is down fails the check
+	Message  string                 `json:"message,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// HealthReport is the JSON body of /healthz and /readyz
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Time       time.Time                  `json:"time"`
+	Uptime     string                     `json:"uptime"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// EbayStatus reports the eBay client's token and last-call state
+type EbayStatus interface {
+	Status() ebay.APIStatus
+}
+
+// storeHealth is implemented by stores that track their writes
+type storeHealth interface {
+	Health() store.StoreHealth
+}
+
+// SetEbayStatus adds the eBay token and API state to /readyz
+func (s *Server) SetEbayStatus(status EbayStatus) {
+	s.ebayStatus = status
+}
+
+// handleLiveness reports whether the process can do its job at all: the Discord
+// gateway is connected and the store accepts writes
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	s.writeHealth(w, map[string]ComponentHealth{
+		"discord": discordHealth(s.discord, time.Now()),
+		"store":   s.storeHealth(),
+	})
+}
+
+// handleReadiness reports the state of every dependency
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	components := map[string]ComponentHealth{
+		"discord":       discordHealth(s.discord, now),
+		"store":         s.storeHealth(),
+		"notifications": notificationHealth(s.LastWebhookDelivery(), now),
+	}
+	switch {
+	case s.accounts != nil:
+		// Accounts other than the default one degrade readiness rather than fail it
+		for n, client := range s.accounts.All() {
+			if n == 0 {
+				components["ebay"] = ebayHealth(client.Status(), now)
+				continue
+			}
+			c := ebayHealth(client.Status(), now)
+			c.Critical = false
+			components["ebay:"+client.Account()] = c
+		}
+	case s.ebayStatus != nil:
+		components["ebay"] = ebayHealth(s.ebayStatus.Status(), now)
+	}
+	if s.store != nil {
+		components["queue"] = s.queueHealth()
+	}
+	s.writeHealth(w, components)
+}
+
+// writeHealth sends the report, with 503 when a critical component is down
+func (s *Server) writeHealth(w http.ResponseWriter, components map[string]ComponentHealth) {
+	report := HealthReport{
+		Status:     HealthOK,
+		Time:       time.Now().UTC(),
+		Uptime:     time.Since(s.started).Round(time.Second).String(),
+		Components: components,
+	}
+	for _, c := range components {
+		switch {
+		case c.Status == HealthDown && c.Critical:
+			report.Status = HealthDown
+		case c.Status != HealthOK && report.Status == HealthOK:
+			report.Status = HealthDegraded
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == HealthDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// discordHealth checks the gateway connection and heartbeat
+func discordHealth(session *discordgo.Session, now time.Time) ComponentHealth {
+	c := ComponentHealth{Status: HealthOK, Critical: true}
+	if session == nil {
+		c.Status, c.Message = HealthDown, "no Discord session"
+		return c
+	}
+
+	session.RLock()
+	ready := session.DataReady
+	lastAck := session.LastHeartbeatAck
+	session.RUnlock()
+
+	c.Details = map[string]interface{}{"connected": ready}
+	if !lastAck.IsZero() {
+		c.Details["lastHeartbeatAck"] = lastAck
+		c.Details["latencyMs"] = session.HeartbeatLatency().Milliseconds()
+	}
+	switch {
+	case !ready:
+		c.Status, c.Message = HealthDown, "gateway disconnected"
+	case !lastAck.IsZero() && now.Sub(lastAck) > staleHeartbeatAfter:
+		c.Status, c.Message = HealthDegraded, fmt.Sprintf("no heartbeat ACK for %s", now.Sub(lastAck).Round(time.Second))
+	}
+	return c
+}
+
+// ebayHealth checks that the seller's token is present and still accepted
+func ebayHealth(status ebay.APIStatus, now time.Time) ComponentHealth {
+	c := ComponentHealth{Status: HealthOK, Critical: true, Details: map[string]interface{}{
+		"environment": status.Environment,
+	}}
+	if !status.TokenExpiry.IsZero() {
+		c.Details["tokenExpiry"] = status.TokenExpiry
+	}
+	if !status.RefreshExpiry.IsZero() {
+		c.Details["refreshExpiry"] = status.RefreshExpiry
+	}
+	if !status.LastSuccess.IsZero() {
+		c.Details["lastSuccess"] = status.LastSuccess
+	}
+	if status.LastError != "" {
+		c.Details["lastError"] = truncateField(status.LastError)
+		c.Details["lastErrorAt"] = status.LastErrorAt
+	}
+
+	switch {
+	case !status.HasToken:
+		c.Status, c.Message = HealthDown, "not authorized - run /ebay-authorize"
+	case status.TokenRejected:
+		c.Status, c.Message = HealthDown, "eBay rejected the access token - run /ebay-authorize"
+	case status.TokenExpired(now):
+		c.Status, c.Message = HealthDown, "access token expired"
+	case !status.RefreshExpiry.IsZero() && !now.Before(status.RefreshExpiry):
+		c.Status, c.Message = HealthDown, "refresh token expired - run /ebay-authorize"
+	case status.LastErrorAt.After(status.LastSuccess):
+		c.Status, c.Message = HealthDegraded, "last eBay API call failed"
+	case !status.RefreshExpiry.IsZero() && status.RefreshExpiry.Sub(now) < 7*24*time.Hour:
+		c.Status, c.Message = HealthDegraded, "refresh token expires "+status.RefreshExpiry.Format("2006-01-02")+" - run /ebay-authorize"
+	}
+	return c
+}
+
+// notificationHealth flags a webhook that has gone quiet
+func notificationHealth(last, now time.Time) ComponentHealth {
+	c := ComponentHealth{Status: HealthOK}
+	switch {
+	case last.IsZero():
+		c.Status, c.Message = HealthDegraded, "no notification received from eBay yet"
+	case now.Sub(last) > staleNotificationAfter:
+		c.Status, c.Message = HealthDegraded, fmt.Sprintf("no notification for %s", now.Sub(last).Round(time.Minute))
+	}
+	if !last.IsZero() {
+		c.Details = map[string]interface{}{"lastReceived": last}
+	}
+	return c
+}
+
+// storeHealth checks that the store is persisting writes
+func (s *Server) storeHealth() ComponentHealth {
+	c := ComponentHealth{Status: HealthOK, Critical: true}
+	if s.store == nil {
+		c.Status, c.Message = HealthDown, "no store configured"
+		return c
+	}
+	h, ok := s.store.(storeHealth)
+	if !ok {
+		return c
+	}
+
+	health := h.Health()
+	c.Details = map[string]interface{}{"path": health.Path}
+	if !health.LastWrite.IsZero() {
+		c.Details["lastWrite"] = health.LastWrite
+	}
+	if health.LastError != "" {
+		c.Status, c.Message = HealthDown, health.LastError
+		c.Details["lastErrorAt"] = health.LastErrorAt
+	}
+	return c
+}
+
+// queueHealth reports the depth of the Discord delivery queue
+func (s *Server) queueHealth() ComponentHealth {
+	c := ComponentHealth{Status: HealthOK}
+	pending, dead, err := outboxDepth(s.store)
+	if err != nil {
+		c.Status, c.Message = HealthDegraded, err.Error()
+		return c
+	}
+
+	c.Details = map[string]interface{}{"pending": len(pending), "deadLetters": len(dead)}
+	if len(pending) > 0 {
+		c.Details["oldestPending"] = pending[0].CreatedAt
+	}
+	switch {
+	case len(pending) > queueBacklogWarning:
+		c.Status, c.Message = HealthDegraded, fmt.Sprintf("%d messages waiting for Discord", len(pending))
+	case len(dead) > 0:
+		c.Status, c.Message = HealthDegraded, fmt.Sprintf("%d dead-lettered messages - see /dead-letters", len(dead))
+	}
+	return c
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type fakeEbayStatus ebay.APIStatus
+
+func (f fakeEbayStatus) Status() ebay.APIStatus { return ebay.APIStatus(f) }
+
+func TestReadiness(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		connected  bool
+		ebay       ebay.APIStatus
+		wantCode   int
+		wantStatus string
+		component  string
+		wantState  string
+	}{
+		{"healthy", true, ebay.APIStatus{HasToken: true, LastSuccess: now}, http.StatusOK, HealthDegraded, "ebay", HealthOK},
+		{"discord down", false, ebay.APIStatus{HasToken: true}, http.StatusServiceUnavailable, HealthDown, "discord", HealthDown},
+		{"token rejected", true, ebay.APIStatus{HasToken: true, TokenRejected: true}, http.StatusServiceUnavailable, HealthDown, "ebay", HealthDown},
+		{"token expired", true, ebay.APIStatus{HasToken: true, TokenExpiry: now.Add(-time.Minute)}, http.StatusServiceUnavailable, HealthDown, "ebay", HealthDown},
+		{"no token", true, ebay.APIStatus{}, http.StatusServiceUnavailable, HealthDown, "ebay", HealthDown},
+		{"last call failed", true, ebay.APIStatus{HasToken: true, LastSuccess: now.Add(-time.Hour), LastError: "boom", LastErrorAt: now}, http.StatusOK, HealthDegraded, "ebay", HealthDegraded},
+	}
+
+	for _, tt := range tests {
+		server := NewServer(&discordgo.Session{DataReady: tt.connected, LastHeartbeatAck: now}, "", "verify-token", "0")
+		server.SetStore(repo)
+		server.SetEbayStatus(fakeEbayStatus(tt.ebay))
+
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var report HealthReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", tt.name, err)
+		}
+		if rec.Code != tt.wantCode || report.Status != tt.wantStatus {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, rec.Code, report.Status, tt.wantCode, tt.wantStatus)
+		}
+		if got := report.Components[tt.component].Status; got != tt.wantState {
+			t.Errorf("%s: %s is %q, want %q", tt.name, tt.component, got, tt.wantState)
+		}
+		for _, name := range []string{"discord", "ebay", "store", "queue", "notifications"} {
+			if _, ok := report.Components[name]; !ok {
+				t.Errorf("%s: missing component %s", tt.name, name)
+			}
+		}
+	}
+}
+
+func TestLivenessIgnoresEbay(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(&discordgo.Session{DataReady: true}, "", "verify-token", "0")
+	server.SetStore(repo)
+	server.SetEbayStatus(fakeEbayStatus{})
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 without an eBay token, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestQueueHealth(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "", "verify-token", "0")
+	server.SetStore(repo)
+
+	if c := server.queueHealth(); c.Status != HealthOK || c.Details["pending"] != 0 {
+		t.Errorf("Expected an empty healthy queue, got %+v", c)
+	}
+
+	msg := &store.OutboxMessage{ChannelID: "chan", Embed: json.RawMessage(`{}`)}
+	if err := repo.EnqueueOutbox(msg); err != nil {
+		t.Fatalf("EnqueueOutbox failed: %v", err)
+	}
+	msg.Status = store.OutboxDead
+	if err := repo.UpdateOutbox(msg); err != nil {
+		t.Fatalf("UpdateOutbox failed: %v", err)
+	}
+	if c := server.queueHealth(); c.Status != HealthDegraded || c.Details["deadLetters"] != 1 {
+		t.Errorf("Expected dead letters to degrade the queue, got %+v", c)
+	}
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ebaymanager-bot/internal/store"
+)
+
+// Notification outcomes recorded in history
+const (
+	OutcomeReceived  = "received"   // stored, not yet processed
+	OutcomeQueued    = "queued"     // queued for Discord; see the per-channel deliveries
+	OutcomeNoChannel = "no channel" // no routing rule or default channel
+	OutcomeFailed    = "failed"     // could not be queued; eBay will retry
+	OutcomeRejected  = "rejected"   // signature verification failed
+	OutcomeInvalid   = "invalid"    // could not be decoded
+	OutcomeDuplicate = "duplicate"  // repeat delivery, not posted again
+	OutcomePurged    = "purged"     // account deletion; payload not kept
+	OutcomeDryRun    = "dry run"    // simulated account deletion; nothing purged
+)
+
+// redactedHeaders are never written to history
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+}
+
+// newHistoryRecord captures a received request for notification history
+func newHistoryRecord(header http.Header, body []byte) *store.Notification {
+	record := &store.Notification{
+		ReceivedAt: time.Now(),
+		Headers:    make(map[string]string, len(header)),
+	}
+	for name, values := range header {
+		if !redactedHeaders[name] {
+			record.Headers[name] = strings.Join(values, ", ")
+		}
+	}
+	if json.Valid(body) {
+		record.Payload = json.RawMessage(body)
+	} else {
+		record.Body = string(body)
+	}
+	return record
+}
+
+// saveHistory stores a received notification with what was done with it
+func (s *Server) saveHistory(record *store.Notification, outcome string, err error) {
+	if s.store == nil {
+		return
+	}
+	record.Outcome = outcome
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if err := s.store.SaveNotification(record); err != nil {
+		log.Printf("⚠️ Failed to store notification: %v", err)
+	}
+}
+
+// setOutcome records the result of processing a stored notification
+func (s *Server) setOutcome(recordID string, queued int, err error) {
+	if s.store == nil || recordID == "" {
+		return
+	}
+
+	outcome, errMsg := OutcomeQueued, ""
+	switch {
+	case err != nil:
+		outcome, errMsg = OutcomeFailed, err.Error()
+	case queued == 0:
+		outcome = OutcomeNoChannel
+	}
+	if err := s.store.SetNotificationOutcome(recordID, outcome, errMsg); err != nil {
+		log.Printf("⚠️ Failed to record notification outcome: %v", err)
+	}
+}
+
+// ReplayNotification runs a stored notification through processing again, bypassing
+// signature checks and duplicate suppression. A simulated notification is replayed as
+// one, so it still stays away from the event sinks and order threads. It returns how
+// many channels it was queued for.
+func (s *Server) ReplayNotification(id string) (int, error) {
+	if s.store == nil {
+		return 0, fmt.Errorf("notification history is not enabled")
+	}
+	record, err := s.store.GetNotification(id)
+	if err != nil {
+		return 0, err
+	}
+	if len(record.Payload) == 0 {
+		return 0, fmt.Errorf("notification %s has no stored payload to replay", id)
+	}
+
+	notification, err := DecodeNotification(record.Payload)
+	if err != nil {
+		return 0, err
+	}
+	if notification.IsAccountDeletion() {
+		return 0, fmt.Errorf("account deletion notifications cannot be replayed")
+	}
+
+	log.Printf("🔁 Replaying notification %s (%s)", id, notification.EventType())
+	record.ReplayedAt = time.Now()
+	if err := s.store.SaveNotification(record); err != nil {
+		log.Printf("⚠️ Failed to mark notification %s as replayed: %v", id, err)
+	}
+
+	source := SourceReplay
+	if record.Headers[simulatedHeader] != "" {
+		source = SourceSimulator
+	}
+	queued, err := s.process(notification, id, source)
+	s.setOutcome(id, queued, err)
+	return queued, err
+}
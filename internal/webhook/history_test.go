@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"ebaymanager-bot/internal/store"
+)
+
+func TestNotificationHistory(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	sender := &fakeSender{}
+	server := NewServer(nil, "sales", "token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(sender, repo, 1))
+
+	post := func(body string) *store.Notification {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/webhook/ebay/notification", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		server.handleNotification(httptest.NewRecorder(), req)
+
+		stored, _ := repo.ListNotifications(1)
+		if len(stored) != 1 {
+			t.Fatalf("Expected the notification to be stored")
+		}
+		return &stored[0]
+	}
+
+	offer := post(`{"metadata":{"topic":"MARKETPLACE_OFFER"},"notification":{"notificationId":"n-1","data":{"eventType":"CREATED","offerId":"o-1"}}}`)
+	if offer.Outcome != OutcomeQueued || offer.Signature != signatureUnverified || offer.EventType != "MARKETPLACE_OFFER.CREATED" {
+		t.Errorf("Unexpected history record: %+v", offer)
+	}
+	if offer.Headers["Content-Type"] != "application/json" || offer.Headers["Authorization"] != "" {
+		t.Errorf("Expected headers to be kept without credentials, got %v", offer.Headers)
+	}
+	if len(offer.Deliveries) != 1 || offer.Deliveries[0].Status != store.DeliveryQueued {
+		t.Errorf("Expected one queued delivery, got %+v", offer.Deliveries)
+	}
+
+	invalid := post(`not json`)
+	if invalid.Outcome != OutcomeInvalid || invalid.Body != "not json" {
+		t.Errorf("Expected invalid body to be kept, got %+v", invalid)
+	}
+
+	// Replaying bypasses duplicate suppression and queues the notification again
+	queued, err := server.ReplayNotification(offer.ID)
+	if err != nil || queued != 1 {
+		t.Fatalf("ReplayNotification = %d, %v", queued, err)
+	}
+	replayed, _ := repo.GetNotification(offer.ID)
+	if replayed.ReplayedAt.IsZero() || replayed.Outcome != OutcomeQueued {
+		t.Errorf("Expected replay to be recorded, got %+v", replayed)
+	}
+	if pending, _ := repo.ListOutbox(store.OutboxPending, 0); len(pending) != 2 {
+		t.Errorf("Expected the replay to be queued, got %d pending messages", len(pending))
+	}
+
+	if _, err := server.ReplayNotification(invalid.ID); err == nil {
+		t.Error("Expected replaying an invalid body to fail")
+	}
+}
+
+func TestRejectedNotificationsAreKept(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "sales", "token", "0")
+	server.SetStore(repo)
+	server.SetSignatureVerifier(NewSignatureVerifier(nil), true)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/ebay/notification", bytes.NewBufferString(`{"metadata":{"topic":"MARKETPLACE_ORDER"}}`))
+	rec := httptest.NewRecorder()
+	server.handleNotification(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected 412, got %d", rec.Code)
+	}
+
+	stored, _ := repo.ListNotifications(0)
+	if len(stored) != 1 || stored[0].Outcome != OutcomeRejected || stored[0].Signature != signatureUnsigned || stored[0].Error == "" {
+		t.Errorf("Expected rejected notification in history, got %+v", stored)
+	}
+}
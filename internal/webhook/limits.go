@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+)
+
+// maxBodyBytes caps request bodies; eBay notifications are a few kilobytes
+const maxBodyBytes = 1 << 20
+
+// limitBody wraps every request body in http.MaxBytesReader
+func limitBody(next http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readBody reads the request body, answering 413 or 400 itself when it can't
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("❌ Rejected request body over %d bytes", tooLarge.Limit)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		log.Printf("❌ Failed to read request body: %v", err)
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
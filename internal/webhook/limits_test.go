@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOversizedBodyIsRejected(t *testing.T) {
+	server := NewServer(nil, "", "token", "0")
+	handler := server.Handler()
+
+	body := bytes.Repeat([]byte("x"), maxBodyBytes+1)
+	req := httptest.NewRequest(http.MethodPost, "/webhook/ebay/notification", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for oversized body, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"time"
+
+	"ebaymanager-bot/internal/metrics"
+	"ebaymanager-bot/internal/store"
+)
+
+var (
+	notificationsReceived = metrics.NewCounterVec("ebay_notifications_received_total",
+		"Notifications received from eBay by topic (simulated ones excluded)", "topic")
+	notificationsRejected = metrics.NewCounterVec("ebay_notification_signature_failures_total",
+		"Notifications rejected by signature verification, by signature state", "signature")
+	notificationDuplicates = metrics.NewCounterVec("ebay_notification_duplicates_total",
+		"Repeated deliveries acknowledged without posting, by topic", "topic")
+	discordSends = metrics.NewCounterVec("discord_messages_sent_total",
+		"Discord message deliveries by result (failure includes attempts that will be retried)", "result")
+	publicKeyLookups = metrics.NewCounterVec("ebay_public_key_lookups_total",
+		"Notification public key lookups that missed the cache, by result (fetched, failed, cached_failure, rate_limited)", "result")
+	publicKeyLatency = metrics.NewHistogramVec("ebay_public_key_fetch_duration_seconds",
+		"Time spent fetching notification public keys from eBay, by result", metrics.DefaultBuckets, "result")
+)
+
+// observeSend records a Discord delivery attempt
+func observeSend(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	discordSends.Inc(result)
+}
+
+// observeKeyFetch records a public key fetched from eBay, or the failure to fetch it
+func observeKeyFetch(start time.Time, err error) {
+	result := "fetched"
+	if err != nil {
+		result = "failed"
+	}
+	publicKeyLatency.Observe(time.Since(start).Seconds(), result)
+	publicKeyLookups.Inc(result)
+}
+
+// exportQueueDepth publishes the size of repo's outbox at scrape time
+func exportQueueDepth(repo store.Repository) {
+	metrics.NewGaugeFunc("discord_queue_depth", "Discord messages in the delivery queue by status", "status", func() map[string]float64 {
+		pending, dead, err := outboxDepth(repo)
+		if err != nil {
+			return nil
+		}
+		return map[string]float64{store.OutboxPending: float64(len(pending)), store.OutboxDead: float64(len(dead))}
+	})
+}
+
+// outboxDepth returns the pending and dead-lettered messages in repo's outbox
+func outboxDepth(repo store.Repository) (pending, dead []store.OutboxMessage, err error) {
+	if pending, err = repo.ListOutbox(store.OutboxPending, 0); err != nil {
+		return nil, nil, err
+	}
+	if dead, err = repo.ListOutbox(store.OutboxDead, 0); err != nil {
+		return nil, nil, err
+	}
+	return pending, dead, nil
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ebaymanager-bot/internal/store"
+)
+
+func TestNotificationMetrics(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "", "verify-token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(&fakeSender{}, repo, 1))
+	handler := server.Handler()
+
+	body, err := BuildSimulatedNotification("listing-ended")
+	if err != nil {
+		t.Fatalf("BuildSimulatedNotification failed: %v", err)
+	}
+	// The same delivery twice: both are counted as received, the repeat as a duplicate
+	for n := 0; n < 2; n++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, notificationPath, bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := rec.Body.String()
+	for _, want := range []string{
+		`ebay_notifications_received_total{topic="ITEM_INVENTORY"}`,
+		`ebay_notification_duplicates_total{topic="ITEM_INVENTORY"}`,
+		`discord_queue_depth{status="pending"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
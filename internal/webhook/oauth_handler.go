@@ -2,9 +2,10 @@ package webhook
 
 import (
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
-	"sync"
+	"net/url"
 	"time"
 
 	"ebaymanager-bot/internal/ebay"
@@ -12,68 +13,81 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-// OAuthCallback represents a pending OAuth authorization
-type OAuthCallback struct {
-	State       string
-	Discord     *discordgo.Session
-	Interaction *discordgo.Interaction
-	CreatedAt   time.Time
-}
+const (
+	oauthStartPath = "/webhook/oauth/start"
 
-var (
-	oauthCallbacks = make(map[string]*OAuthCallback)
-	callbacksMutex sync.RWMutex
-	ebayClient     *ebay.Client // eBay client for token exchange
+	// oauthCookie holds the browser's binding to the authorization links it opened
+	oauthCookie = "ebay_oauth_binding"
 )
 
-// SetupOAuthHandlers adds OAuth callback endpoints to the webhook server
-func (s *Server) SetupOAuthHandlers() {
-	http.HandleFunc("/webhook/oauth/callback", s.handleOAuthCallback)
-	http.HandleFunc("/webhook/oauth/declined", s.handleOAuthDeclined)
+// SetupOAuthHandlers adds OAuth callback endpoints to the webhook server's mux
+func (s *Server) SetupOAuthHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(oauthStartPath, s.handleOAuthStart)
+	mux.HandleFunc("/webhook/oauth/callback", s.handleOAuthCallback)
+	mux.HandleFunc("/webhook/oauth/declined", s.handleOAuthDeclined)
 	log.Println("📍 OAuth callback endpoints registered")
 }
 
-// SetEbayClient sets the eBay client for token exchange
-func SetEbayClient(client *ebay.Client) {
-	ebayClient = client
-	log.Println("✅ eBay client configured for OAuth")
+// BeginOAuth starts an /ebay-authorize request for client's account and scopes and returns
+// the link for the requester to open. The link passes through this server on its way to
+// eBay, binding the flow to the browser that opens it; it can complete the flow once.
+func (s *Server) BeginOAuth(discord *discordgo.Session, interaction *discordgo.Interaction, client *ebay.Client, scopes []string) (string, error) {
+	base := s.PublicBaseURL()
+	if base == "" {
+		return "", fmt.Errorf("WEBHOOK_PUBLIC_URL is not set, so there is no link to open")
+	}
+	state, err := s.oauth.Issue(discord, interaction, client, scopes)
+	if err != nil {
+		return "", err
+	}
+	account := ebay.DefaultAccount
+	if client != nil {
+		account = client.Account()
+	}
+	log.Printf("📝 OAuth authorization %s for account %s started by user %s", stateLabel(state), account, interactionUserID(interaction))
+	return base + oauthStartPath + "?state=" + url.QueryEscape(state), nil
 }
 
-// RegisterOAuthCallback registers a pending OAuth authorization
-func RegisterOAuthCallback(state string, discord *discordgo.Session, interaction *discordgo.Interaction) {
-	callbacksMutex.Lock()
-	defer callbacksMutex.Unlock()
-
-	oauthCallbacks[state] = &OAuthCallback{
-		State:       state,
-		Discord:     discord,
-		Interaction: interaction,
-		CreatedAt:   time.Now(),
+// handleOAuthStart binds the browser opening an authorization link to its state with a
+// cookie, then sends it on to eBay
+func (s *Server) handleOAuthStart(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	auth, binding, err := s.oauth.Open(state, oauthBinding(r))
+	if err == nil && auth.Client == nil {
+		err = fmt.Errorf("no eBay client for the authorization")
+	}
+	if err != nil {
+		log.Printf("⚠️ Refused to open OAuth authorization %s: %v", stateLabel(state), err)
+		sendInvalidState(w)
+		return
 	}
 
-	log.Printf("📝 Registered OAuth callback for state: %s", state)
-
-	// Clean up old callbacks
-	go cleanupOldCallbacks()
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthCookie,
+		Value:    binding,
+		Path:     "/webhook/oauth/",
+		MaxAge:   int(time.Until(auth.Expires).Seconds()) + 1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || s.publicURL != nil && s.publicURL.Scheme == "https",
+		SameSite: http.SameSiteLaxMode, // still sent when eBay redirects back
+	})
+	http.Redirect(w, r, auth.Client.GetUserAuthorizationURL(state, auth.Scopes...), http.StatusFound)
 }
 
-// RegisterOAuthCallback implements the WebhookServer interface for Server
-func (s *Server) RegisterOAuthCallback(state string, discord *discordgo.Session, interaction *discordgo.Interaction) {
-	RegisterOAuthCallback(state, discord, interaction)
+// oauthBinding returns the binding cookie the browser presents, if any
+func oauthBinding(r *http.Request) string {
+	if cookie, err := r.Cookie(oauthCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
 }
 
-// cleanupOldCallbacks removes OAuth callbacks older than 10 minutes
-func cleanupOldCallbacks() {
-	callbacksMutex.Lock()
-	defer callbacksMutex.Unlock()
-
-	cutoff := time.Now().Add(-10 * time.Minute)
-	for state, callback := range oauthCallbacks {
-		if callback.CreatedAt.Before(cutoff) {
-			delete(oauthCallbacks, state)
-			log.Printf("🗑️ Cleaned up expired OAuth callback: %s", state)
-		}
+// stateLabel shortens a state for logs so a full, still valid state is never written out
+func stateLabel(state string) string {
+	if len(state) > 8 {
+		return state[:8] + "…"
 	}
+	return state
 }
 
 // handleOAuthCallback processes OAuth authorization callbacks from eBay
@@ -82,15 +96,23 @@ func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 	state := r.URL.Query().Get("state")
 	errorParam := r.URL.Query().Get("error")
 
-	log.Printf("📨 OAuth callback received - State: %s, Has code: %v, Error: %s", state, code != "", errorParam)
+	log.Printf("📨 OAuth callback received - State: %s, Has code: %v, Error: %s", stateLabel(state), code != "", errorParam)
+
+	// The opening browser's callback uses the state up, so a callback URL can't be replayed
+	auth, err := s.oauth.Consume(state, oauthBinding(r))
+	if err != nil {
+		log.Printf("⚠️ Refused OAuth callback for state %s: %v", stateLabel(state), err)
+		sendInvalidState(w)
+		return
+	}
 
 	if errorParam != "" {
 		errorDesc := r.URL.Query().Get("error_description")
-		sendOAuthError(state, fmt.Sprintf("%s: %s", errorParam, errorDesc))
+		sendOAuthError(auth, fmt.Sprintf("%s: %s", errorParam, errorDesc))
 
 		html := `<!DOCTYPE html>
 <html><head><title>Authorization Failed</title><style>body{font-family:Arial;max-width:600px;margin:50px auto;padding:20px}.error{color:red}</style></head>
-<body><h1 class="error">❌ Authorization Failed</h1><p>` + errorDesc + `</p><p>You can close this window and try again in Discord with <code>/ebay-authorize</code></p></body></html>`
+<body><h1 class="error">❌ Authorization Failed</h1><p>` + template.HTMLEscapeString(errorDesc) + `</p><p>You can close this window and try again in Discord with <code>/ebay-authorize</code></p></body></html>`
 
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(html))
@@ -98,7 +120,7 @@ func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if code == "" {
-		sendOAuthError(state, "No authorization code received")
+		sendOAuthError(auth, "No authorization code received")
 		http.Error(w, "Missing authorization code", http.StatusBadRequest)
 		return
 	}
@@ -112,15 +134,21 @@ func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 
 	// Process token exchange in background
-	go processOAuthToken(state, code)
+	s.goBackground(func() { s.processOAuthToken(auth, code) })
 }
 
 // handleOAuthDeclined handles when user declines authorization
 func (s *Server) handleOAuthDeclined(w http.ResponseWriter, r *http.Request) {
 	state := r.URL.Query().Get("state")
 
-	log.Printf("❌ OAuth declined - State: %s", state)
-	sendOAuthError(state, "Authorization declined by user")
+	log.Printf("❌ OAuth declined - State: %s", stateLabel(state))
+	auth, err := s.oauth.Consume(state, oauthBinding(r))
+	if err != nil {
+		log.Printf("⚠️ Refused OAuth callback for state %s: %v", stateLabel(state), err)
+		sendInvalidState(w)
+		return
+	}
+	sendOAuthError(auth, "Authorization declined by user")
 
 	html := `<!DOCTYPE html>
 <html><head><title>Authorization Declined</title><style>body{font-family:Arial;max-width:600px;margin:50px auto;padding:20px}.error{color:red}</style></head>
@@ -130,81 +158,69 @@ func (s *Server) handleOAuthDeclined(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
-// processOAuthToken exchanges the code for tokens and notifies Discord
-func processOAuthToken(state, code string) {
-	log.Printf("🔄 Processing OAuth token exchange for state: %s", state)
-
-	callbacksMutex.RLock()
-	callback, exists := oauthCallbacks[state]
-	callbacksMutex.RUnlock()
+// sendInvalidState answers a callback whose state was refused
+func sendInvalidState(w http.ResponseWriter) {
+	html := `<!DOCTYPE html>
+<html><head><title>Authorization Link Invalid</title><style>body{font-family:Arial;max-width:600px;margin:50px auto;padding:20px}.error{color:red}</style></head>
+<body><h1 class="error">❌ Authorization Link Invalid</h1><p>This authorization link is invalid, has expired or has already been used.</p><p>Run <code>/ebay-authorize</code> in Discord to get a new one.</p></body></html>`
 
-	if !exists || callback == nil {
-		log.Printf("⚠️ No callback found for state: %s", state)
-		return
-	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(html))
+}
 
-	// Check if eBay client is configured
-	if ebayClient == nil {
+// processOAuthToken exchanges the code for tokens and notifies the requester in Discord
+func (s *Server) processOAuthToken(auth *PendingAuth, code string) {
+	client := auth.Client
+	if client == nil {
 		log.Println("❌ eBay client is nil")
-		callback.Discord.FollowupMessageCreate(callback.Interaction, true, &discordgo.WebhookParams{
+		auth.Discord.FollowupMessageCreate(auth.Interaction, true, &discordgo.WebhookParams{
 			Content: "❌ **Server configuration error**\n\nEbay client not properly configured. Contact administrator.",
+			Flags:   discordgo.MessageFlagsEphemeral,
 		})
 		return
 	}
+	log.Printf("🔄 Processing OAuth token exchange for account %s, user %s", client.Account(), auth.UserID)
 
-	// Exchange code for token using ebayClient
-	_, err := ebayClient.ExchangeCodeForToken(code)
+	// Exchange code for token using the account's client
+	_, err := client.ExchangeCodeForToken(code, auth.Scopes...)
 	if err != nil {
 		log.Printf("❌ Failed to exchange code for token: %v", err)
-		callback.Discord.FollowupMessageCreate(callback.Interaction, true, &discordgo.WebhookParams{
+		auth.Discord.FollowupMessageCreate(auth.Interaction, true, &discordgo.WebhookParams{
 			Content: fmt.Sprintf("❌ **Failed to get access token:** %v\n\nTry `/ebay-authorize` again or use `/ebay-code` for manual entry.", err),
+			Flags:   discordgo.MessageFlagsEphemeral,
 		})
-
-		// Remove callback
-		callbacksMutex.Lock()
-		delete(oauthCallbacks, state)
-		callbacksMutex.Unlock()
 		return
 	}
 
-	// Persist tokens to .env so they survive restarts
-	if err := ebayClient.SaveTokensToEnv(); err != nil {
-		log.Printf("⚠️ Failed to save tokens to .env: %v", err)
-	} else {
-		log.Println("✅ Tokens saved to .env")
+	// Persist tokens so they survive restarts
+	if err := client.SaveTokens(); err != nil {
+		log.Printf("⚠️ Failed to save tokens: %v", err)
+	}
+
+	// Learn which seller signed in, so notifications can be matched to the account
+	connected := "Your eBay account has been connected."
+	if err := client.Identify(); err != nil {
+		log.Printf("⚠️ Failed to identify the seller for account %s: %v", client.Account(), err)
+	} else if s.multiAccount() {
+		connected = fmt.Sprintf("eBay seller **%s** is connected as account **%s**.", client.Username(), client.Account())
+	}
+	if other := s.accountWithSameSeller(client); other != "" {
+		connected += fmt.Sprintf("\n\n⚠️ Account **%s** is signed in as the same eBay seller; its notifications will be attributed to only one of them.", other)
 	}
 
 	// Success! Notify Discord
-	log.Printf("✅ OAuth tokens obtained successfully for state: %s", state)
-	callback.Discord.FollowupMessageCreate(callback.Interaction, true, &discordgo.WebhookParams{
-		Content: "✅ **Authorization Successful!**\n\nYour eBay account has been connected.\nAccess token and refresh token have been saved.\n\n🎉 You can now use all eBay commands!\n\n💡 The bot will automatically refresh your token every 90 minutes.",
+	log.Printf("✅ OAuth tokens obtained successfully for account %s, user %s", client.Account(), auth.UserID)
+	auth.Discord.FollowupMessageCreate(auth.Interaction, true, &discordgo.WebhookParams{
+		Content: "✅ **Authorization Successful!**\n\n" + connected + "\nAccess token and refresh token have been saved.\n\n🎉 You can now use all eBay commands!\n\n💡 The bot will automatically refresh your token every 90 minutes.",
+		Flags:   discordgo.MessageFlagsEphemeral,
 	})
-
-	// Clean up callback
-	callbacksMutex.Lock()
-	delete(oauthCallbacks, state)
-	callbacksMutex.Unlock()
-
-	log.Printf("📨 Notified Discord and cleaned up OAuth callback for state: %s", state)
 }
 
-// sendOAuthError sends an error message to Discord for a pending OAuth flow
-func sendOAuthError(state, errorMsg string) {
-	callbacksMutex.RLock()
-	callback, exists := oauthCallbacks[state]
-	callbacksMutex.RUnlock()
-
-	if !exists || callback == nil {
-		log.Printf("⚠️ No callback found for state: %s", state)
-		return
-	}
-
-	callback.Discord.FollowupMessageCreate(callback.Interaction, true, &discordgo.WebhookParams{
+// sendOAuthError tells the requester in Discord that their authorization failed
+func sendOAuthError(auth *PendingAuth, errorMsg string) {
+	auth.Discord.FollowupMessageCreate(auth.Interaction, true, &discordgo.WebhookParams{
 		Content: fmt.Sprintf("❌ **Authorization failed:** %s\n\n💡 Try again with `/ebay-authorize`", errorMsg),
+		Flags:   discordgo.MessageFlagsEphemeral,
 	})
-
-	// Remove callback
-	callbacksMutex.Lock()
-	delete(oauthCallbacks, state)
-	callbacksMutex.Unlock()
 }
@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// oauthStateTTL is how long an /ebay-authorize link can be completed
+	oauthStateTTL = 10 * time.Minute
+
+	// oauthSweepInterval is how often expired states are removed
+	oauthSweepInterval = time.Minute
+)
+
+// OAuth state errors
+var (
+	ErrUnknownState     = errors.New("unknown or already used authorization state")
+	ErrStateExpired     = errors.New("authorization state expired")
+	ErrStateOpened      = errors.New("authorization link already opened in another browser")
+	ErrRequesterChanged = errors.New("authorization callback did not come from the browser that opened the link")
+)
+
+// PendingAuth is an /ebay-authorize request waiting for eBay to redirect back
+type PendingAuth struct {
+	UserID      string
+	GuildID     string
+	Client      *ebay.Client // the seller account being authorized
+	Scopes      []string     // scopes the authorization URL requests
+	Discord     *discordgo.Session
+	Interaction *discordgo.Interaction
+	Expires     time.Time
+	Binding     string // secret given to the browser that opened the link; empty until then
+}
+
+// OAuthStates issues OAuth state values that are random and usable once. The first
+// browser to open a state's link is bound to it, and only a callback from that browser
+// completes the flow. Expired states are swept in the background.
+type OAuthStates struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*PendingAuth
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewOAuthStates creates a state registry whose states expire after ttl
+func NewOAuthStates(ttl time.Duration) *OAuthStates {
+	return &OAuthStates{ttl: ttl, pending: make(map[string]*PendingAuth)}
+}
+
+// Issue returns a new state for the user and guild that sent interaction, authorizing scopes
+// for client's account
+func (o *OAuthStates) Issue(discord *discordgo.Session, interaction *discordgo.Interaction, client *ebay.Client, scopes []string) (string, error) {
+	userID := interactionUserID(interaction)
+	if userID == "" {
+		return "", fmt.Errorf("interaction has no user")
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[state] = &PendingAuth{
+		UserID:      userID,
+		GuildID:     interaction.GuildID,
+		Client:      client,
+		Scopes:      scopes,
+		Discord:     discord,
+		Interaction: interaction,
+		Expires:     time.Now().Add(o.ttl),
+	}
+	return state, nil
+}
+
+// Open binds state to the browser opening its link and returns the request and the
+// binding the browser must keep. binding is what the browser already holds, if anything;
+// opening the link again from the same browser is allowed, from another one it isn't.
+func (o *OAuthStates) Open(state, binding string) (*PendingAuth, string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	auth, ok := o.pending[state]
+	switch {
+	case !ok:
+		return nil, "", ErrUnknownState
+	case time.Now().After(auth.Expires):
+		return nil, "", ErrStateExpired
+	case auth.Binding != "":
+		if !sameBinding(auth.Binding, binding) {
+			return nil, "", ErrStateOpened
+		}
+		return auth, binding, nil
+	}
+
+	if binding == "" {
+		var err error
+		if binding, err = randomToken(); err != nil {
+			return nil, "", fmt.Errorf("failed to generate binding: %w", err)
+		}
+	}
+	auth.Binding = binding
+	return auth, binding, nil
+}
+
+// Consume removes state and returns its request. It fails if the state is unknown, was
+// already used or has expired, or if binding isn't what the browser that opened the
+// link was given. A refused browser doesn't use the state up, so a replayed callback
+// can't cancel the real one.
+func (o *OAuthStates) Consume(state, binding string) (*PendingAuth, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	auth, ok := o.pending[state]
+	switch {
+	case !ok:
+		return nil, ErrUnknownState
+	case time.Now().After(auth.Expires):
+		delete(o.pending, state)
+		return nil, ErrStateExpired
+	case auth.Binding == "" || !sameBinding(auth.Binding, binding):
+		return nil, ErrRequesterChanged
+	}
+	delete(o.pending, state)
+	return auth, nil
+}
+
+func sameBinding(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// randomToken returns 32 random bytes, URL-safe encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Pending returns how many states are waiting to be completed
+func (o *OAuthStates) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// sweep removes expired states
+func (o *OAuthStates) sweep(now time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for state, auth := range o.pending {
+		if now.After(auth.Expires) {
+			delete(o.pending, state)
+			log.Printf("🗑️ Expired OAuth authorization started by user %s", auth.UserID)
+		}
+	}
+}
+
+// StartSweeper removes expired states every minute until StopSweeper is called
+func (o *OAuthStates) StartSweeper() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.stop != nil {
+		return
+	}
+	o.stop = make(chan struct{})
+	o.done = make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(oauthSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				o.sweep(now)
+			}
+		}
+	}(o.stop, o.done)
+}
+
+// StopSweeper ends the background sweep
+func (o *OAuthStates) StopSweeper() {
+	o.mu.Lock()
+	stop, done := o.stop, o.done
+	o.stop, o.done = nil, nil
+	o.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// interactionUserID returns who sent an interaction, in a guild or a DM
+func interactionUserID(i *discordgo.Interaction) string {
+	switch {
+	case i == nil:
+		return ""
+	case i.Member != nil && i.Member.User != nil:
+		return i.Member.User.ID
+	case i.User != nil:
+		return i.User.ID
+	}
+	return ""
+}
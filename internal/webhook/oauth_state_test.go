@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/config"
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func authorizeInteraction(userID, guildID string) *discordgo.Interaction {
+	return &discordgo.Interaction{GuildID: guildID, Member: &discordgo.Member{User: &discordgo.User{ID: userID}}}
+}
+
+func TestOAuthStates(t *testing.T) {
+	states := NewOAuthStates(time.Minute)
+
+	first, err := states.Issue(nil, authorizeInteraction("user-1", "guild-1"), nil, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	second, _ := states.Issue(nil, authorizeInteraction("user-1", "guild-1"), nil, nil)
+	if first == second || len(first) < 40 {
+		t.Errorf("Expected distinct, unguessable states, got %q and %q", first, second)
+	}
+
+	_, binding, err := states.Open(first, "")
+	if err != nil || binding == "" {
+		t.Fatalf("Open() = %q, %v", binding, err)
+	}
+	auth, err := states.Consume(first, binding)
+	if err != nil || auth.UserID != "user-1" || auth.GuildID != "guild-1" {
+		t.Fatalf("Consume() = %+v, %v", auth, err)
+	}
+	if _, err := states.Consume(first, binding); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("Consume() twice = %v, want ErrUnknownState", err)
+	}
+	if _, err := states.Consume("state_1700000000", binding); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("Consume() of a guessed state = %v, want ErrUnknownState", err)
+	}
+
+	// DMs carry the user outside Member
+	dm, _ := states.Issue(nil, &discordgo.Interaction{User: &discordgo.User{ID: "user-2"}}, nil, nil)
+	states.Open(dm, binding)
+	if auth, err := states.Consume(dm, binding); err != nil || auth.UserID != "user-2" {
+		t.Errorf("Consume() of a DM state = %+v, %v", auth, err)
+	}
+	if _, err := states.Issue(nil, &discordgo.Interaction{}, nil, nil); err == nil {
+		t.Error("Expected an interaction without a user to be refused")
+	}
+}
+
+func TestOAuthStateBoundToBrowser(t *testing.T) {
+	states := NewOAuthStates(time.Minute)
+
+	// A callback for a link nobody opened through the bot is refused
+	state, _ := states.Issue(nil, authorizeInteraction("user-1", "guild-1"), nil, nil)
+	if _, err := states.Consume(state, ""); !errors.Is(err, ErrRequesterChanged) {
+		t.Errorf("Consume() of an unopened state = %v, want ErrRequesterChanged", err)
+	}
+
+	state, _ = states.Issue(nil, authorizeInteraction("user-1", "guild-1"), nil, nil)
+	_, binding, _ := states.Open(state, "")
+	if _, again, err := states.Open(state, binding); err != nil || again != binding {
+		t.Errorf("Open() again in the same browser = %q, %v", again, err)
+	}
+	if _, _, err := states.Open(state, ""); !errors.Is(err, ErrStateOpened) {
+		t.Errorf("Open() in another browser = %v, want ErrStateOpened", err)
+	}
+	if _, err := states.Consume(state, "other-browser"); !errors.Is(err, ErrRequesterChanged) {
+		t.Errorf("Consume() from another browser = %v, want ErrRequesterChanged", err)
+	}
+	if auth, err := states.Consume(state, binding); err != nil || auth.UserID != "user-1" {
+		t.Errorf("Consume() after a refused callback = %+v, %v; the opening browser should still complete", auth, err)
+	}
+	if _, err := states.Consume(state, binding); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("Consume() twice = %v, want ErrUnknownState", err)
+	}
+}
+
+func TestOAuthStateExpiry(t *testing.T) {
+	states := NewOAuthStates(-time.Second)
+	expired, _ := states.Issue(nil, authorizeInteraction("user-1", "guild-1"), nil, nil)
+	if _, _, err := states.Open(expired, ""); !errors.Is(err, ErrStateExpired) {
+		t.Errorf("Open() of an expired state = %v, want ErrStateExpired", err)
+	}
+	if _, err := states.Consume(expired, ""); !errors.Is(err, ErrStateExpired) {
+		t.Errorf("Consume() of an expired state = %v, want ErrStateExpired", err)
+	}
+
+	states.Issue(nil, authorizeInteraction("user-1", "guild-1"), nil, nil)
+	states.sweep(time.Now())
+	if n := states.Pending(); n != 0 {
+		t.Errorf("Pending() after sweeping = %d, want 0", n)
+	}
+
+	states.StartSweeper()
+	states.StopSweeper()
+}
+
+func TestOAuthCallbackRefusesInvalidState(t *testing.T) {
+	server := NewServer(nil, "", "verify-token", "0")
+	server.SetPublicURL("https://bot.example.com" + notificationPath)
+	state := beginOAuth(t, server, nil)
+	_, binding, _ := server.oauth.Open(state, "")
+	if _, err := server.oauth.Consume(state, binding); err != nil {
+		t.Fatalf("Consume() = %v", err)
+	}
+
+	for _, target := range []string{
+		"/webhook/oauth/callback?code=abc&state=state_1700000000",
+		"/webhook/oauth/callback?code=abc&state=" + url.QueryEscape(state),
+		"/webhook/oauth/declined?state=" + url.QueryEscape(state),
+		"/webhook/oauth/start?state=" + url.QueryEscape(state),
+	} {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want %d", target, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestOAuthCallbackNeedsTheOpeningBrowser(t *testing.T) {
+	server := NewServer(nil, "", "verify-token", "0")
+	server.SetPublicURL("https://bot.example.com" + notificationPath)
+	client := ebay.NewClient(config.EbayConfig{AppID: "app", Environment: "SANDBOX"})
+	state := beginOAuth(t, server, client)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhook/oauth/start?state="+url.QueryEscape(state), nil))
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || location == nil || location.Query().Get("state") != state {
+		t.Fatalf("Opening the link = %d to %q, want a redirect to eBay", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthCookie || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("Expected a secure, HTTP-only binding cookie, got %+v", cookies)
+	}
+
+	// eBay's redirect carrying the state, replayed from a browser without the cookie
+	req := httptest.NewRequest(http.MethodGet, "/webhook/oauth/callback?code=abc&state="+url.QueryEscape(state), nil)
+	req.AddCookie(&http.Cookie{Name: oauthCookie, Value: "forged"})
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Callback from another browser: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if n := server.oauth.Pending(); n != 1 {
+		t.Errorf("Expected the refused callback to leave the state for the opening browser, %d pending", n)
+	}
+}
+
+// beginOAuth starts an authorization for user-1 and returns its state
+func beginOAuth(t *testing.T, server *Server, client *ebay.Client) string {
+	t.Helper()
+	link, err := server.BeginOAuth(nil, authorizeInteraction("user-1", "guild-1"), client, nil)
+	if err != nil {
+		t.Fatalf("BeginOAuth failed: %v", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Host != "bot.example.com" || u.Path != oauthStartPath {
+		t.Fatalf("BeginOAuth() = %q, want a link to %s", link, oauthStartPath)
+	}
+	return u.Query().Get("state")
+}
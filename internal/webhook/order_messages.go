@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// orderThreadArchiveMinutes is how long an order thread stays open without activity (7 days)
+const orderThreadArchiveMinutes = 10080
+
+// trackOrder records an order event in the order's timeline and adds the timeline to
+// the embed. It reports whether the notification should update the order's live message.
+func (s *Server) trackOrder(notification *EbayNotification, embed *discordgo.MessageEmbed) bool {
+	order := notification.Order
+	if s.dispatcher == nil || s.store == nil || order == nil || order.OrderID == "" {
+		return false
+	}
+
+	status := notification.Event
+	if status == "" {
+		status = "UPDATED"
+	}
+	timeline, err := s.store.AppendOrderStatus(order.OrderID, status, time.Now())
+	if err != nil {
+		log.Printf("⚠️ Failed to record status of order %s, posting a separate message: %v", order.OrderID, err)
+		return false
+	}
+
+	addTimeline(embed, timeline)
+	return true
+}
+
+// addTimeline lists every status the order has been through
+func addTimeline(embed *discordgo.MessageEmbed, timeline []store.OrderStatus) {
+	var b strings.Builder
+	for _, step := range timeline {
+		fmt.Fprintf(&b, "• %s <t:%d:R>\n", statusLabel(step.Status), step.At.Unix())
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  "🕒 Timeline",
+		Value: truncateField(b.String()),
+	})
+}
+
+// statusLabel turns an event such as PAID into "Paid"
+func statusLabel(status string) string {
+	if status == "" {
+		return ""
+	}
+	return status[:1] + strings.ToLower(status[1:])
+}
+
+// orderThreadNote is the short update posted in an order's thread for the team
+func orderThreadNote(event string, order *OrderEvent) string {
+	switch event {
+	case OrderPlaced:
+		return "🎉 Order placed"
+	case OrderPaid:
+		if order.TotalPrice.Value != "" {
+			return "💵 Payment received: " + order.TotalPrice.String()
+		}
+		return "💵 Payment received"
+	case OrderShipped, OrderFulfilled:
+		return "📦 Marked as shipped"
+	case OrderDelivered:
+		return "📬 Delivered to the buyer"
+	case OrderRefunded:
+		return "💸 Refund issued"
+	case OrderCancelled:
+		return "🚫 Order cancelled"
+	default:
+		return "📋 Order updated"
+	}
+}
+
+// deliverOrderUpdate edits the order's live message in the channel, or posts it (and
+// starts a thread under it) the first time. Discord doesn't notify anyone of an edit, so
+// the routed roles are mentioned again in the thread note; without a thread an edit is
+// silent. The thread note is best effort.
+func (d *Dispatcher) deliverOrderUpdate(msg *store.OutboxMessage, send *discordgo.MessageSend) error {
+	existing, err := d.store.GetOrderMessage(msg.OrderID, msg.ChannelID)
+	switch {
+	case err == nil:
+		_, err := d.sender.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:              existing.MessageID,
+			Channel:         msg.ChannelID,
+			Content:         &send.Content,
+			Embeds:          send.Embeds,
+			Components:      send.Components,
+			AllowedMentions: send.AllowedMentions,
+		}, discordgo.WithRetryOnRatelimit(false))
+		if err == nil {
+			d.postThreadNote(existing, msg.ThreadNote, msg.MentionRoles)
+			return nil
+		}
+		if !isUnknownMessage(err) {
+			return err
+		}
+		log.Printf("⚠️ Message for order %s was deleted, posting a new one", msg.OrderID)
+	case err != store.ErrNotFound:
+		return err
+	}
+
+	sent, err := d.sender.ChannelMessageSendComplex(msg.ChannelID, send, discordgo.WithRetryOnRatelimit(false))
+	if err != nil {
+		return err
+	}
+
+	orderMsg := &store.OrderMessage{ChannelID: msg.ChannelID, MessageID: sent.ID}
+	if thread, err := d.sender.MessageThreadStart(msg.ChannelID, sent.ID, "Order "+msg.OrderID, orderThreadArchiveMinutes); err != nil {
+		log.Printf("⚠️ Failed to start thread for order %s: %v", msg.OrderID, err)
+	} else {
+		orderMsg.ThreadID = thread.ID
+	}
+	if err := d.store.SaveOrderMessage(msg.OrderID, orderMsg); err != nil {
+		log.Printf("⚠️ Failed to remember message for order %s: %v", msg.OrderID, err)
+	}
+
+	// The new message already mentions the roles
+	d.postThreadNote(orderMsg, msg.ThreadNote, nil)
+	return nil
+}
+
+// postThreadNote replies in the order's thread, if it has one, mentioning roleIDs
+func (d *Dispatcher) postThreadNote(orderMsg *store.OrderMessage, note string, roleIDs []string) {
+	if orderMsg.ThreadID == "" || note == "" {
+		return
+	}
+	send := &discordgo.MessageSend{Content: note}
+	if len(roleIDs) > 0 {
+		send.Content = roleMentions(roleIDs) + " " + note
+		send.AllowedMentions = &discordgo.MessageAllowedMentions{Roles: roleIDs}
+	}
+	if _, err := d.sender.ChannelMessageSendComplex(orderMsg.ThreadID, send); err != nil {
+		log.Printf("⚠️ Failed to post order update in thread %s: %v", orderMsg.ThreadID, err)
+	}
+}
+
+// isUnknownMessage reports whether Discord rejected an edit because the message no longer exists
+func isUnknownMessage(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMessage
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestOrderUpdatesEditOneMessage(t *testing.T) {
+	sender := &fakeSender{}
+	d, repo := newTestDispatcher(t, sender)
+
+	update := func(event string) {
+		t.Helper()
+		out := Outgoing{
+			ChannelID:  "sales",
+			EventType:  TopicOrder + "." + event,
+			RoleIDs:    []string{"role-1"},
+			Embed:      &discordgo.MessageEmbed{Title: event},
+			OrderID:    "12-345",
+			ThreadNote: orderThreadNote(event, &OrderEvent{}),
+		}
+		if err := d.Enqueue(out); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		due := d.due()
+		if len(due) != 1 {
+			t.Fatalf("Expected one message due, got %d", len(due))
+		}
+		d.deliver(due[0])
+	}
+
+	update(OrderPlaced)
+	update(OrderPaid)
+	update(OrderShipped)
+
+	if len(sender.sent) != 1 || len(sender.threads) != 1 {
+		t.Fatalf("Expected one message with one thread, got %d messages and %d threads", len(sender.sent), len(sender.threads))
+	}
+	if len(sender.edits) != 2 || sender.edits[0] != "msg-1" {
+		t.Errorf("Expected later events to edit msg-1, got edits %v", sender.edits)
+	}
+	// Edits don't notify, so the routed roles are mentioned again in the thread
+	if len(sender.notes) != 3 || sender.notes[0] != "thread-msg-1: 🎉 Order placed" || sender.notes[2] != "thread-msg-1: <@&role-1> 📦 Marked as shipped" {
+		t.Errorf("Unexpected thread notes: %v", sender.notes)
+	}
+	if edit := sender.lastEdit; edit.Content == nil || *edit.Content != "<@&role-1>" || edit.AllowedMentions == nil {
+		t.Errorf("Expected edits to keep the role mentions, got %+v", edit)
+	}
+
+	// A deleted message is replaced by a new one
+	sender.editErr = &discordgo.RESTError{
+		Response: &http.Response{StatusCode: http.StatusNotFound},
+		Message:  &discordgo.APIErrorMessage{Code: discordgo.ErrCodeUnknownMessage},
+	}
+	update(OrderDelivered)
+	msg, err := repo.GetOrderMessage("12-345", "sales")
+	if err != nil || msg.MessageID != "msg-2" {
+		t.Errorf("Expected the order to move to msg-2, got %+v (%v)", msg, err)
+	}
+}
+
+func TestOrderUpdatesAreDeliveredInOrder(t *testing.T) {
+	d, _ := newTestDispatcher(t, &fakeSender{})
+
+	for _, event := range []string{OrderPlaced, OrderPaid} {
+		d.Enqueue(Outgoing{ChannelID: "sales", Embed: &discordgo.MessageEmbed{Title: event}, OrderID: "12-345"})
+	}
+	d.Enqueue(Outgoing{ChannelID: "sales", Embed: &discordgo.MessageEmbed{Title: "other"}, OrderID: "67-890"})
+
+	due := d.due()
+	if len(due) != 2 {
+		t.Fatalf("Expected one update per order to be due, got %d", len(due))
+	}
+	if d.due() != nil {
+		t.Error("Expected the next update to wait until the first is delivered")
+	}
+}
+
+func TestTrackOrderAddsTimeline(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "default", "token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(&fakeSender{}, repo, 1))
+
+	var embed discordgo.MessageEmbed
+	for _, event := range []string{OrderPlaced, OrderPaid} {
+		embed = discordgo.MessageEmbed{}
+		n := &EbayNotification{Topic: TopicOrder, Event: event, Order: &OrderEvent{OrderID: "12-345"}}
+		if !server.trackOrder(n, &embed) {
+			t.Fatal("Expected order to be tracked")
+		}
+	}
+
+	if len(embed.Fields) != 1 || embed.Fields[0].Name != "🕒 Timeline" {
+		t.Fatalf("Expected a timeline field, got %+v", embed.Fields)
+	}
+	if v := embed.Fields[0].Value; !contains(v, "• Placed") || !contains(v, "• Paid") {
+		t.Errorf("Timeline should list Placed and Paid, got %q", v)
+	}
+}
@@ -0,0 +1,462 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxDeliveryAttempts is how many times a message is tried before it is dead-lettered
+	maxDeliveryAttempts = 8
+
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 15 * time.Minute
+
+	// outboxScanInterval is how often the queue is checked for messages due a retry
+	outboxScanInterval = 2 * time.Second
+)
+
+// MessageSender is the subset of the Discord session the dispatcher needs
+type MessageSender interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	MessageThreadStart(channelID, messageID string, name string, archiveDuration int, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+}
+
+// Outgoing is a Discord message to deliver through the queue
+type Outgoing struct {
+	ChannelID  string
+	EventType  string
+	RoleIDs    []string // roles to mention above the embed
+	Embed      *discordgo.MessageEmbed
+	Components []discordgo.MessageComponent
+
+	// OrderID keeps one live message per order and channel: later events edit it
+	// and post ThreadNote in the order's thread instead of sending a new message
+	OrderID    string
+	ThreadNote string
+
+	// NotificationID is the stored notification whose delivery status is kept up to date
+	NotificationID string
+}
+
+// Dispatcher delivers queued Discord messages with a pool of workers, retrying with
+// backoff and moving messages that keep failing to the dead-letter list
+type Dispatcher struct {
+	sender  MessageSender
+	store   store.Repository
+	workers int
+
+	mu       sync.Mutex
+	inflight map[string]bool
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewDispatcher creates a dispatcher that delivers through sender using workers goroutines
+func NewDispatcher(sender MessageSender, repo store.Repository, workers int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	exportQueueDepth(repo)
+	return &Dispatcher{
+		sender:   sender,
+		store:    repo,
+		workers:  workers,
+		inflight: make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Enqueue writes a message to the persistent queue. Once it returns nil the
+// message will be delivered even if the process restarts.
+func (d *Dispatcher) Enqueue(out Outgoing) error {
+	data, err := json.Marshal(out.Embed)
+	if err != nil {
+		return fmt.Errorf("failed to encode embed: %w", err)
+	}
+
+	msg := &store.OutboxMessage{
+		ChannelID:      out.ChannelID,
+		EventType:      out.EventType,
+		MentionRoles:   out.RoleIDs,
+		Embed:          data,
+		OrderID:        out.OrderID,
+		ThreadNote:     out.ThreadNote,
+		NotificationID: out.NotificationID,
+	}
+	if len(out.Components) > 0 {
+		if msg.Components, err = json.Marshal(out.Components); err != nil {
+			return fmt.Errorf("failed to encode components: %w", err)
+		}
+	}
+	if err := d.store.EnqueueOutbox(msg); err != nil {
+		return fmt.Errorf("failed to queue message: %w", err)
+	}
+	d.updateHistory(msg, store.DeliveryQueued, "")
+
+	d.signal()
+	return nil
+}
+
+// signal wakes the scheduler without blocking
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the scheduler and worker pool until Stop is called
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return
+	}
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+
+	log.Printf("📤 Discord delivery queue started (%d workers)", d.workers)
+	go d.run(d.stop, d.done)
+}
+
+// Stop ends delivery and waits for in-flight messages to finish
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	stop, done := d.stop, d.done
+	d.stop, d.done = nil, nil
+	d.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Drain keeps delivering until no message is due or in flight, or ctx ends.
+// Messages waiting for a later retry stay queued for the next start.
+func (d *Dispatcher) Drain(ctx context.Context) error {
+	for {
+		d.signal()
+		if d.idle() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("delivery queue not drained: %w", ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// idle reports whether nothing is being delivered and nothing is due
+func (d *Dispatcher) idle() bool {
+	pending, err := d.store.ListOutbox(store.OutboxPending, 0)
+	if err != nil {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.inflight) > 0 {
+		return false
+	}
+	now := time.Now()
+	for _, msg := range pending {
+		if !msg.NextAttemptAt.After(now) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Dispatcher) run(stop, done chan struct{}) {
+	defer close(done)
+
+	jobs := make(chan store.OutboxMessage)
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range jobs {
+				d.deliver(msg)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(jobs)
+
+	for {
+		for _, msg := range d.due() {
+			select {
+			case jobs <- msg:
+			case <-stop:
+				d.release(msg.ID)
+				return
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-d.wake:
+		case <-time.After(outboxScanInterval):
+		}
+	}
+}
+
+// due returns pending messages whose next attempt has arrived and marks them in flight.
+// Updates to the same order message are released one at a time, oldest first.
+func (d *Dispatcher) due() []store.OutboxMessage {
+	pending, err := d.store.ListOutbox(store.OutboxPending, 0)
+	if err != nil {
+		log.Printf("⚠️ Failed to read delivery queue: %v", err)
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	var ready []store.OutboxMessage
+	busyOrders := make(map[string]bool)
+	for _, msg := range pending {
+		orderKey := ""
+		if msg.OrderID != "" {
+			orderKey = msg.OrderID + "/" + msg.ChannelID
+			if busyOrders[orderKey] {
+				continue
+			}
+			busyOrders[orderKey] = true
+		}
+		if d.inflight[msg.ID] || msg.NextAttemptAt.After(now) {
+			continue
+		}
+		d.inflight[msg.ID] = true
+		ready = append(ready, msg)
+	}
+	return ready
+}
+
+func (d *Dispatcher) release(id string) {
+	d.mu.Lock()
+	delete(d.inflight, id)
+	d.mu.Unlock()
+}
+
+// deliver sends one message and records the outcome
+func (d *Dispatcher) deliver(msg store.OutboxMessage) {
+	defer d.release(msg.ID)
+
+	var embed discordgo.MessageEmbed
+	if err := json.Unmarshal(msg.Embed, &embed); err != nil {
+		d.deadLetter(&msg, fmt.Errorf("stored embed is invalid: %w", err))
+		return
+	}
+	components, err := decodeComponents(msg.Components)
+	if err != nil {
+		d.deadLetter(&msg, fmt.Errorf("stored components are invalid: %w", err))
+		return
+	}
+
+	send := messageWithMentions(&embed, msg.MentionRoles)
+	send.Components = components
+
+	// Rate limits are handled here rather than by sleeping inside discordgo,
+	// so one throttled channel doesn't hold a worker
+	if msg.OrderID != "" {
+		err = d.deliverOrderUpdate(&msg, send)
+	} else {
+		_, err = d.sender.ChannelMessageSendComplex(msg.ChannelID, send, discordgo.WithRetryOnRatelimit(false))
+	}
+	observeSend(err)
+	if err == nil {
+		if err := d.store.DeleteOutbox(msg.ID); err != nil && err != store.ErrNotFound {
+			log.Printf("⚠️ Failed to remove delivered message %s from queue: %v", msg.ID, err)
+		}
+		d.updateHistory(&msg, store.DeliverySent, "")
+		log.Printf("✅ Notification sent to Discord channel %s", msg.ChannelID)
+		return
+	}
+
+	// A rate limit says nothing about whether the message can be delivered, so it is
+	// retried when Discord allows without using up an attempt
+	if delay, limited := rateLimitDelay(err); limited {
+		msg.LastError = err.Error()
+		d.reschedule(&msg, delay)
+		log.Printf("⏳ Discord rate limited delivery to channel %s, retrying in %s", msg.ChannelID, delay)
+		return
+	}
+
+	msg.Attempts++
+	msg.LastError = err.Error()
+
+	delay, permanent := retryDelay(err, msg.Attempts)
+	if permanent || msg.Attempts >= maxDeliveryAttempts {
+		d.deadLetter(&msg, err)
+		return
+	}
+
+	d.reschedule(&msg, delay)
+	log.Printf("⚠️ Discord delivery failed (attempt %d/%d, retrying in %s): %v", msg.Attempts, maxDeliveryAttempts, delay, err)
+}
+
+// reschedule keeps a message that failed to send queued for another try after delay
+func (d *Dispatcher) reschedule(msg *store.OutboxMessage, delay time.Duration) {
+	msg.NextAttemptAt = time.Now().Add(delay)
+	if err := d.store.UpdateOutbox(msg); err != nil {
+		log.Printf("⚠️ Failed to update queued message %s: %v", msg.ID, err)
+	}
+	d.updateHistory(msg, store.DeliveryRetrying, msg.LastError)
+}
+
+// messageWithMentions builds a message that pings roleIDs (and nobody else) above the embed
+func messageWithMentions(embed *discordgo.MessageEmbed, roleIDs []string) *discordgo.MessageSend {
+	return &discordgo.MessageSend{
+		Content:         roleMentions(roleIDs),
+		Embeds:          []*discordgo.MessageEmbed{embed},
+		AllowedMentions: &discordgo.MessageAllowedMentions{Roles: roleIDs},
+	}
+}
+
+// roleMentions renders the mentions of roleIDs, separated by spaces
+func roleMentions(roleIDs []string) string {
+	mentions := make([]string, len(roleIDs))
+	for i, id := range roleIDs {
+		mentions[i] = "<@&" + id + ">"
+	}
+	return strings.Join(mentions, " ")
+}
+
+// decodeComponents restores message components saved with a queued message
+func decodeComponents(raw json.RawMessage) ([]discordgo.MessageComponent, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	components := make([]discordgo.MessageComponent, 0, len(items))
+	for _, item := range items {
+		c, err := discordgo.MessageComponentFromJSON(item)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+// deadLetter parks a message that cannot be delivered until it is replayed
+func (d *Dispatcher) deadLetter(msg *store.OutboxMessage, err error) {
+	msg.Status = store.OutboxDead
+	msg.LastError = err.Error()
+	if saveErr := d.store.UpdateOutbox(msg); saveErr != nil {
+		log.Printf("⚠️ Failed to dead-letter message %s: %v", msg.ID, saveErr)
+	}
+	d.updateHistory(msg, store.DeliveryDead, msg.LastError)
+	log.Printf("☠️ Discord message %s moved to dead letters after %d attempts: %v", msg.ID, msg.Attempts, err)
+}
+
+// updateHistory updates the delivery status shown in notification history
+func (d *Dispatcher) updateHistory(msg *store.OutboxMessage, status, errMsg string) {
+	if msg.NotificationID == "" {
+		return
+	}
+	err := d.store.RecordDelivery(msg.NotificationID, store.Delivery{ChannelID: msg.ChannelID, Status: status, Error: errMsg})
+	if err != nil && err != store.ErrNotFound {
+		log.Printf("⚠️ Failed to record delivery of notification %s: %v", msg.NotificationID, err)
+	}
+}
+
+// DeadLetters returns messages that could not be delivered, oldest first
+func (d *Dispatcher) DeadLetters(limit int) ([]store.OutboxMessage, error) {
+	return d.store.ListOutbox(store.OutboxDead, limit)
+}
+
+// Replay moves a dead letter back into the queue for immediate delivery
+func (d *Dispatcher) Replay(id string) error {
+	msg, err := d.store.GetOutbox(id)
+	if err != nil {
+		return err
+	}
+	if msg.Status != store.OutboxDead {
+		return fmt.Errorf("message %s is not a dead letter", id)
+	}
+
+	msg.Status = store.OutboxPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = time.Now()
+	if err := d.store.UpdateOutbox(msg); err != nil {
+		return err
+	}
+	d.updateHistory(msg, store.DeliveryQueued, "")
+
+	d.signal()
+	return nil
+}
+
+// retryDelay decides how long to wait before retrying err. Rate limits use Discord's
+// retry-after; client errors such as an unknown channel or missing permission are permanent.
+func retryDelay(err error, attempts int) (time.Duration, bool) {
+	if delay, limited := rateLimitDelay(err); limited {
+		return delay, false
+	}
+
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		if code := restErr.Response.StatusCode; code >= 400 && code < 500 {
+			return 0, true
+		}
+	}
+
+	delay := retryBaseDelay << (attempts - 1)
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return delay, false
+}
+
+// rateLimitDelay reports whether err is a Discord rate limit and how long it asks to wait.
+// Without a usable Retry-After the wait is retryBaseDelay.
+func rateLimitDelay(err error) (time.Duration, bool) {
+	var rateLimited *discordgo.RateLimitError
+	if errors.As(err, &rateLimited) && rateLimited.TooManyRequests != nil {
+		return maxDuration(rateLimited.RetryAfter, time.Second), true
+	}
+
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusTooManyRequests {
+		after, err := strconv.ParseFloat(restErr.Response.Header.Get("Retry-After"), 64)
+		if err != nil {
+			return retryBaseDelay, true
+		}
+		return maxDuration(time.Duration(after*float64(time.Second)), time.Second), true
+	}
+	return 0, false
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
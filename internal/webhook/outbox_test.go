@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/store"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type fakeSender struct {
+	mu    sync.Mutex
+	errs  []error // returned in order, then success
+	sent  []string
+	last  *discordgo.MessageSend
+	calls int
+
+	edits     []string // IDs of edited messages
+	lastEdit  *discordgo.MessageEdit
+	editErr   error
+	threads   []string // IDs of messages threads were started on
+	notes     []string // plain-text messages, such as thread notes
+	nextMsgID int
+}
+
+func (f *fakeSender) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	if len(data.Embeds) == 0 {
+		f.notes = append(f.notes, channelID+": "+data.Content)
+		return &discordgo.Message{}, nil
+	}
+	f.sent = append(f.sent, data.Embeds[0].Title)
+	f.last = data
+	f.nextMsgID++
+	return &discordgo.Message{ID: fmt.Sprintf("msg-%d", f.nextMsgID), ChannelID: channelID}, nil
+}
+
+func (f *fakeSender) ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.editErr != nil {
+		return nil, f.editErr
+	}
+	f.edits = append(f.edits, m.ID)
+	f.lastEdit = m
+	return &discordgo.Message{ID: m.ID}, nil
+}
+
+func (f *fakeSender) MessageThreadStart(channelID, messageID string, name string, archiveDuration int, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.threads = append(f.threads, messageID)
+	return &discordgo.Channel{ID: "thread-" + messageID}, nil
+}
+
+func newTestDispatcher(t *testing.T, sender *fakeSender) (*Dispatcher, *store.FileStore) {
+	t.Helper()
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	return NewDispatcher(sender, repo, 1), repo
+}
+
+func TestDispatcherRetriesThenDelivers(t *testing.T) {
+	sender := &fakeSender{errs: []error{errors.New("discord unavailable")}}
+	d, repo := newTestDispatcher(t, sender)
+
+	if err := d.Enqueue(Outgoing{ChannelID: "chan-1", EventType: "MARKETPLACE_ORDER.PLACED", Embed: &discordgo.MessageEmbed{Title: "New order"}}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	msg := d.due()[0]
+	d.deliver(msg)
+
+	queued, _ := repo.GetOutbox(msg.ID)
+	if queued == nil || queued.Attempts != 1 || queued.Status != store.OutboxPending {
+		t.Fatalf("Expected message to stay pending after one failure, got %+v", queued)
+	}
+	if !queued.NextAttemptAt.After(time.Now()) {
+		t.Error("Expected the retry to be scheduled in the future")
+	}
+	if len(d.due()) != 0 {
+		t.Error("Message should not be due before its retry time")
+	}
+
+	d.deliver(*queued)
+	if len(sender.sent) != 1 {
+		t.Fatalf("Expected message to be delivered on retry, got %d sends", len(sender.sent))
+	}
+	if _, err := repo.GetOutbox(msg.ID); err != store.ErrNotFound {
+		t.Error("Delivered message should be removed from the queue")
+	}
+}
+
+func TestDispatcherKeepsComponents(t *testing.T) {
+	sender := &fakeSender{}
+	d, _ := newTestDispatcher(t, sender)
+
+	buttons := []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: "Accept", CustomID: "offer:accept:123"},
+	}}}
+	if err := d.Enqueue(Outgoing{ChannelID: "chan-1", EventType: "MARKETPLACE_OFFER.CREATED", Embed: &discordgo.MessageEmbed{Title: "New offer"}, Components: buttons}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	d.deliver(d.due()[0])
+
+	if sender.last == nil || len(sender.last.Components) != 1 {
+		t.Fatalf("Expected one row of components to be sent, got %+v", sender.last)
+	}
+	row, ok := sender.last.Components[0].(*discordgo.ActionsRow)
+	if !ok || len(row.Components) != 1 || row.Components[0].(*discordgo.Button).CustomID != "offer:accept:123" {
+		t.Errorf("Unexpected components after queueing: %+v", sender.last.Components)
+	}
+}
+
+func TestDispatcherDeadLettersAndReplays(t *testing.T) {
+	badChannel := &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	sender := &fakeSender{errs: []error{badChannel}}
+	d, _ := newTestDispatcher(t, sender)
+
+	d.Enqueue(Outgoing{ChannelID: "missing-channel", EventType: "MARKETPLACE_OFFER.CREATED", Embed: &discordgo.MessageEmbed{Title: "New offer"}})
+	d.deliver(d.due()[0])
+
+	dead, _ := d.DeadLetters(0)
+	if len(dead) != 1 {
+		t.Fatalf("Expected permanent failure to be dead-lettered, got %d dead letters", len(dead))
+	}
+
+	if err := d.Replay(dead[0].ID); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	due := d.due()
+	if len(due) != 1 || due[0].Attempts != 0 {
+		t.Fatalf("Expected replayed message to be due with reset attempts, got %+v", due)
+	}
+	d.deliver(due[0])
+	if len(sender.sent) != 1 {
+		t.Error("Expected replayed message to be delivered")
+	}
+}
+
+func TestDispatcherRateLimitsUseNoAttempts(t *testing.T) {
+	rateLimited := &discordgo.RESTError{Response: &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"3"}},
+	}}
+	errs := make([]error, maxDeliveryAttempts+1)
+	for i := range errs {
+		errs[i] = rateLimited
+	}
+	sender := &fakeSender{errs: errs}
+	d, repo := newTestDispatcher(t, sender)
+	d.Enqueue(Outgoing{ChannelID: "chan-1", EventType: "MARKETPLACE_ORDER.PLACED", Embed: &discordgo.MessageEmbed{Title: "New order"}})
+
+	msg := d.due()[0]
+	for i := 0; i < len(errs); i++ {
+		before := time.Now()
+		d.deliver(msg)
+		queued, err := repo.GetOutbox(msg.ID)
+		if err != nil || queued.Status != store.OutboxPending || queued.Attempts != 0 {
+			t.Fatalf("Rate limited message should stay pending without using attempts, got %+v (%v)", queued, err)
+		}
+		if queued.NextAttemptAt.Before(before.Add(3 * time.Second)) {
+			t.Errorf("Retry scheduled at %s, before Retry-After", queued.NextAttemptAt)
+		}
+		msg = *queued
+	}
+
+	d.deliver(msg)
+	if len(sender.sent) != 1 {
+		t.Errorf("Expected delivery once the rate limit lifted, got %d sends", len(sender.sent))
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	rateLimited := &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{
+		TooManyRequests: &discordgo.TooManyRequests{RetryAfter: 7 * time.Second},
+	}}
+
+	tests := []struct {
+		name      string
+		err       error
+		attempts  int
+		want      time.Duration
+		permanent bool
+	}{
+		{"first failure", errors.New("timeout"), 1, retryBaseDelay, false},
+		{"third failure", errors.New("timeout"), 3, 4 * retryBaseDelay, false},
+		{"capped", errors.New("timeout"), 30, retryMaxDelay, false},
+		{"rate limited", rateLimited, 1, 7 * time.Second, false},
+		{"forbidden", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusForbidden}}, 1, 0, true},
+		{"server error", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusBadGateway}}, 2, 2 * retryBaseDelay, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, permanent := retryDelay(tt.err, tt.attempts)
+			if got != tt.want || permanent != tt.permanent {
+				t.Errorf("retryDelay() = %s, %v; want %s, %v", got, permanent, tt.want, tt.permanent)
+			}
+		})
+	}
+}
+
+func TestDrainDeliversQueuedMessages(t *testing.T) {
+	sender := &fakeSender{}
+	d, repo := newTestDispatcher(t, sender)
+	d.Enqueue(Outgoing{ChannelID: "chan-1", EventType: "MARKETPLACE_ORDER.PLACED", Embed: &discordgo.MessageEmbed{Title: "Queued"}})
+
+	d.Start()
+	defer d.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Drain(ctx); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if remaining, _ := repo.ListOutbox("", 0); len(remaining) != 0 {
+		t.Errorf("Expected queue to be empty after drain, got %d messages", len(remaining))
+	}
+}
@@ -0,0 +1,284 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Notification topics the bot understands
+const (
+	TopicOffer           = "MARKETPLACE_OFFER"
+	TopicOrder           = "MARKETPLACE_ORDER"
+	TopicInventory       = "ITEM_INVENTORY"
+	TopicAccountDeletion = "MARKETPLACE_ACCOUNT_DELETION"
+)
+
+// Order events
+const (
+	OrderPlaced    = "PLACED"
+	OrderPaid      = "PAID"
+	OrderShipped   = "SHIPPED"
+	OrderFulfilled = "FULFILLED"
+	OrderDelivered = "DELIVERED"
+	OrderRefunded  = "REFUNDED"
+	OrderCancelled = "CANCELLED"
+)
+
+// Offer events
+const (
+	OfferCreated   = "CREATED"
+	OfferAccepted  = "ACCEPTED"
+	OfferDeclined  = "DECLINED"
+	OfferCountered = "COUNTERED"
+	OfferUpdated   = "UPDATED"
+	OfferExpired   = "EXPIRED"
+)
+
+// Inventory events
+const (
+	InventoryQuantityChanged = "QUANTITY_CHANGED"
+	InventoryListingEnded    = "LISTING_ENDED"
+)
+
+// EbayNotification is a decoded eBay notification. Exactly one of the typed
+// payloads is set for known topics; Data keeps the raw payload otherwise.
+type EbayNotification struct {
+	Topic          string // e.g. MARKETPLACE_ORDER
+	Event          string // e.g. PLACED
+	SchemaVersion  string
+	NotificationID string
+	PublishDate    string
+	SellerID       string // eBay user ID of the seller it is for, when the payload says
+	Account        string // seller account it was attributed to
+
+	Order           *OrderEvent
+	Offer           *OfferEvent
+	Inventory       *InventoryEvent
+	AccountDeletion *AccountDeletionEvent
+	Data            json.RawMessage
+}
+
+// EventType returns the full event name, e.g. MARKETPLACE_ORDER.PLACED
+func (n *EbayNotification) EventType() string {
+	if n.Event == "" {
+		return n.Topic
+	}
+	return n.Topic + "." + n.Event
+}
+
+// Amount is an eBay monetary value
+type Amount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+// Float returns the amount as a number
+func (a Amount) Float() (float64, bool) {
+	f, err := strconv.ParseFloat(a.Value, 64)
+	return f, err == nil
+}
+
+// String formats the amount for display, e.g. $12.50 or 12.50 EUR
+func (a Amount) String() string {
+	f, ok := a.Float()
+	if !ok {
+		return a.Value
+	}
+	if a.Currency == "" || a.Currency == "USD" {
+		return fmt.Sprintf("$%.2f", f)
+	}
+	return fmt.Sprintf("%.2f %s", f, a.Currency)
+}
+
+// OrderEvent is the data of a MARKETPLACE_ORDER notification
+type OrderEvent struct {
+	EventType     string   `json:"eventType"`
+	OrderID       string   `json:"orderId"`
+	BuyerUsername string   `json:"buyerUsername"`
+	TotalPrice    Amount   `json:"totalPrice"`
+	ItemTitle     string   `json:"itemTitle"`
+	SKUs          []string `json:"skus"`
+}
+
+// OfferEvent is the data of a MARKETPLACE_OFFER notification
+type OfferEvent struct {
+	EventType     string `json:"eventType"`
+	OfferID       string `json:"offerId"`
+	BuyerUsername string `json:"buyerUsername"`
+	ItemID        string `json:"itemId"`
+	ItemTitle     string `json:"itemTitle"`
+	SKU           string `json:"sku"`
+	OfferPrice    Amount `json:"offerPrice"`
+	ListPrice     Amount `json:"listPrice"`
+}
+
+// InventoryEvent is the data of an ITEM_INVENTORY notification
+type InventoryEvent struct {
+	EventType string `json:"eventType"`
+	ItemID    string `json:"itemId"`
+	SKU       string `json:"sku"`
+	Title     string `json:"title"`
+	Quantity  int    `json:"quantity"`
+}
+
+// AccountDeletionEvent is the data of a MARKETPLACE_ACCOUNT_DELETION notification
+type AccountDeletionEvent struct {
+	Username  string `json:"username"`
+	UserID    string `json:"userId"`
+	EiasToken string `json:"eiasToken"`
+}
+
+// envelope is the Notification API wire format
+type envelope struct {
+	Metadata struct {
+		Topic         string `json:"topic"`
+		SchemaVersion string `json:"schemaVersion"`
+		Deprecated    bool   `json:"deprecated"`
+	} `json:"metadata"`
+	Notification struct {
+		NotificationID string          `json:"notificationId"`
+		EventDate      string          `json:"eventDate"`
+		PublishDate    string          `json:"publishDate"`
+		Data           json.RawMessage `json:"data"`
+	} `json:"notification"`
+}
+
+// topicDecoder decodes notification.data for one topic
+type topicDecoder struct {
+	majorVersions []string // supported schemaVersion majors
+	decode        func(data json.RawMessage, n *EbayNotification) error
+}
+
+var topicDecoders = map[string]topicDecoder{
+	TopicOrder: {[]string{"1"}, func(data json.RawMessage, n *EbayNotification) error {
+		n.Order = &OrderEvent{}
+		if err := json.Unmarshal(data, n.Order); err != nil {
+			return err
+		}
+		n.Event = firstNonEmpty(n.Event, n.Order.EventType)
+		return nil
+	}},
+	TopicOffer: {[]string{"1"}, func(data json.RawMessage, n *EbayNotification) error {
+		n.Offer = &OfferEvent{}
+		if err := json.Unmarshal(data, n.Offer); err != nil {
+			return err
+		}
+		n.Event = firstNonEmpty(n.Event, n.Offer.EventType)
+		return nil
+	}},
+	TopicInventory: {[]string{"1"}, func(data json.RawMessage, n *EbayNotification) error {
+		n.Inventory = &InventoryEvent{}
+		if err := json.Unmarshal(data, n.Inventory); err != nil {
+			return err
+		}
+		n.Event = firstNonEmpty(n.Event, n.Inventory.EventType)
+		return nil
+	}},
+	TopicAccountDeletion: {[]string{"1"}, func(data json.RawMessage, n *EbayNotification) error {
+		n.AccountDeletion = &AccountDeletionEvent{}
+		return json.Unmarshal(data, n.AccountDeletion)
+	}},
+}
+
+// DecodeNotification parses a Notification API payload, dispatching on metadata.topic
+// and schemaVersion. The topic may carry the event as a suffix (MARKETPLACE_OFFER.CREATED);
+// otherwise it is read from data.eventType. Unknown topics, and schema versions without a
+// decoder, decode with only Data set - except that the user of an account deletion is
+// still read when the data names one, so purges survive a version bump.
+func DecodeNotification(body []byte) (*EbayNotification, error) {
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("invalid notification JSON: %w", err)
+	}
+	if env.Metadata.Topic == "" {
+		return nil, fmt.Errorf("notification has no metadata.topic")
+	}
+
+	topic, event, _ := strings.Cut(strings.ToUpper(env.Metadata.Topic), ".")
+	n := &EbayNotification{
+		Topic:          topic,
+		Event:          event,
+		SchemaVersion:  env.Metadata.SchemaVersion,
+		NotificationID: env.Notification.NotificationID,
+		PublishDate:    firstNonEmpty(env.Notification.PublishDate, env.Notification.EventDate),
+		Data:           env.Notification.Data,
+	}
+	if topic != TopicAccountDeletion {
+		n.SellerID = sellerID(env.Notification.Data)
+	}
+
+	decoder, ok := topicDecoders[topic]
+	if !ok || len(env.Notification.Data) == 0 {
+		return n, nil
+	}
+	if !supportsVersion(decoder.majorVersions, n.SchemaVersion) {
+		// Rejecting it would only make eBay retry a payload that can never succeed
+		log.Printf("⚠️ No decoder for %s schema version %q, handling it untyped", topic, n.SchemaVersion)
+		if topic == TopicAccountDeletion {
+			n.AccountDeletion = readAccountDeletion(env.Notification.Data)
+		}
+		return n, nil
+	}
+	if err := decoder.decode(env.Notification.Data, n); err != nil {
+		return nil, fmt.Errorf("invalid %s data: %w", topic, err)
+	}
+	n.Event = strings.ToUpper(n.Event)
+
+	return n, nil
+}
+
+// readAccountDeletion reads the deleted user from account deletion data of a schema
+// version without a decoder, or returns nil if it names no user
+func readAccountDeletion(data json.RawMessage) *AccountDeletionEvent {
+	var event AccountDeletionEvent
+	if json.Unmarshal(data, &event) != nil || (event.Username == "" && event.UserID == "") {
+		return nil
+	}
+	return &event
+}
+
+// IsAccountDeletion reports whether n is an account deletion, even one whose data
+// could not be read
+func (n *EbayNotification) IsAccountDeletion() bool {
+	return n.Topic == TopicAccountDeletion
+}
+
+// sellerID reads the seller's user ID from notification data. Account deletions are left
+// out: their userId is the deleted user's.
+func sellerID(data json.RawMessage) string {
+	var ids struct {
+		UserID   string `json:"userId"`
+		SellerID string `json:"sellerId"`
+	}
+	if len(data) == 0 || json.Unmarshal(data, &ids) != nil {
+		return ""
+	}
+	return firstNonEmpty(ids.UserID, ids.SellerID)
+}
+
+// supportsVersion reports whether version's major number is listed. A missing
+// version is treated as the oldest supported one.
+func supportsVersion(majors []string, version string) bool {
+	if version == "" {
+		return true
+	}
+	major, _, _ := strings.Cut(version, ".")
+	for _, m := range majors {
+		if m == major {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
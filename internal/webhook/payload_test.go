@@ -0,0 +1,115 @@
+package webhook
+
+import "testing"
+
+func TestDecodeNotification(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantType  string
+		wantErr   bool
+		checkData func(t *testing.T, n *EbayNotification)
+	}{
+		{
+			name:     "order",
+			body:     `{"metadata":{"topic":"MARKETPLACE_ORDER","schemaVersion":"1.0"},"notification":{"notificationId":"n-1","data":{"eventType":"paid","orderId":"12-345","totalPrice":{"value":"99.50","currency":"USD"}}}}`,
+			wantType: "MARKETPLACE_ORDER.PAID",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if n.Order == nil || n.Order.OrderID != "12-345" || n.Order.TotalPrice.String() != "$99.50" {
+					t.Errorf("Unexpected order payload: %+v", n.Order)
+				}
+			},
+		},
+		{
+			name:     "offer with event in topic",
+			body:     `{"metadata":{"topic":"MARKETPLACE_OFFER.CREATED","schemaVersion":"1.1"},"notification":{"data":{"offerId":"o-1","offerPrice":{"value":"40","currency":"EUR"}}}}`,
+			wantType: "MARKETPLACE_OFFER.CREATED",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if n.Offer == nil || n.Offer.OfferPrice.String() != "40.00 EUR" {
+					t.Errorf("Unexpected offer payload: %+v", n.Offer)
+				}
+			},
+		},
+		{
+			name:     "inventory",
+			body:     `{"metadata":{"topic":"ITEM_INVENTORY"},"notification":{"data":{"eventType":"QUANTITY_CHANGED","sku":"CAM-1","quantity":0}}}`,
+			wantType: "ITEM_INVENTORY.QUANTITY_CHANGED",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if n.Inventory == nil || n.Inventory.SKU != "CAM-1" {
+					t.Errorf("Unexpected inventory payload: %+v", n.Inventory)
+				}
+			},
+		},
+		{
+			name:     "account deletion",
+			body:     `{"metadata":{"topic":"MARKETPLACE_ACCOUNT_DELETION","schemaVersion":"1.0"},"notification":{"data":{"username":"gone","userId":"u-1"}}}`,
+			wantType: "MARKETPLACE_ACCOUNT_DELETION",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if n.AccountDeletion == nil || n.AccountDeletion.Username != "gone" {
+					t.Errorf("Unexpected account deletion payload: %+v", n.AccountDeletion)
+				}
+			},
+		},
+		{
+			name:     "unknown topic keeps raw data",
+			body:     `{"metadata":{"topic":"AUTHORIZATION_REVOCATION"},"notification":{"data":{"userId":"u-1"}}}`,
+			wantType: "AUTHORIZATION_REVOCATION",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if len(n.Data) == 0 || n.Order != nil {
+					t.Errorf("Expected only raw data, got %+v", n)
+				}
+			},
+		},
+		{
+			name:     "unsupported schema version keeps raw data",
+			body:     `{"metadata":{"topic":"MARKETPLACE_ORDER.PAID","schemaVersion":"2.0"},"notification":{"data":{"order":{"id":"12-345"}}}}`,
+			wantType: "MARKETPLACE_ORDER.PAID",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if len(n.Data) == 0 || n.Order != nil {
+					t.Errorf("Expected only raw data, got %+v", n)
+				}
+			},
+		},
+		{
+			name:     "account deletion of an unsupported schema version",
+			body:     `{"metadata":{"topic":"MARKETPLACE_ACCOUNT_DELETION","schemaVersion":"2.0"},"notification":{"data":{"username":"gone","userId":"u-1","reason":"new"}}}`,
+			wantType: "MARKETPLACE_ACCOUNT_DELETION",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if n.AccountDeletion == nil || n.AccountDeletion.UserID != "u-1" {
+					t.Errorf("Expected the deleted user to be read, got %+v", n.AccountDeletion)
+				}
+			},
+		},
+		{
+			name:     "account deletion naming no user",
+			body:     `{"metadata":{"topic":"MARKETPLACE_ACCOUNT_DELETION","schemaVersion":"2.0"},"notification":{"data":{"account":{"id":"u-1"}}}}`,
+			wantType: "MARKETPLACE_ACCOUNT_DELETION",
+			checkData: func(t *testing.T, n *EbayNotification) {
+				if n.AccountDeletion != nil || !n.IsAccountDeletion() {
+					t.Errorf("Expected an untyped account deletion, got %+v", n)
+				}
+			},
+		},
+		{name: "missing topic", body: `{"notification":{"data":{}}}`, wantErr: true},
+		{name: "invalid JSON", body: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := DecodeNotification([]byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeNotification failed: %v", err)
+			}
+			if n.EventType() != tt.wantType {
+				t.Errorf("EventType() = %s, want %s", n.EventType(), tt.wantType)
+			}
+			tt.checkData(t, n)
+		})
+	}
+}
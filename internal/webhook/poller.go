@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+)
+
+const (
+	// pollInitializedKey marks that the first poll has recorded a baseline of orders
+	pollInitializedKey = "poller:initialized"
+	// pollOffersInitializedKey marks that a baseline of offers has been recorded, which
+	// waits for the first poll that can read them
+	pollOffersInitializedKey = "poller:offersInitialized"
+	// webhookConfirmedKey records the last time eBay delivered a real notification;
+	// suffixed with an account name, the last one for that account
+	webhookConfirmedKey = "webhook:lastDelivery"
+
+	// webhookConfirmWindow is how recent a webhook delivery must be for the
+	// poller to consider webhooks working
+	webhookConfirmWindow = 7 * 24 * time.Hour
+	// pollMarkRetention is how long seen orders and offers are remembered
+	pollMarkRetention = 120 * 24 * time.Hour
+
+	// pollLookback limits polling to recently modified orders
+	pollLookback   = 14 * 24 * time.Hour
+	pollOrderLimit = 200 // orders per page
+)
+
+// PollSource is the subset of the eBay client the poller needs
+type PollSource interface {
+	GetOrdersModifiedSince(since time.Time, limit, offset int) ([]ebay.Order, int, error)
+	GetOffers() ([]ebay.Offer, error)
+}
+
+// Poller announces new orders, payments and offers by polling the eBay API.
+// It is a fallback for sellers who cannot expose a public webhook endpoint and
+// switches itself off once webhook delivery is confirmed.
+type Poller struct {
+	server   *Server
+	source   PollSource
+	store    store.Repository
+	interval time.Duration
+	account  string // attributes notifications and names the baseline; empty for the default account
+	notify   func(*EbayNotification) error
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPoller creates a poller that posts through the webhook server's Discord pipeline
+func NewPoller(server *Server, source PollSource, repo store.Repository, interval time.Duration) *Poller {
+	return &Poller{
+		server:   server,
+		source:   source,
+		store:    repo,
+		interval: interval,
+		notify:   server.processNotification,
+	}
+}
+
+// SetAccount polls a seller account other than the default one: its notifications are
+// attributed to name and it keeps its own baseline
+func (p *Poller) SetAccount(name string) {
+	p.account = name
+}
+
+// initializedKey names the poll mark recording that the account's baseline was taken
+func (p *Poller) initializedKey(base string) string {
+	if p.account == "" {
+		return base
+	}
+	return base + ":" + p.account
+}
+
+// needsBaseline reports whether the account's baseline under base is still to be taken
+func (p *Poller) needsBaseline(base string) bool {
+	_, err := p.store.GetPollMark(p.initializedKey(base))
+	return err != nil
+}
+
+// saveBaseline records that the account's baseline under base was taken
+func (p *Poller) saveBaseline(base string) error {
+	if err := p.store.SavePollMark(p.initializedKey(base), time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to save poller baseline: %w", err)
+	}
+	return nil
+}
+
+// Start runs the poll loop in the background until Stop is called or webhooks are confirmed
+func (p *Poller) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	log.Printf("🔁 Notification poller started%s (every %s)", forAccount(p.account), p.interval)
+	go p.loop(p.stop, p.done)
+}
+
+// Stop ends the poll loop and waits for an in-progress poll to finish
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop = nil
+	p.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (p *Poller) loop(stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		if p.webhooksConfirmed() {
+			log.Printf("✅ Webhook delivery confirmed%s - notification poller switched off", forAccount(p.account))
+			return
+		}
+
+		if err := p.PollOnce(); err != nil {
+			log.Printf("⚠️ Notification poll failed%s: %v", forAccount(p.account), err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// webhooksConfirmed reports whether eBay has recently delivered a real notification for
+// the poller's account. Another account's deliveries say nothing about its subscriptions.
+func (p *Poller) webhooksConfirmed() bool {
+	last := p.server.LastAccountDelivery(firstNonEmpty(p.account, p.server.defaultAccount()))
+	return !last.IsZero() && time.Since(last) < webhookConfirmWindow
+}
+
+// PollOnce compares current orders and offers with what has been seen and announces changes.
+// The first poll that reads them only records a baseline, so existing orders and offers
+// are not re-announced.
+func (p *Poller) PollOnce() error {
+	ordersBaseline := p.needsBaseline(pollInitializedKey)
+	orders, err := p.pollOrders()
+	if err != nil {
+		return fmt.Errorf("failed to poll orders: %w", err)
+	}
+	for _, order := range orders {
+		p.checkOrder(order, ordersBaseline)
+	}
+	if ordersBaseline {
+		log.Printf("🔁 Poller baseline recorded%s (%d orders)", forAccount(p.account), len(orders))
+		if err := p.saveBaseline(pollInitializedKey); err != nil {
+			return err
+		}
+	}
+
+	// The Negotiation API is not available to every seller; treat failures as "no offers".
+	// Until a poll reads them, the offer baseline stays to be taken.
+	if offers, err := p.source.GetOffers(); err == nil {
+		offersBaseline := p.needsBaseline(pollOffersInitializedKey)
+		for _, offer := range offers {
+			p.checkOffer(offer, offersBaseline)
+		}
+		if offersBaseline {
+			log.Printf("🔁 Poller offer baseline recorded%s (%d offers)", forAccount(p.account), len(offers))
+			if err := p.saveBaseline(pollOffersInitializedKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	return p.store.PrunePollMarks(time.Now().Add(-pollMarkRetention))
+}
+
+// pollOrders reads every recently modified order, a page at a time
+func (p *Poller) pollOrders() ([]ebay.Order, error) {
+	since := time.Now().Add(-pollLookback)
+	var all []ebay.Order
+	for offset := 0; ; {
+		orders, total, err := p.source.GetOrdersModifiedSince(since, pollOrderLimit, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, orders...)
+		offset += len(orders)
+		if len(orders) == 0 || offset >= total {
+			return all, nil
+		}
+	}
+}
+
+// checkOrder announces a new order or a change to PAID for a known order. An order
+// that could not be announced isn't marked as seen, so the next poll tries again.
+func (p *Poller) checkOrder(order ebay.Order, baseline bool) {
+	key := "order:" + order.OrderID
+	status := order.OrderPaymentStatus
+
+	var announceErr error
+	mark, err := p.store.GetPollMark(key)
+	switch {
+	case err != nil && !baseline:
+		announceErr = p.announce(orderNotification(OrderPlaced, order))
+	case err == nil && mark.Value != status && status == "PAID" && !baseline:
+		announceErr = p.announce(orderNotification(OrderPaid, order))
+	case err == nil && mark.Value == status:
+		return
+	}
+	if announceErr != nil {
+		log.Printf("❌ %v - retrying order %s on the next poll", announceErr, order.OrderID)
+		return
+	}
+
+	if err := p.store.SavePollMark(key, status); err != nil {
+		log.Printf("⚠️ Failed to record order %s as seen: %v", order.OrderID, err)
+	}
+}
+
+// checkOffer announces offers that have not been seen before, retrying on the next poll
+// if that fails
+func (p *Poller) checkOffer(offer ebay.Offer, baseline bool) {
+	key := "offer:" + offer.OfferID
+	if mark, err := p.store.GetPollMark(key); err == nil && mark.Value == offer.Status {
+		return
+	} else if err != nil && !baseline {
+		if err := p.announce(offerNotification(OfferCreated, offer)); err != nil {
+			log.Printf("❌ %v - retrying offer %s on the next poll", err, offer.OfferID)
+			return
+		}
+	}
+
+	if err := p.store.SavePollMark(key, offer.Status); err != nil {
+		log.Printf("⚠️ Failed to record offer %s as seen: %v", offer.OfferID, err)
+	}
+}
+
+// announce sends a synthesized notification through the normal Discord pipeline
+func (p *Poller) announce(notification *EbayNotification) error {
+	notification.Account = p.account
+	log.Printf("🔁 Poller detected %s", notification.EventType())
+	if err := p.notify(notification); err != nil {
+		return fmt.Errorf("failed to queue polled %s: %w", notification.EventType(), err)
+	}
+	return nil
+}
+
+// orderNotification builds a typed order notification from a polled order
+func orderNotification(event string, order ebay.Order) *EbayNotification {
+	data := &OrderEvent{
+		EventType:     event,
+		OrderID:       order.OrderID,
+		BuyerUsername: order.BuyerUsername,
+		TotalPrice:    Amount{Value: strconv.FormatFloat(order.TotalPrice, 'f', 2, 64), Currency: order.Currency},
+	}
+	if len(order.LineItems) > 0 {
+		data.ItemTitle = order.LineItems[0].Title
+	}
+	for _, li := range order.LineItems {
+		if li.SKU != "" {
+			data.SKUs = append(data.SKUs, li.SKU)
+		}
+	}
+
+	return &EbayNotification{
+		Topic:       TopicOrder,
+		Event:       event,
+		PublishDate: time.Now().Format(time.RFC3339),
+		Order:       data,
+	}
+}
+
+// offerNotification builds a typed offer notification from a polled offer
+func offerNotification(event string, offer ebay.Offer) *EbayNotification {
+	return &EbayNotification{
+		Topic:       TopicOffer,
+		Event:       event,
+		PublishDate: time.Now().Format(time.RFC3339),
+		Offer: &OfferEvent{
+			EventType:     event,
+			OfferID:       offer.OfferID,
+			BuyerUsername: offer.BuyerUsername,
+			ItemID:        offer.ItemID,
+			ItemTitle:     offer.ItemTitle,
+			OfferPrice:    Amount{Value: strconv.FormatFloat(offer.OfferPrice, 'f', 2, 64), Currency: offer.Currency},
+			ListPrice:     Amount{Value: strconv.FormatFloat(offer.ListPrice, 'f', 2, 64), Currency: offer.Currency},
+		},
+	}
+}
@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+)
+
+type fakePollSource struct {
+	orders    []ebay.Order
+	offers    []ebay.Offer
+	offersErr error
+}
+
+func (f *fakePollSource) GetOrdersModifiedSince(since time.Time, limit, offset int) ([]ebay.Order, int, error) {
+	if offset >= len(f.orders) {
+		return nil, len(f.orders), nil
+	}
+	end := offset + limit
+	if end > len(f.orders) {
+		end = len(f.orders)
+	}
+	return f.orders[offset:end], len(f.orders), nil
+}
+
+func (f *fakePollSource) GetOffers() ([]ebay.Offer, error) { return f.offers, f.offersErr }
+
+func newTestPoller(t *testing.T, source *fakePollSource) (*Poller, *[]string) {
+	t.Helper()
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	server := NewServer(nil, "", "token", "0")
+	server.SetStore(repo)
+
+	p := NewPoller(server, source, repo, time.Minute)
+	var announced []string
+	p.notify = func(n *EbayNotification) error {
+		announced = append(announced, n.EventType())
+		return nil
+	}
+	return p, &announced
+}
+
+func TestPollerBaselineDoesNotAnnounce(t *testing.T) {
+	source := &fakePollSource{
+		orders: []ebay.Order{{OrderID: "o1", OrderPaymentStatus: "PAID"}},
+		offers: []ebay.Offer{{OfferID: "f1", Status: "PENDING"}},
+	}
+	p, announced := newTestPoller(t, source)
+
+	if err := p.PollOnce(); err != nil {
+		t.Fatalf("PollOnce failed: %v", err)
+	}
+	if len(*announced) != 0 {
+		t.Errorf("Expected no announcements on baseline poll, got %v", *announced)
+	}
+}
+
+func TestPollerAnnouncesNewOrdersPaymentsAndOffers(t *testing.T) {
+	source := &fakePollSource{
+		orders: []ebay.Order{{OrderID: "o1", OrderPaymentStatus: "PAID"}},
+	}
+	p, announced := newTestPoller(t, source)
+	p.PollOnce() // baseline
+
+	source.orders = append(source.orders, ebay.Order{OrderID: "o2", OrderPaymentStatus: "PENDING"})
+	source.offers = []ebay.Offer{{OfferID: "f1", Status: "PENDING"}}
+	p.PollOnce()
+
+	source.orders[1].OrderPaymentStatus = "PAID"
+	p.PollOnce()
+
+	// Nothing changed: nothing new is announced
+	p.PollOnce()
+
+	want := []string{"MARKETPLACE_ORDER.PLACED", "MARKETPLACE_OFFER.CREATED", "MARKETPLACE_ORDER.PAID"}
+	if len(*announced) != len(want) {
+		t.Fatalf("Expected announcements %v, got %v", want, *announced)
+	}
+	for i := range want {
+		if (*announced)[i] != want[i] {
+			t.Errorf("Announcement %d: expected %s, got %s", i, want[i], (*announced)[i])
+		}
+	}
+}
+
+func TestPollerRetriesFailedAnnouncements(t *testing.T) {
+	source := &fakePollSource{}
+	p, announced := newTestPoller(t, source)
+	p.PollOnce() // baseline
+
+	source.orders = []ebay.Order{{OrderID: "o1", OrderPaymentStatus: "PENDING"}}
+	source.offers = []ebay.Offer{{OfferID: "f1", Status: "PENDING"}}
+	notify := p.notify
+	p.notify = func(n *EbayNotification) error { return errors.New("outbox unavailable") }
+	p.PollOnce()
+
+	p.notify = notify
+	p.PollOnce()
+	want := []string{"MARKETPLACE_ORDER.PLACED", "MARKETPLACE_OFFER.CREATED"}
+	if len(*announced) != len(want) || (*announced)[0] != want[0] || (*announced)[1] != want[1] {
+		t.Errorf("Expected the failed announcements to be retried as %v, got %v", want, *announced)
+	}
+}
+
+func TestPollerReadsEveryOrderPage(t *testing.T) {
+	source := &fakePollSource{}
+	for i := 0; i <= pollOrderLimit; i++ {
+		source.orders = append(source.orders, ebay.Order{OrderID: fmt.Sprintf("o%d", i), OrderPaymentStatus: "PAID"})
+	}
+	p, announced := newTestPoller(t, source)
+	p.PollOnce() // baseline covers both pages
+
+	source.orders = append(source.orders, ebay.Order{OrderID: "new", OrderPaymentStatus: "PENDING"})
+	p.PollOnce()
+	if len(*announced) != 1 || (*announced)[0] != "MARKETPLACE_ORDER.PLACED" {
+		t.Errorf("Expected only the new order on the second page announced, got %v", *announced)
+	}
+}
+
+func TestPollerRetriesOfferBaseline(t *testing.T) {
+	source := &fakePollSource{
+		offers:    []ebay.Offer{{OfferID: "f1", Status: "PENDING"}},
+		offersErr: errors.New("negotiation API unavailable"),
+	}
+	p, announced := newTestPoller(t, source)
+	p.PollOnce() // order baseline; offers can't be read
+
+	source.offersErr = nil
+	p.PollOnce() // offer baseline: the existing offer isn't new
+	if len(*announced) != 0 {
+		t.Fatalf("Expected existing offers to form the baseline, got %v", *announced)
+	}
+
+	source.offers = append(source.offers, ebay.Offer{OfferID: "f2", Status: "PENDING"})
+	p.PollOnce()
+	if len(*announced) != 1 || (*announced)[0] != "MARKETPLACE_OFFER.CREATED" {
+		t.Errorf("Expected the new offer announced, got %v", *announced)
+	}
+}
+
+func TestPollerSwitchesOffAfterWebhookDelivery(t *testing.T) {
+	p, _ := newTestPoller(t, &fakePollSource{})
+
+	if p.webhooksConfirmed() {
+		t.Fatal("Webhooks should not be confirmed before any delivery")
+	}
+
+	p.server.recordDelivery(&EbayNotification{Topic: TopicOrder})
+	if !p.webhooksConfirmed() {
+		t.Error("Webhooks should be confirmed after a delivery")
+	}
+}
+
+func TestPollersAreConfirmedPerAccount(t *testing.T) {
+	main, _ := newTestPoller(t, &fakePollSource{})
+	server := main.server
+	server.SetAccounts(testAccounts(t))
+	vintage := NewPoller(server, &fakePollSource{}, server.store, time.Minute)
+	vintage.SetAccount("vintage")
+
+	// Account deletions are for the app, not for a seller's subscriptions
+	server.recordDelivery(&EbayNotification{Topic: TopicAccountDeletion, AccountDeletion: &AccountDeletionEvent{UserID: "buyer-9"}})
+	if main.webhooksConfirmed() || vintage.webhooksConfirmed() {
+		t.Error("An account deletion must not switch a poller off")
+	}
+
+	server.recordDelivery(&EbayNotification{Topic: TopicOrder, SellerID: "seller-2"})
+	if !vintage.webhooksConfirmed() {
+		t.Error("vintage's delivery should switch its poller off")
+	}
+	if main.webhooksConfirmed() {
+		t.Error("vintage's delivery must not switch main's poller off")
+	}
+
+	// Confirmations survive a restart
+	restarted := NewServer(nil, "", "token", "0")
+	restarted.SetStore(server.store)
+	if restarted.LastAccountDelivery("vintage").IsZero() || !restarted.LastAccountDelivery("main").IsZero() {
+		t.Error("Expected only vintage's delivery to be remembered")
+	}
+}
+
+func TestOnlyVerifiedNotificationsConfirmWebhooks(t *testing.T) {
+	p, _ := newTestPoller(t, &fakePollSource{})
+	verifier, fetcher := newTestVerifier(t)
+	p.server.SetSignatureVerifier(verifier, false)
+
+	body := []byte(`{"metadata":{"topic":"MARKETPLACE_OFFER"},"notification":{"notificationId":"n-1","data":{"offerId":"f1"}}}`)
+	post := func(body []byte, signature string) {
+		req := httptest.NewRequest(http.MethodPost, notificationPath, bytes.NewReader(body))
+		if signature != "" {
+			req.Header.Set("X-EBAY-SIGNATURE", signature)
+		}
+		rec := httptest.NewRecorder()
+		p.server.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+	}
+
+	// Accepted without strict signatures, but anyone could have sent it
+	post(body, "")
+	if p.webhooksConfirmed() {
+		t.Error("An unsigned notification must not switch the poller off")
+	}
+
+	body = bytes.Replace(body, []byte("n-1"), []byte("n-2"), 1)
+	post(body, signBody(t, fetcher.key, "test-kid", body))
+	if !p.webhooksConfirmed() {
+		t.Error("A verified notification should switch the poller off")
+	}
+}
+
+func TestAccountPollerKeepsItsOwnBaseline(t *testing.T) {
+	source := &fakePollSource{orders: []ebay.Order{{OrderID: "o1", OrderPaymentStatus: "PAID"}}}
+	p, _ := newTestPoller(t, source)
+	p.PollOnce() // the default account's baseline
+
+	p.SetAccount("vintage")
+	var accounts []string
+	p.notify = func(n *EbayNotification) error {
+		accounts = append(accounts, n.Account)
+		return nil
+	}
+	source.orders = append(source.orders, ebay.Order{OrderID: "o2"})
+	p.PollOnce() // vintage's baseline: nothing announced
+
+	source.orders = append(source.orders, ebay.Order{OrderID: "o3"})
+	p.PollOnce()
+	if len(accounts) != 1 || accounts[0] != "vintage" {
+		t.Errorf("Expected one order announced for vintage, got %v", accounts)
+	}
+}
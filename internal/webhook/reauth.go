@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// reminderCheckInterval is how often the refresh token's expiry is checked
+const reminderCheckInterval = time.Hour
+
+// reminderDays are the days before the refresh token expires that a reminder is posted,
+// least urgent first
+var reminderDays = []int{30, 7, 1}
+
+// ReminderTokens is the eBay client's view of the refresh token and the reminders sent for it
+type ReminderTokens interface {
+	Status() ebay.APIStatus
+	ReminderSent() int
+	RecordReminder(days int) error
+}
+
+// ReauthReminder posts escalating reminders to the admin channel before the seller's
+// refresh token expires and the bot loses access to eBay
+type ReauthReminder struct {
+	tokens     ReminderTokens
+	dispatcher *Dispatcher
+	channelID  string
+	authorize  string // how to mention /ebay-authorize in the reminder
+	account    string // seller account named in the reminder; empty with a single account
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReauthReminder creates a reminder that posts to channelID through dispatcher
+func NewReauthReminder(tokens ReminderTokens, dispatcher *Dispatcher, channelID string) *ReauthReminder {
+	return &ReauthReminder{
+		tokens:     tokens,
+		dispatcher: dispatcher,
+		channelID:  channelID,
+		authorize:  "`/ebay-authorize`",
+	}
+}
+
+// SetAuthorizeCommand sets the mention of /ebay-authorize (e.g. </ebay-authorize:id>)
+// so the reminder links straight to the command
+func (r *ReauthReminder) SetAuthorizeCommand(mention string) {
+	if mention != "" {
+		r.authorize = mention
+	}
+}
+
+// SetAccount names the seller account whose refresh token the reminder is for
+func (r *ReauthReminder) SetAccount(name string) {
+	r.account = name
+}
+
+// Start checks the refresh token now and every hour until Stop is called
+func (r *ReauthReminder) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.loop(r.stop, r.done)
+}
+
+// Stop ends the checks
+func (r *ReauthReminder) Stop() {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (r *ReauthReminder) loop(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Check(time.Now()); err != nil {
+			log.Printf("⚠️ Re-authorization reminder%s failed: %v", forAccount(r.account), err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check posts the most urgent reminder that is due and hasn't been posted for the current
+// refresh token. If the bot was down through a reminder, only the most urgent one is posted.
+func (r *ReauthReminder) Check(now time.Time) error {
+	expiry := r.tokens.Status().RefreshExpiry
+	if expiry.IsZero() || r.channelID == "" {
+		return nil
+	}
+
+	days := dueReminder(expiry.Sub(now))
+	if days == 0 {
+		return nil
+	}
+	if sent := r.tokens.ReminderSent(); sent != 0 && sent <= days {
+		return nil
+	}
+
+	if err := r.dispatcher.Enqueue(Outgoing{ChannelID: r.channelID, EventType: "REAUTH_REMINDER", Embed: r.embed(expiry, now, days)}); err != nil {
+		return err
+	}
+	log.Printf("🔑 Posted %d-day re-authorization reminder%s (refresh token expires %s)", days, forAccount(r.account), expiry.Format(time.RFC3339))
+	if err := r.tokens.RecordReminder(days); err != nil {
+		log.Printf("⚠️ Failed to save re-authorization reminder state: %v", err)
+	}
+	return nil
+}
+
+// dueReminder returns the most urgent reminder threshold within remaining, or 0 for none
+func dueReminder(remaining time.Duration) int {
+	due := 0
+	for _, days := range reminderDays {
+		if remaining <= time.Duration(days)*24*time.Hour {
+			due = days
+		}
+	}
+	return due
+}
+
+// embed builds the reminder, redder as expiry gets closer
+func (r *ReauthReminder) embed(expiry, now time.Time, days int) *discordgo.MessageEmbed {
+	title, color := "🔑 eBay Re-authorization Due Soon", 0xf1c40f
+	switch {
+	case !now.Before(expiry):
+		title, color = "🚨 eBay Authorization Expired", 0xe74c3c
+	case days <= 1:
+		title, color = "🚨 eBay Authorization Expires Within 24 Hours", 0xe74c3c
+	case days <= 7:
+		title, color = "⚠️ eBay Authorization Expires This Week", 0xe67e22
+	}
+
+	status := fmt.Sprintf("expires <t:%d:R>", expiry.Unix())
+	if !now.Before(expiry) {
+		status = fmt.Sprintf("expired <t:%d:R>", expiry.Unix())
+	}
+	whose, authorize := "The bot's eBay refresh token", r.authorize
+	if r.account != "" {
+		whose = fmt.Sprintf("The eBay refresh token for account **%s**", r.account)
+		authorize += fmt.Sprintf(" with `account: %s`", r.account)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: title,
+		Description: fmt.Sprintf("%s %s (<t:%d:f>). After that it can no longer reach eBay: "+
+			"orders, offers and notifications stop.\n\nRun %s and sign in to eBay to renew it.", whose, status, expiry.Unix(), authorize),
+		Color:     color,
+		Timestamp: now.Format(time.RFC3339),
+	}
+	if r.account != "" {
+		embed.Author = &discordgo.MessageEmbedAuthor{Name: "🏪 " + r.account}
+	}
+	return embed
+}
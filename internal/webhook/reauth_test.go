@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+)
+
+// fakeReminderTokens is a refresh token with a fixed expiry
+type fakeReminderTokens struct {
+	expiry time.Time
+	sent   int
+}
+
+func (f *fakeReminderTokens) Status() ebay.APIStatus {
+	return ebay.APIStatus{HasToken: true, RefreshExpiry: f.expiry}
+}
+func (f *fakeReminderTokens) ReminderSent() int { return f.sent }
+func (f *fakeReminderTokens) RecordReminder(days int) error {
+	f.sent = days
+	return nil
+}
+
+func TestDueReminder(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		remaining time.Duration
+		want      int
+	}{
+		{60 * day, 0},
+		{30*day + time.Hour, 0},
+		{30 * day, 30},
+		{8 * day, 30},
+		{7 * day, 7},
+		{2 * day, 7},
+		{12 * time.Hour, 1},
+		{-day, 1},
+	}
+	for _, tt := range tests {
+		if got := dueReminder(tt.remaining); got != tt.want {
+			t.Errorf("dueReminder(%s) = %d, want %d", tt.remaining, got, tt.want)
+		}
+	}
+}
+
+func TestReauthReminderEscalates(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	now := time.Now()
+	tokens := &fakeReminderTokens{expiry: now.Add(40 * 24 * time.Hour)}
+	reminder := NewReauthReminder(tokens, NewDispatcher(&fakeSender{}, repo, 1), "admin")
+	reminder.SetAuthorizeCommand("</ebay-authorize:123>")
+
+	posted := func() []store.OutboxMessage {
+		pending, _ := repo.ListOutbox(store.OutboxPending, 0)
+		return pending
+	}
+	checkAt := func(daysLeft float64) {
+		t.Helper()
+		at := tokens.expiry.Add(-time.Duration(daysLeft * float64(24*time.Hour)))
+		if err := reminder.Check(at); err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+	}
+
+	checkAt(40)
+	if len(posted()) != 0 {
+		t.Fatal("Expected no reminder 40 days out")
+	}
+
+	checkAt(29)
+	checkAt(28)
+	if len(posted()) != 1 || tokens.sent != 30 {
+		t.Fatalf("Expected one 30-day reminder, got %d (sent %d)", len(posted()), tokens.sent)
+	}
+
+	// The bot was down through the 7-day reminder: only the 1-day one is posted
+	checkAt(0.5)
+	checkAt(0.25)
+	if msgs := posted(); len(msgs) != 2 || tokens.sent != 1 || msgs[1].ChannelID != "admin" {
+		t.Fatalf("Expected the 1-day reminder next, got %d (sent %d)", len(msgs), tokens.sent)
+	}
+
+	// Re-authorizing issues a new refresh token and starts over
+	tokens.expiry, tokens.sent = now.Add(540*24*time.Hour), 0
+	checkAt(6)
+	if len(posted()) != 3 || tokens.sent != 7 {
+		t.Errorf("Expected a reminder for the new token, got %d (sent %d)", len(posted()), tokens.sent)
+	}
+}
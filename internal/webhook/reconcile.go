@@ -0,0 +1,340 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SubscriptionAPI is the subset of the eBay client the reconciler needs
+type SubscriptionAPI interface {
+	GetDestinations() ([]ebay.Destination, error)
+	CreateDestination(dest ebay.Destination) (string, error)
+	UpdateDestination(dest ebay.Destination) error
+	GetSubscriptions() ([]ebay.NotificationSubscription, error)
+	SubscribeTopic(topicID, destinationID string) (*ebay.NotificationSubscription, error)
+	UpdateSubscription(sub ebay.NotificationSubscription) error
+	EnableSubscription(subscriptionID string) error
+	DeleteSubscription(subscriptionID string) error
+}
+
+// DesiredSubscriptions is the Notification API state the reconciler converges on
+type DesiredSubscriptions struct {
+	Endpoint    string   // public notification URL
+	VerifyToken string   // token eBay challenges the endpoint with
+	Topics      []string // topics to deliver to Endpoint
+}
+
+// Reconciliation actions
+const (
+	ActionCreateDestination = "create destination"
+	ActionUpdateDestination = "update destination"
+	ActionSubscribe         = "subscribe"
+	ActionEnable            = "enable"
+	ActionMove              = "move to endpoint"
+	ActionUnsubscribe       = "unsubscribe"
+)
+
+// ReconcileChange is one difference between the desired and actual state
+type ReconcileChange struct {
+	Action string
+	Target string // topic or destination
+	Detail string
+	Err    error // set when applying the change failed
+}
+
+// ReconcileReport describes what a reconciliation found and did
+type ReconcileReport struct {
+	Endpoint      string
+	DestinationID string
+	DryRun        bool
+	Changes       []ReconcileChange
+}
+
+// InSync reports whether nothing differed from the desired state
+func (r *ReconcileReport) InSync() bool {
+	return len(r.Changes) == 0
+}
+
+// Failed returns the changes that could not be applied
+func (r *ReconcileReport) Failed() []ReconcileChange {
+	var failed []ReconcileChange
+	for _, c := range r.Changes {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Summary renders the report as Discord message text
+func (r *ReconcileReport) Summary() string {
+	var b strings.Builder
+	switch {
+	case r.InSync():
+		fmt.Fprintf(&b, "✅ Subscriptions for `%s` match the configuration\n", r.Endpoint)
+		return b.String()
+	case r.DryRun:
+		fmt.Fprintf(&b, "🔍 **%d difference(s)** for `%s` - run without `dry-run` to fix them\n\n", len(r.Changes), r.Endpoint)
+	default:
+		fmt.Fprintf(&b, "🔧 **Reconciled %d difference(s)** for `%s`\n\n", len(r.Changes), r.Endpoint)
+	}
+	for _, c := range r.Changes {
+		icon := "•"
+		if !r.DryRun {
+			icon = "✅"
+			if c.Err != nil {
+				icon = "❌"
+			}
+		}
+		fmt.Fprintf(&b, "%s %s **%s**", icon, c.Action, c.Target)
+		if c.Detail != "" {
+			fmt.Fprintf(&b, " - %s", c.Detail)
+		}
+		if c.Err != nil {
+			fmt.Fprintf(&b, "\n   %v", c.Err)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Reconciler keeps the Notification API destination and subscriptions for the bot's
+// endpoint in line with the configuration. Only the destination for the configured
+// endpoint is changed; desired topics subscribed elsewhere are moved to it.
+type Reconciler struct {
+	api     SubscriptionAPI
+	desired DesiredSubscriptions
+
+	dispatcher   *Dispatcher
+	adminChannel string
+	account      string // seller account named in reports; empty with a single account
+}
+
+// reconcileMu runs one reconciliation at a time across every account: destinations
+// belong to the eBay app, so accounts reconciling at once would each create one
+var reconcileMu sync.Mutex
+
+// NewReconciler creates a reconciler converging on desired
+func NewReconciler(api SubscriptionAPI, desired DesiredSubscriptions) *Reconciler {
+	return &Reconciler{api: api, desired: desired}
+}
+
+// SetReporter posts applied changes and failures to channelID through dispatcher
+func (r *Reconciler) SetReporter(dispatcher *Dispatcher, channelID string) {
+	r.dispatcher = dispatcher
+	r.adminChannel = channelID
+}
+
+// SetAccount names the seller account whose subscriptions this reconciler manages in
+// the reports it posts
+func (r *Reconciler) SetAccount(name string) {
+	r.account = name
+}
+
+// Reconcile diffs the desired state against eBay and, unless dryRun is set, applies the
+// differences. Drift that was acted on is reported to the admin channel.
+func (r *Reconciler) Reconcile(dryRun bool) (*ReconcileReport, error) {
+	reconcileMu.Lock()
+	defer reconcileMu.Unlock()
+
+	report := &ReconcileReport{Endpoint: r.desired.Endpoint, DryRun: dryRun}
+	if err := r.reconcileDestination(report); err != nil {
+		return nil, err
+	}
+	if err := r.reconcileSubscriptions(report); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case report.InSync():
+		log.Printf("✅ Notification subscriptions for %s are in sync", report.Endpoint)
+	case dryRun:
+		log.Printf("🔍 Notification subscriptions for %s differ in %d place(s)", report.Endpoint, len(report.Changes))
+	default:
+		log.Printf("🔧 Reconciled %d notification subscription change(s), %d failed", len(report.Changes), len(report.Failed()))
+		r.report(report)
+	}
+	return report, nil
+}
+
+// reconcileDestination finds or creates the destination for the endpoint and enables it
+func (r *Reconciler) reconcileDestination(report *ReconcileReport) error {
+	destinations, err := r.api.GetDestinations()
+	if err != nil {
+		return err
+	}
+
+	for _, dest := range destinations {
+		if dest.DeliveryConfig.Endpoint != r.desired.Endpoint {
+			continue
+		}
+		report.DestinationID = dest.DestinationID
+
+		var drift []string
+		if dest.Status != ebay.StatusEnabled {
+			drift = append(drift, "status "+dest.Status)
+		}
+		// eBay may leave the token out of responses; only a returned, different token is drift
+		if token := dest.DeliveryConfig.VerificationToken; token != "" && token != r.desired.VerifyToken {
+			drift = append(drift, "verification token changed")
+		}
+		if len(drift) == 0 {
+			return nil
+		}
+
+		change := ReconcileChange{Action: ActionUpdateDestination, Target: dest.DestinationID, Detail: strings.Join(drift, ", ")}
+		if !report.DryRun {
+			dest.Status = ebay.StatusEnabled
+			dest.DeliveryConfig.VerificationToken = r.desired.VerifyToken
+			change.Err = r.api.UpdateDestination(dest)
+		}
+		report.Changes = append(report.Changes, change)
+		return nil
+	}
+
+	change := ReconcileChange{Action: ActionCreateDestination, Target: r.desired.Endpoint}
+	if !report.DryRun {
+		report.DestinationID, change.Err = r.api.CreateDestination(ebay.Destination{
+			Name:   "Discord_Bot_Notifications",
+			Status: ebay.StatusEnabled,
+			DeliveryConfig: ebay.DeliveryConfig{
+				Endpoint:          r.desired.Endpoint,
+				VerificationToken: r.desired.VerifyToken,
+			},
+		})
+	}
+	report.Changes = append(report.Changes, change)
+	return nil
+}
+
+// reconcileSubscriptions subscribes, enables, moves and removes topics on the destination
+func (r *Reconciler) reconcileSubscriptions(report *ReconcileReport) error {
+	subs, err := r.api.GetSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	desired := make(map[string]bool, len(r.desired.Topics))
+	for _, topic := range r.desired.Topics {
+		desired[topic] = true
+	}
+	destID := report.DestinationID
+
+	// Subscriptions to the desired topics, preferring one already on the destination
+	current := make(map[string]ebay.NotificationSubscription)
+	for _, sub := range subs {
+		if !desired[sub.TopicID] {
+			if destID != "" && sub.DestinationID == destID {
+				report.Changes = append(report.Changes, r.apply(report, ReconcileChange{Action: ActionUnsubscribe, Target: sub.TopicID, Detail: "not in the configured topics"}, func() error {
+					return r.api.DeleteSubscription(sub.SubscriptionID)
+				}))
+			}
+			continue
+		}
+		if existing, ok := current[sub.TopicID]; !ok || (existing.DestinationID != destID && sub.DestinationID == destID) {
+			current[sub.TopicID] = sub
+		}
+	}
+
+	for _, topic := range r.desired.Topics {
+		sub, ok := current[topic]
+		switch {
+		case !ok:
+			report.Changes = append(report.Changes, r.apply(report, ReconcileChange{Action: ActionSubscribe, Target: topic}, func() error {
+				if destID == "" {
+					return fmt.Errorf("no destination for %s", r.desired.Endpoint)
+				}
+				_, err := r.api.SubscribeTopic(topic, destID)
+				return err
+			}))
+		case sub.DestinationID != destID:
+			report.Changes = append(report.Changes, r.apply(report, ReconcileChange{Action: ActionMove, Target: topic, Detail: "was delivered to destination " + sub.DestinationID}, func() error {
+				if destID == "" {
+					return fmt.Errorf("no destination for %s", r.desired.Endpoint)
+				}
+				sub.DestinationID = destID
+				sub.Status = ebay.StatusEnabled
+				return r.api.UpdateSubscription(sub)
+			}))
+		case sub.Status != ebay.StatusEnabled:
+			report.Changes = append(report.Changes, r.apply(report, ReconcileChange{Action: ActionEnable, Target: topic, Detail: "status " + sub.Status}, func() error {
+				return r.api.EnableSubscription(sub.SubscriptionID)
+			}))
+		}
+	}
+	return nil
+}
+
+// apply runs fn for change unless this is a dry run
+func (r *Reconciler) apply(report *ReconcileReport, change ReconcileChange, fn func() error) ReconcileChange {
+	if !report.DryRun {
+		change.Err = fn()
+		if change.Err != nil {
+			log.Printf("❌ Failed to %s %s: %v", change.Action, change.Target, change.Err)
+		}
+	}
+	return change
+}
+
+// report posts an applied reconciliation to the admin channel
+func (r *Reconciler) report(report *ReconcileReport) {
+	if r.dispatcher == nil || r.adminChannel == "" {
+		return
+	}
+
+	description := report.Summary()
+	if r := []rune(description); len(r) > 4096 {
+		description = string(r[:4095]) + "…"
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "🔧 Notification Subscription Drift",
+		Description: description,
+		Color:       0xf1c40f,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	if r.account != "" {
+		embed.Author = &discordgo.MessageEmbedAuthor{Name: "🏪 " + r.account}
+	}
+	if len(report.Failed()) > 0 {
+		embed.Color = 0xe74c3c
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "Some changes failed - check /webhook-subscription list"}
+	}
+	if err := r.dispatcher.Enqueue(Outgoing{ChannelID: r.adminChannel, EventType: "SUBSCRIPTION_DRIFT", Embed: embed}); err != nil {
+		log.Printf("⚠️ Failed to report subscription drift: %v", err)
+	}
+}
+
+// SetReconciler enables on-demand subscription reconciliation for a seller account
+func (s *Server) SetReconciler(account string, r *Reconciler) {
+	if s.reconcilers == nil {
+		s.reconcilers = make(map[string]*Reconciler)
+	}
+	s.reconcilers[account] = r
+}
+
+// ReconcileSubscriptions runs the account's reconciler (the default account's when account
+// is empty) and returns its report as Discord text
+func (s *Server) ReconcileSubscriptions(account string, dryRun bool) (string, error) {
+	if account == "" {
+		account = s.defaultAccount()
+	}
+	reconciler, ok := s.reconcilers[account]
+	if !ok {
+		return "", fmt.Errorf("subscription reconciliation is disabled: set WEBHOOK_PUBLIC_URL")
+	}
+	report, err := reconciler.Reconcile(dryRun)
+	if err != nil {
+		return "", err
+	}
+	if s.multiAccount() {
+		return fmt.Sprintf("🏪 Account **%s**\n%s", account, report.Summary()), nil
+	}
+	return report.Summary(), nil
+}
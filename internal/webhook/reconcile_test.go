@@ -0,0 +1,211 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+)
+
+// fakeSubscriptionAPI keeps Notification API state in memory and records mutating calls
+type fakeSubscriptionAPI struct {
+	destinations  []ebay.Destination
+	subscriptions []ebay.NotificationSubscription
+	calls         []string
+}
+
+func (f *fakeSubscriptionAPI) GetDestinations() ([]ebay.Destination, error) {
+	return append([]ebay.Destination(nil), f.destinations...), nil
+}
+
+func (f *fakeSubscriptionAPI) CreateDestination(dest ebay.Destination) (string, error) {
+	dest.DestinationID = fmt.Sprintf("dest-%d", len(f.destinations)+1)
+	f.destinations = append(f.destinations, dest)
+	f.calls = append(f.calls, "create destination")
+	return dest.DestinationID, nil
+}
+
+func (f *fakeSubscriptionAPI) UpdateDestination(dest ebay.Destination) error {
+	for i := range f.destinations {
+		if f.destinations[i].DestinationID == dest.DestinationID {
+			f.destinations[i] = dest
+		}
+	}
+	f.calls = append(f.calls, "update destination "+dest.DestinationID)
+	return nil
+}
+
+func (f *fakeSubscriptionAPI) GetSubscriptions() ([]ebay.NotificationSubscription, error) {
+	return append([]ebay.NotificationSubscription(nil), f.subscriptions...), nil
+}
+
+func (f *fakeSubscriptionAPI) SubscribeTopic(topicID, destinationID string) (*ebay.NotificationSubscription, error) {
+	sub := ebay.NotificationSubscription{
+		SubscriptionID: fmt.Sprintf("sub-%d", len(f.subscriptions)+1),
+		TopicID:        topicID,
+		DestinationID:  destinationID,
+		Status:         ebay.StatusEnabled,
+	}
+	f.subscriptions = append(f.subscriptions, sub)
+	f.calls = append(f.calls, "subscribe "+topicID)
+	return &sub, nil
+}
+
+func (f *fakeSubscriptionAPI) UpdateSubscription(sub ebay.NotificationSubscription) error {
+	f.setSubscription(sub.SubscriptionID, func(s *ebay.NotificationSubscription) { *s = sub })
+	f.calls = append(f.calls, "update "+sub.SubscriptionID)
+	return nil
+}
+
+func (f *fakeSubscriptionAPI) EnableSubscription(id string) error {
+	f.setSubscription(id, func(s *ebay.NotificationSubscription) { s.Status = ebay.StatusEnabled })
+	f.calls = append(f.calls, "enable "+id)
+	return nil
+}
+
+func (f *fakeSubscriptionAPI) DeleteSubscription(id string) error {
+	for i := range f.subscriptions {
+		if f.subscriptions[i].SubscriptionID == id {
+			f.subscriptions = append(f.subscriptions[:i], f.subscriptions[i+1:]...)
+			break
+		}
+	}
+	f.calls = append(f.calls, "delete "+id)
+	return nil
+}
+
+func (f *fakeSubscriptionAPI) setSubscription(id string, fn func(*ebay.NotificationSubscription)) {
+	for i := range f.subscriptions {
+		if f.subscriptions[i].SubscriptionID == id {
+			fn(&f.subscriptions[i])
+		}
+	}
+}
+
+var testDesired = DesiredSubscriptions{
+	Endpoint:    "https://bot.example.com/webhook/ebay/notification",
+	VerifyToken: "verify-token",
+	Topics:      []string{"MARKETPLACE_OFFER", "MARKETPLACE_ORDER", "ITEM_INVENTORY"},
+}
+
+func TestReconcileFromScratch(t *testing.T) {
+	api := &fakeSubscriptionAPI{}
+	report, err := NewReconciler(api, testDesired).Reconcile(false)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	want := "create destination,subscribe MARKETPLACE_OFFER,subscribe MARKETPLACE_ORDER,subscribe ITEM_INVENTORY"
+	if got := strings.Join(api.calls, ","); got != want {
+		t.Errorf("Calls = %q, want %q", got, want)
+	}
+	if report.DestinationID != "dest-1" || len(report.Changes) != 4 || len(report.Failed()) != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if dest := api.destinations[0]; dest.DeliveryConfig.VerificationToken != "verify-token" || dest.Status != ebay.StatusEnabled {
+		t.Errorf("Unexpected destination: %+v", dest)
+	}
+
+	// A second run finds nothing to do
+	api.calls = nil
+	report, err = NewReconciler(api, testDesired).Reconcile(false)
+	if err != nil {
+		t.Fatalf("Second Reconcile failed: %v", err)
+	}
+	if !report.InSync() || len(api.calls) != 0 {
+		t.Errorf("Expected no changes on the second run, got %v", api.calls)
+	}
+}
+
+// slowDestinations widens the window between reading and creating destinations, and
+// guards the fake's state so concurrent callers are all recorded
+type slowDestinations struct {
+	*fakeSubscriptionAPI
+	mu *sync.Mutex
+}
+
+func (s slowDestinations) GetDestinations() ([]ebay.Destination, error) {
+	s.mu.Lock()
+	destinations, err := s.fakeSubscriptionAPI.GetDestinations()
+	s.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	return destinations, err
+}
+
+func (s slowDestinations) CreateDestination(dest ebay.Destination) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fakeSubscriptionAPI.CreateDestination(dest)
+}
+
+func TestAccountsReconcilingAtOnceShareOneDestination(t *testing.T) {
+	api := slowDestinations{&fakeSubscriptionAPI{}, &sync.Mutex{}}
+
+	var wg sync.WaitGroup
+	for _, account := range []string{"main", "vintage"} {
+		reconciler := NewReconciler(api, testDesired)
+		reconciler.SetAccount(account)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := reconciler.Reconcile(false); err != nil {
+				t.Errorf("Reconcile failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(api.destinations) != 1 {
+		t.Errorf("Expected one destination for the app, got %d", len(api.destinations))
+	}
+}
+
+func TestReconcileFixesDrift(t *testing.T) {
+	api := &fakeSubscriptionAPI{
+		destinations: []ebay.Destination{
+			{DestinationID: "old", Status: ebay.StatusEnabled, DeliveryConfig: ebay.DeliveryConfig{Endpoint: "https://old.example.com/hook"}},
+			{DestinationID: "ours", Status: ebay.StatusDisabled, DeliveryConfig: ebay.DeliveryConfig{Endpoint: testDesired.Endpoint}},
+		},
+		subscriptions: []ebay.NotificationSubscription{
+			{SubscriptionID: "offer", TopicID: "MARKETPLACE_OFFER", DestinationID: "ours", Status: ebay.StatusDisabled},
+			{SubscriptionID: "order", TopicID: "MARKETPLACE_ORDER", DestinationID: "old", Status: ebay.StatusEnabled},
+			{SubscriptionID: "inventory", TopicID: "ITEM_INVENTORY", DestinationID: "ours", Status: ebay.StatusEnabled},
+			{SubscriptionID: "deletion", TopicID: "MARKETPLACE_ACCOUNT_DELETION", DestinationID: "ours", Status: ebay.StatusEnabled},
+			{SubscriptionID: "elsewhere", TopicID: "AUTHORIZATION_REVOCATION", DestinationID: "old", Status: ebay.StatusEnabled},
+		},
+	}
+
+	// A dry run reports the drift without touching anything
+	report, err := NewReconciler(api, testDesired).Reconcile(true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(report.Changes) != 4 || len(api.calls) != 0 {
+		t.Fatalf("Expected 4 differences and no calls, got %+v and %v", report.Changes, api.calls)
+	}
+	if !strings.Contains(report.Summary(), "dry-run") {
+		t.Errorf("Expected the dry run summary to mention dry-run, got %q", report.Summary())
+	}
+
+	if _, err := NewReconciler(api, testDesired).Reconcile(false); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	want := "update destination ours,delete deletion,enable offer,update order"
+	if got := strings.Join(api.calls, ","); got != want {
+		t.Errorf("Calls = %q, want %q", got, want)
+	}
+	for _, sub := range api.subscriptions {
+		if sub.SubscriptionID == "elsewhere" {
+			if sub.DestinationID != "old" {
+				t.Errorf("Subscriptions on other destinations must be left alone, got %+v", sub)
+			}
+			continue
+		}
+		if sub.DestinationID != "ours" || sub.Status != ebay.StatusEnabled {
+			t.Errorf("Expected an enabled subscription to ours, got %+v", sub)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"log"
+	"strings"
+
+	"ebaymanager-bot/internal/store"
+)
+
+// destination is a channel a notification is delivered to, with the roles to mention
+type destination struct {
+	channelID string
+	roleIDs   []string
+}
+
+// notificationFacts are the fields routing rules match against
+type notificationFacts struct {
+	eventType string
+	amount    float64
+	hasAmount bool
+	skus      []string
+	buyer     string
+	account   string
+}
+
+// destinations returns every channel whose rules match the notification. When no
+// rule matches, the account's channel or the default notification channel is used.
+func (s *Server) destinations(notification *EbayNotification) []destination {
+	var routes []store.Route
+	if s.store != nil {
+		var err error
+		if routes, err = s.store.ListRoutes(); err != nil {
+			log.Printf("⚠️ Failed to load routing rules, using default channel: %v", err)
+		}
+	}
+
+	facts := factsFor(notification)
+	byChannel := make(map[string]*destination)
+	var order []string
+	for _, route := range routes {
+		if !routeMatches(route, facts) {
+			continue
+		}
+		dest, ok := byChannel[route.ChannelID]
+		if !ok {
+			dest = &destination{channelID: route.ChannelID}
+			byChannel[route.ChannelID] = dest
+			order = append(order, route.ChannelID)
+		}
+		if route.MentionRoleID != "" && !containsString(dest.roleIDs, route.MentionRoleID) {
+			dest.roleIDs = append(dest.roleIDs, route.MentionRoleID)
+		}
+	}
+
+	if len(order) == 0 {
+		channelID := s.defaultChannel(notification)
+		if channelID == "" {
+			return nil
+		}
+		return []destination{{channelID: channelID}}
+	}
+
+	dests := make([]destination, 0, len(order))
+	for _, id := range order {
+		dests = append(dests, *byChannel[id])
+	}
+	return dests
+}
+
+// routeMatches reports whether every criterion set on route holds for the notification
+func routeMatches(route store.Route, facts notificationFacts) bool {
+	if route.Account != "" && !strings.EqualFold(facts.account, route.Account) {
+		return false
+	}
+	if route.EventType != "" && !contains(facts.eventType, route.EventType) {
+		return false
+	}
+	if route.MinAmount > 0 && (!facts.hasAmount || facts.amount < route.MinAmount) {
+		return false
+	}
+	if route.Buyer != "" && !strings.EqualFold(facts.buyer, route.Buyer) {
+		return false
+	}
+	if route.SKUPrefix != "" {
+		matched := false
+		for _, sku := range facts.skus {
+			if strings.HasPrefix(strings.ToUpper(sku), strings.ToUpper(route.SKUPrefix)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// factsFor extracts the routable fields from a notification's typed payload
+func factsFor(notification *EbayNotification) notificationFacts {
+	facts := notificationFacts{eventType: notification.EventType(), account: notification.Account}
+
+	switch {
+	case notification.Order != nil:
+		facts.amount, facts.hasAmount = notification.Order.TotalPrice.Float()
+		facts.buyer = notification.Order.BuyerUsername
+		facts.skus = notification.Order.SKUs
+	case notification.Offer != nil:
+		facts.amount, facts.hasAmount = notification.Offer.OfferPrice.Float()
+		facts.buyer = notification.Offer.BuyerUsername
+		if notification.Offer.SKU != "" {
+			facts.skus = []string{notification.Offer.SKU}
+		}
+	case notification.Inventory != nil:
+		if notification.Inventory.SKU != "" {
+			facts.skus = []string{notification.Inventory.SKU}
+		}
+	}
+
+	return facts
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
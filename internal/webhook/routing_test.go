@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+
+	"ebaymanager-bot/internal/store"
+)
+
+func TestRouteMatches(t *testing.T) {
+	bigSale := factsFor(&EbayNotification{
+		Topic: TopicOrder,
+		Event: OrderPlaced,
+		Order: &OrderEvent{
+			TotalPrice:    Amount{Value: "612.50", Currency: "USD"},
+			BuyerUsername: "BigSpender",
+			SKUs:          []string{"CAM-001"},
+		},
+	})
+
+	tests := []struct {
+		name  string
+		route store.Route
+		want  bool
+	}{
+		{"no criteria", store.Route{}, true},
+		{"event type", store.Route{EventType: "order"}, true},
+		{"other event type", store.Route{EventType: "OFFER"}, false},
+		{"amount above minimum", store.Route{EventType: "ORDER", MinAmount: 500}, true},
+		{"amount below minimum", store.Route{MinAmount: 1000}, false},
+		{"sku prefix", store.Route{SKUPrefix: "cam-"}, true},
+		{"other sku prefix", store.Route{SKUPrefix: "LENS"}, false},
+		{"buyer", store.Route{Buyer: "bigspender"}, true},
+		{"other buyer", store.Route{Buyer: "someone"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeMatches(tt.route, bigSale); got != tt.want {
+				t.Errorf("routeMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDestinations(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	repo.SaveRoute(&store.Route{ChannelID: "offers", EventType: "OFFER"})
+	repo.SaveRoute(&store.Route{ChannelID: "sales", EventType: "ORDER"})
+	repo.SaveRoute(&store.Route{ChannelID: "big-sales", EventType: "ORDER", MinAmount: 500, MentionRoleID: "role-1"})
+
+	server := NewServer(nil, "default", "token", "0")
+	server.SetStore(repo)
+
+	order := &EbayNotification{
+		Topic: TopicOrder,
+		Event: OrderPlaced,
+		Order: &OrderEvent{TotalPrice: Amount{Value: "750.00"}},
+	}
+	dests := server.destinations(order)
+	if len(dests) != 2 || dests[0].channelID != "sales" || dests[1].channelID != "big-sales" {
+		t.Fatalf("Expected sales and big-sales, got %+v", dests)
+	}
+	if len(dests[1].roleIDs) != 1 || dests[1].roleIDs[0] != "role-1" {
+		t.Errorf("Expected big-sales to mention role-1, got %v", dests[1].roleIDs)
+	}
+
+	other := &EbayNotification{Topic: TopicInventory, Inventory: &InventoryEvent{SKU: "CAM-001"}}
+	if dests := server.destinations(other); len(dests) != 1 || dests[0].channelID != "default" {
+		t.Errorf("Expected unmatched notification to use the default channel, got %+v", dests)
+	}
+}
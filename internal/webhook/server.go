@@ -1,26 +1,70 @@
 package webhook
 
 import (
-	"crypto/hmac"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/metrics"
+	"ebaymanager-bot/internal/store"
+
 	"github.com/bwmarrin/discordgo"
 )
 
+// notificationPath is the endpoint eBay delivers subscribed notifications to
+const notificationPath = "/webhook/ebay/notification"
+
 // Server handles incoming eBay webhook notifications
 type Server struct {
 	discord     *discordgo.Session
 	channelID   string
 	verifyToken string
 	port        string
+	store       store.Repository
+
+	verifier         *SignatureVerifier
+	strictSignatures bool // reject notifications without a valid signature
+
+	dispatcher *Dispatcher
+	orders     OrderFetcher // enriches order notifications with full order details
+	ebayStatus EbayStatus   // token and API state for /readyz
+
+	offerComponents func(account, offerID string) []discordgo.MessageComponent
+	simulator       *SimulatorKey // signs /webhook-simulate notifications
+
+	publicURL   *url.URL               // notification endpoint as registered with eBay
+	reconcilers map[string]*Reconciler // keep each account's subscriptions in line with the config
+	oauth       *OAuthStates           // pending /ebay-authorize requests
+
+	accounts        *ebay.Accounts    // seller accounts notifications are attributed to
+	accountChannels map[string]string // default channel per account
+
+	discordEvents EventFilter // events posted to Discord; empty means all
+	sinks         []Sink      // other consumers of events
+
+	tlsCertFile string
+	tlsKeyFile  string
+	httpServer  *http.Server
+	background  sync.WaitGroup // work that outlives a request (purges, token exchanges)
+
+	mu                sync.RWMutex
+	lastDelivery      time.Time            // last real notification received from eBay
+	accountDeliveries map[string]time.Time // the same per seller account, loaded as needed
+
+	duplicates atomic.Uint64 // duplicate deliveries suppressed since start
+	started    time.Time
 }
 
 // NewServer creates a new webhook server
@@ -30,24 +74,243 @@ func NewServer(discord *discordgo.Session, channelID, verifyToken, port string)
 		channelID:   channelID,
 		verifyToken: verifyToken,
 		port:        port,
+		oauth:       NewOAuthStates(oauthStateTTL),
+		started:     time.Now(),
 	}
 }
 
-// Start begins listening for webhook notifications
-func (s *Server) Start() error {
-	http.HandleFunc("/webhook/ebay/notification", s.handleNotification)
-	http.HandleFunc("/webhook/ebay/challenge", s.handleChallenge)
-	http.HandleFunc("/webhook/health", s.handleHealth)
+// SetStore sets the local store used to record received notifications
+func (s *Server) SetStore(repo store.Repository) {
+	s.store = repo
+
+	if mark, err := repo.GetPollMark(webhookConfirmedKey); err == nil {
+		if t, err := time.Parse(time.RFC3339, mark.Value); err == nil {
+			s.mu.Lock()
+			s.lastDelivery = t
+			s.mu.Unlock()
+		}
+	}
+}
+
+// SetSignatureVerifier enables ECDSA verification of X-EBAY-SIGNATURE headers.
+// In strict mode unsigned or unverifiable notifications are rejected.
+func (s *Server) SetSignatureVerifier(verifier *SignatureVerifier, strict bool) {
+	s.verifier = verifier
+	s.strictSignatures = strict
+	s.trustSimulator()
+}
+
+// SetDispatcher routes Discord messages through a persistent delivery queue
+// instead of sending them directly
+func (s *Server) SetDispatcher(dispatcher *Dispatcher) {
+	s.dispatcher = dispatcher
+}
+
+// SetOfferComponents sets the buttons attached to offer notifications the seller can
+// still respond to. They are built by the bot, which handles the button presses.
+func (s *Server) SetOfferComponents(build func(account, offerID string) []discordgo.MessageComponent) {
+	s.offerComponents = build
+}
+
+// SetPublicURL sets the notification endpoint URL registered with eBay. Challenge
+// responses are computed from it rather than the Host header, which a reverse proxy
+// may rewrite.
+func (s *Server) SetPublicURL(publicURL string) error {
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid public URL %q: must be absolute, e.g. https://yourdomain.com%s", publicURL, notificationPath)
+	}
+	s.publicURL = u
+	return nil
+}
+
+// PublicBaseURL returns the scheme and host of the public URL, or "" when none is set
+func (s *Server) PublicBaseURL() string {
+	if s.publicURL == nil {
+		return ""
+	}
+	return s.publicURL.Scheme + "://" + s.publicURL.Host
+}
+
+// challengeEndpoint returns the endpoint URL eBay hashes into its challenge for r:
+// the public URL for the notification endpoint, the public URL's host for other
+// endpoints, and the Host header when no public URL is set
+func (s *Server) challengeEndpoint(r *http.Request) string {
+	if s.publicURL == nil {
+		return "https://" + r.Host + r.URL.Path
+	}
+	if r.URL.Path == notificationPath {
+		return s.publicURL.String()
+	}
+	return s.PublicBaseURL() + r.URL.Path
+}
+
+// SetTLS serves HTTPS directly from the given certificate and key files
+// instead of relying on a reverse proxy for TLS
+func (s *Server) SetTLS(certFile, keyFile string) {
+	s.tlsCertFile = certFile
+	s.tlsKeyFile = keyFile
+}
+
+// LastWebhookDelivery returns when eBay last delivered a notification for any account
+// (zero if never)
+func (s *Server) LastWebhookDelivery() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastDelivery
+}
+
+// LastAccountDelivery returns when eBay last delivered a notification attributed to
+// account (zero if never)
+func (s *Server) LastAccountDelivery(account string) time.Time {
+	s.mu.RLock()
+	last, ok := s.accountDeliveries[account]
+	s.mu.RUnlock()
+	if ok || s.store == nil {
+		return last
+	}
+
+	if mark, err := s.store.GetPollMark(accountDeliveryKey(account)); err == nil {
+		last, _ = time.Parse(time.RFC3339, mark.Value)
+	}
+	s.mu.Lock()
+	if s.accountDeliveries == nil {
+		s.accountDeliveries = make(map[string]time.Time)
+	}
+	s.accountDeliveries[account] = last
+	s.mu.Unlock()
+	return last
+}
+
+// accountDeliveryKey names the poll mark recording account's last delivery
+func accountDeliveryKey(account string) string {
+	return webhookConfirmedKey + ":" + account
+}
+
+// recordDelivery notes that a verified notification arrived, so the poller of the account
+// it is for can switch off. Account deletions concern the app rather than a seller and
+// confirm no account's subscriptions.
+func (s *Server) recordDelivery(notification *EbayNotification) {
+	now := time.Now()
+	keys := []string{webhookConfirmedKey}
+	account := ""
+	if !notification.IsAccountDeletion() {
+		s.attribute(notification)
+		account = firstNonEmpty(notification.Account, s.defaultAccount())
+		keys = append(keys, accountDeliveryKey(account))
+	}
+
+	s.mu.Lock()
+	s.lastDelivery = now
+	if account != "" {
+		if s.accountDeliveries == nil {
+			s.accountDeliveries = make(map[string]time.Time)
+		}
+		s.accountDeliveries[account] = now
+	}
+	s.mu.Unlock()
+
+	if s.store == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := s.store.SavePollMark(key, now.Format(time.RFC3339)); err != nil {
+			log.Printf("⚠️ Failed to record webhook delivery: %v", err)
+		}
+	}
+}
+
+// Handler returns the server's routes with request body limits applied
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(notificationPath, s.handleNotification)
+	mux.HandleFunc("/webhook/ebay/challenge", s.handleChallenge)
+	mux.HandleFunc(accountDeletionPath, s.handleAccountDeletion)
+	mux.HandleFunc("/webhook/health", s.handleHealth)
+	mux.HandleFunc("/healthz", s.handleLiveness)
+	mux.HandleFunc("/readyz", s.handleReadiness)
+	mux.Handle("/metrics", metrics.Handler())
 
 	// Setup OAuth callback handlers
-	s.SetupOAuthHandlers()
+	s.SetupOAuthHandlers(mux)
 
+	return limitBody(mux, maxBodyBytes)
+}
+
+// Start begins listening for webhook notifications. It returns nil once Shutdown is called.
+func (s *Server) Start() error {
 	addr := ":" + s.port
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	s.mu.Lock()
+	s.httpServer = srv
+	s.mu.Unlock()
+	s.oauth.StartSweeper()
+
+	scheme := "http"
+	if s.tlsCertFile != "" {
+		scheme = "https"
+	}
 	log.Printf("🎣 Webhook server starting on %s", addr)
-	log.Printf("📍 Notification endpoint: http://localhost%s/webhook/ebay/notification", addr)
-	log.Printf("📍 Challenge endpoint: http://localhost%s/webhook/ebay/challenge", addr)
+	log.Printf("📍 Notification endpoint: %s://localhost%s/webhook/ebay/notification", scheme, addr)
+	log.Printf("📍 Challenge endpoint: %s://localhost%s/webhook/ebay/challenge", scheme, addr)
+	log.Printf("📍 Account deletion endpoint: %s://localhost%s%s", scheme, addr, accountDeletionPath)
+	log.Printf("📍 Health endpoints: %s://localhost%s/healthz, /readyz", scheme, addr)
+	log.Printf("📍 Metrics: %s://localhost%s/metrics", scheme, addr)
+
+	var err error
+	if s.tlsCertFile != "" {
+		err = srv.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
 
-	return http.ListenAndServe(addr, nil)
+// Shutdown stops accepting requests, waits for in-flight requests and background
+// work started by them, and gives up when ctx ends
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.RLock()
+	srv := s.httpServer
+	s.mu.RUnlock()
+	defer s.oauth.StopSweeper()
+
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			return fmt.Errorf("webhook server shutdown: %w", err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background work still running: %w", ctx.Err())
+	}
+}
+
+// goBackground runs fn in a goroutine that Shutdown waits for
+func (s *Server) goBackground(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
 }
 
 // handleChallenge responds to eBay's endpoint verification challenge
@@ -70,12 +333,7 @@ func (s *Server) handleChallenge(w http.ResponseWriter, r *http.Request) {
 
 	// Construct full endpoint URL for hash calculation
 	// eBay expects: SHA256(challengeCode + verificationToken + endpointUrl)
-	scheme := "https" // Always use https for eBay challenge verification
-	host := r.Host
-	if host == "" {
-		host = "jacob.it.com"
-	}
-	endpointURL := fmt.Sprintf("%s://%s%s", scheme, host, r.URL.Path)
+	endpointURL := s.challengeEndpoint(r)
 
 	log.Printf("🔐 Computing challenge response for endpoint: %s", endpointURL)
 	log.Printf("[DEBUG] Webhook Verification Inputs:\n  challengeCode: %s\n  verifyToken: %s\n  endpointURL: %s", challengeCode, s.verifyToken, endpointURL)
@@ -119,12 +377,7 @@ func (s *Server) handleNotification(w http.ResponseWriter, r *http.Request) {
 
 		// Construct full endpoint URL for hash calculation
 		// eBay expects: SHA256(challengeCode + verificationToken + endpointUrl)
-		scheme := "https" // Always use https for eBay challenge verification
-		host := r.Host
-		if host == "" {
-			host = "jacob.it.com"
-		}
-		endpointURL := fmt.Sprintf("%s://%s%s", scheme, host, r.URL.Path)
+		endpointURL := s.challengeEndpoint(r)
 
 		log.Printf("🔐 Computing challenge response for endpoint: %s", endpointURL)
 		log.Printf("[DEBUG] Webhook Verification Inputs:\n  challengeCode: %s\n  verifyToken: %s\n  endpointURL: %s", challengeCode, s.verifyToken, endpointURL)
@@ -156,39 +409,119 @@ func (s *Server) handleNotification(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Read request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Printf("❌ Failed to read notification body: %v", err)
-		http.Error(w, "Failed to read body", http.StatusBadRequest)
+	body, ok := readBody(w, r)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
+	status, message := s.receiveNotification(body, r.Header, false)
+	reply(w, status, message)
+}
 
-	// Verify signature (optional but recommended)
-	signature := r.Header.Get("X-EBAY-SIGNATURE")
-	if signature != "" && !s.verifySignature(body, signature) {
-		log.Printf("❌ Invalid signature for notification")
-		http.Error(w, "Invalid signature", http.StatusUnauthorized)
-		return
+// receiveNotification verifies, records, deduplicates and processes a notification
+// delivered with header, and returns the HTTP status and message to answer with. With
+// deletionOnly set, anything but an account deletion is refused.
+func (s *Server) receiveNotification(body []byte, header http.Header, deletionOnly bool) (int, string) {
+	record := newHistoryRecord(header, body)
+	simulated := header.Get(simulatedHeader) != ""
+
+	// Verify eBay's signature
+	signature, err := s.verifyNotification(body, header.Get("X-EBAY-SIGNATURE"))
+	record.Signature = signature
+	if err != nil {
+		log.Printf("❌ Rejected notification: %v", err)
+		notificationsRejected.Inc(signature)
+		s.saveHistory(record, OutcomeRejected, err)
+		// eBay expects 412 Precondition Failed when verification fails
+		return http.StatusPreconditionFailed, "Signature verification failed"
 	}
 
 	// Parse notification
-	var notification EbayNotification
-	if err := json.Unmarshal(body, &notification); err != nil {
+	notification, err := DecodeNotification(body)
+	if err == nil && deletionOnly && !notification.IsAccountDeletion() {
+		err = fmt.Errorf("unexpected topic %s on the account deletion endpoint", notification.Topic)
+	}
+	if err != nil {
 		log.Printf("❌ Failed to parse notification: %v", err)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
+		s.saveHistory(record, OutcomeInvalid, err)
+		return http.StatusBadRequest, "Invalid notification"
 	}
+	record.NotificationID = notification.NotificationID
+	record.EventType = notification.EventType()
+	record.PublishDate = notification.PublishDate
 
-	log.Printf("📨 Received eBay notification: %s", notification.NotificationEventType)
+	// Account deletions carry personal data: don't keep or post the payload
+	if notification.IsAccountDeletion() {
+		record.Payload = nil
+	}
 
-	// Process and send to Discord
-	go s.processNotification(&notification)
+	// A purge can't be undone, so it needs eBay's signature even outside strict mode
+	if notification.IsAccountDeletion() && signature != signatureVerified {
+		log.Printf("❌ Rejected %s account deletion notification: nothing purged", signature)
+		notificationsRejected.Inc(signature)
+		s.saveHistory(record, OutcomeRejected, errUnverifiedDeletion)
+		return http.StatusPreconditionFailed, "Signature verification failed"
+	}
 
-	// Respond with 200 OK
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintln(w, "OK")
+	log.Printf("📨 Received eBay notification: %s", notification.EventType())
+	if !simulated {
+		// Anyone can post unsigned notifications; only eBay's signature confirms delivery
+		if signature == signatureVerified {
+			s.recordDelivery(notification)
+		}
+		notificationsReceived.Inc(notification.Topic)
+	}
+
+	// eBay retries deliveries; acknowledge repeats without posting them again
+	if s.isDuplicate(notification, body) {
+		notificationDuplicates.Inc(notification.Topic)
+		s.saveHistory(record, OutcomeDuplicate, nil)
+		return http.StatusOK, "OK"
+	}
+
+	source := SourceWebhook
+	if simulated {
+		source = SourceSimulator
+	}
+
+	// A simulated account deletion is a dry run, as its sample buyer may match real data.
+	// One that names no user can't be purged, and a retry wouldn't change that.
+	if notification.IsAccountDeletion() {
+		switch {
+		case notification.AccountDeletion == nil:
+			log.Printf("❌ Account deletion %s names no user (schema version %q): nothing purged", notification.NotificationID, notification.SchemaVersion)
+			s.saveHistory(record, OutcomeInvalid, errDeletionWithoutUser)
+		case source == SourceSimulator:
+			s.saveHistory(record, OutcomeDryRun, nil)
+			log.Printf("🧪 Simulated account deletion %s: nothing purged and no audit recorded", notification.NotificationID)
+		default:
+			s.saveHistory(record, OutcomePurged, nil)
+			s.acceptAccountDeletion(notification)
+		}
+		return http.StatusOK, "OK"
+	}
+
+	// Keep the raw notification before queueing so deliveries can be tracked against it
+	s.saveHistory(record, OutcomeReceived, nil)
+
+	// Queue for Discord before acknowledging so eBay retries if it can't be persisted
+	queued, err := s.process(notification, record.ID, source)
+	s.setOutcome(record.ID, queued, err)
+	if err != nil {
+		log.Printf("❌ Failed to queue notification: %v", err)
+		s.forgetDelivery(notification, body)
+		return http.StatusInternalServerError, "Failed to queue notification"
+	}
+	return http.StatusOK, "OK"
+}
+
+// reply answers a request with status and a plain-text message
+func reply(w http.ResponseWriter, status int, message string) {
+	if status != http.StatusOK {
+		http.Error(w, message, status)
+		return
+	}
+	w.WriteHeader(status)
+	fmt.Fprintln(w, message)
 }
 
 // handleHealth provides a health check endpoint
@@ -197,116 +530,237 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "OK")
 }
 
-// verifySignature verifies the eBay notification signature
-func (s *Server) verifySignature(body []byte, signature string) bool {
-	mac := hmac.New(sha256.New, []byte(s.verifyToken))
-	mac.Write(body)
-	expectedMAC := mac.Sum(nil)
-	expectedSignature := base64.StdEncoding.EncodeToString(expectedMAC)
-	return hmac.Equal([]byte(signature), []byte(expectedSignature))
+// Signature verification outcomes
+const (
+	signatureVerified   = "verified"
+	signatureUnsigned   = "unsigned"
+	signatureUnverified = "unverified" // no verifier configured
+	signatureInvalid    = "invalid"
+)
+
+// verifyNotification checks the X-EBAY-SIGNATURE header and returns the outcome.
+// A non-nil error means the notification must be rejected.
+func (s *Server) verifyNotification(body []byte, header string) (string, error) {
+	if s.verifier == nil {
+		if s.strictSignatures {
+			return signatureUnverified, fmt.Errorf("strict signature mode is on but no verifier is configured")
+		}
+		return signatureUnverified, nil
+	}
+
+	err := s.verifier.Verify(body, header)
+	switch {
+	case err == nil:
+		return signatureVerified, nil
+	case errors.Is(err, ErrMissingSignature):
+		if s.strictSignatures {
+			return signatureUnsigned, err
+		}
+		log.Printf("⚠️ Accepting unsigned notification (strict signature mode is off)")
+		return signatureUnsigned, nil
+	default:
+		return signatureInvalid, err
+	}
+}
+
+// processNotification builds the Discord embed and queues it for every channel whose
+// routing rules match, then publishes the event to the other sinks. Without a dispatcher
+// the embed is sent directly in the background.
+func (s *Server) processNotification(notification *EbayNotification) error {
+	_, err := s.process(notification, "", SourcePoller)
+	return err
+}
+
+// process is processNotification for a notification kept in history under recordID
+// (empty if it isn't) and detected by source. It returns how many channels the
+// notification was queued for.
+func (s *Server) process(notification *EbayNotification, recordID, source string) (int, error) {
+	s.attribute(notification)
+	event := NewEvent(notification, source)
+
+	queued := 0
+	if event == nil || s.discordEvents.Match(event.Type) {
+		var err error
+		if queued, err = s.postToDiscord(notification, recordID, source); err != nil {
+			return 0, err
+		}
+	} else {
+		log.Printf("🔕 Not posting %s to Discord (filtered by DISCORD_EVENTS)", event.Type)
+	}
+
+	// Simulated events only exercise Discord; the other sinks reach outside systems
+	switch {
+	case event == nil:
+	case source == SourceSimulator:
+		log.Printf("🧪 Not publishing simulated %s to the event sinks", event.Type)
+	default:
+		s.publish(event)
+	}
+	return queued, nil
 }
 
-// processNotification handles the notification and sends to Discord
-func (s *Server) processNotification(notification *EbayNotification) {
-	if s.channelID == "" {
+// postToDiscord queues the notification's embed for every matching channel. Simulated
+// orders are posted on their own rather than joining the order's live message and thread.
+func (s *Server) postToDiscord(notification *EbayNotification, recordID, source string) (int, error) {
+	details := s.orderDetails(notification)
+
+	dests := s.destinations(notification)
+	if len(dests) == 0 {
 		log.Println("⚠️ No Discord channel configured for notifications")
-		return
+		return 0, nil
 	}
 
-	embed := s.buildDiscordEmbed(notification)
+	embed := s.buildDiscordEmbed(notification, details)
+	components := s.componentsFor(notification)
+	tracked := source != SourceSimulator && s.trackOrder(notification, embed)
+
+	for _, dest := range dests {
+		if s.dispatcher != nil {
+			out := Outgoing{
+				ChannelID:      dest.channelID,
+				EventType:      notification.EventType(),
+				RoleIDs:        dest.roleIDs,
+				Embed:          embed,
+				Components:     components,
+				NotificationID: recordID,
+			}
+			if tracked {
+				out.OrderID = notification.Order.OrderID
+				out.ThreadNote = orderThreadNote(notification.Event, notification.Order)
+			}
+			if err := s.dispatcher.Enqueue(out); err != nil {
+				return 0, err
+			}
+			continue
+		}
 
-	_, err := s.discord.ChannelMessageSendEmbed(s.channelID, embed)
-	if err != nil {
-		log.Printf("❌ Failed to send Discord notification: %v", err)
-		return
+		dest := dest
+		s.goBackground(func() {
+			send := messageWithMentions(embed, dest.roleIDs)
+			send.Components = components
+			_, err := s.discord.ChannelMessageSendComplex(dest.channelID, send)
+			observeSend(err)
+			if err != nil {
+				log.Printf("❌ Failed to send Discord notification: %v", err)
+				return
+			}
+			log.Printf("✅ Notification sent to Discord channel %s", dest.channelID)
+		})
+	}
+	return len(dests), nil
+}
+
+// componentsFor returns the buttons to send with a notification, if any
+func (s *Server) componentsFor(notification *EbayNotification) []discordgo.MessageComponent {
+	if s.offerComponents == nil || !offerAwaitingResponse(notification) {
+		return nil
 	}
+	// Buttons only need to name the account when there is more than one to choose from
+	account := ""
+	if s.multiAccount() {
+		account = notification.Account
+	}
+	return s.offerComponents(account, notification.Offer.OfferID)
+}
 
-	log.Printf("✅ Notification sent to Discord channel %s", s.channelID)
+// offerAwaitingResponse reports whether the seller can still accept, counter or decline the offer
+func offerAwaitingResponse(notification *EbayNotification) bool {
+	if notification.Offer == nil || notification.Offer.OfferID == "" {
+		return false
+	}
+	switch notification.Event {
+	case OfferCreated, OfferCountered, OfferUpdated:
+		return true
+	}
+	return false
 }
 
-// buildDiscordEmbed creates a rich embed for the Discord notification
-func (s *Server) buildDiscordEmbed(notification *EbayNotification) *discordgo.MessageEmbed {
+// buildDiscordEmbed creates a rich embed for the Discord notification. details is the
+// full order for order notifications, or nil when it could not be fetched.
+func (s *Server) buildDiscordEmbed(notification *EbayNotification, details *ebay.Order) *discordgo.MessageEmbed {
 	embed := &discordgo.MessageEmbed{
 		Title:     "🔔 eBay Notification",
 		Timestamp: time.Now().Format(time.RFC3339),
 		Color:     0x0099ff,
 	}
 
-	// Parse notification type (eBay uses format: MARKETPLACE_ACCOUNT.ORDER.FULFILLED)
-	notifType := notification.NotificationEventType
-
-	// Handle ORDER notifications
-	if contains(notifType, "ORDER") {
-		s.handleOrderNotification(notification, embed)
-	} else if contains(notifType, "OFFER") {
-		s.handleOfferNotification(notification, embed)
-	} else {
-		// Generic notification
+	switch {
+	case notification.Order != nil:
+		s.handleOrderNotification(notification.Event, notification.Order, details, embed)
+	case notification.Offer != nil:
+		s.handleOfferNotification(notification.Event, notification.Offer, embed)
+	case notification.Inventory != nil:
+		s.handleInventoryNotification(notification.Event, notification.Inventory, embed)
+	default:
 		s.handleGenericNotification(notification, embed)
 	}
+	s.labelAccount(notification, embed)
 
 	return embed
 }
 
 // handleOrderNotification processes order-related notifications
-func (s *Server) handleOrderNotification(notification *EbayNotification, embed *discordgo.MessageEmbed) {
-	notifType := notification.NotificationEventType
-
-	// Determine specific order event
-	if contains(notifType, "FULFILLED") || contains(notifType, "PLACED") {
+func (s *Server) handleOrderNotification(event string, order *OrderEvent, details *ebay.Order, embed *discordgo.MessageEmbed) {
+	switch event {
+	case OrderPlaced, OrderFulfilled:
 		embed.Color = 0x00ff00
 		embed.Title = "🎉 New Order Received!"
 		embed.Description = "You have a new sale!"
-	} else if contains(notifType, "PAID") || contains(notifType, "PAYMENT") {
+	case OrderPaid:
 		embed.Color = 0x00ff00
 		embed.Title = "💵 Payment Received"
 		embed.Description = "Payment has been confirmed for an order"
-	} else if contains(notifType, "SHIPPED") {
+	case OrderShipped:
 		embed.Color = 0x3498db
 		embed.Title = "📦 Order Shipped"
 		embed.Description = "An order has been marked as shipped"
-	} else {
+	case OrderDelivered:
+		embed.Color = 0x2ecc71
+		embed.Title = "📬 Order Delivered"
+		embed.Description = "The buyer has received their order"
+	case OrderRefunded:
+		embed.Color = 0xe67e22
+		embed.Title = "💸 Order Refunded"
+		embed.Description = "A refund has been issued for an order"
+	case OrderCancelled:
+		embed.Color = 0xff0000
+		embed.Title = "🚫 Order Cancelled"
+		embed.Description = "An order has been cancelled"
+	default:
 		embed.Color = 0x0099ff
 		embed.Title = "📋 Order Update"
 		embed.Description = "Order status has changed"
 	}
 
-	// Extract order details from metadata
-	if notification.Metadata != nil {
-		if orderId, ok := notification.Metadata["orderId"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "📦 Order ID",
-				Value:  orderId,
-				Inline: true,
-			})
-		}
-		if buyer, ok := notification.Metadata["buyerUsername"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "👤 Buyer",
-				Value:  buyer,
-				Inline: true,
-			})
-		}
-		if price, ok := notification.Metadata["totalPrice"].(float64); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "💰 Total",
-				Value:  fmt.Sprintf("$%.2f", price),
-				Inline: true,
-			})
-		} else if priceStr, ok := notification.Metadata["totalPrice"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "💰 Total",
-				Value:  priceStr,
-				Inline: true,
-			})
-		}
-		if itemTitle, ok := notification.Metadata["itemTitle"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "📦 Item",
-				Value:  itemTitle,
-				Inline: false,
-			})
-		}
+	if order.OrderID != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "📦 Order ID",
+			Value:  order.OrderID,
+			Inline: true,
+		})
+	}
+	if order.BuyerUsername != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "👤 Buyer",
+			Value:  order.BuyerUsername,
+			Inline: true,
+		})
+	}
+	if order.TotalPrice.Value != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "💰 Total",
+			Value:  order.TotalPrice.String(),
+			Inline: true,
+		})
+	}
+	if details != nil {
+		addOrderDetails(embed, details)
+	} else if order.ItemTitle != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "📦 Item",
+			Value:  order.ItemTitle,
+			Inline: false,
+		})
 	}
 
 	embed.Footer = &discordgo.MessageEmbedFooter{
@@ -315,127 +769,141 @@ func (s *Server) handleOrderNotification(notification *EbayNotification, embed *
 }
 
 // handleOfferNotification processes offer-related notifications
-func (s *Server) handleOfferNotification(notification *EbayNotification, embed *discordgo.MessageEmbed) {
-	notifType := notification.NotificationEventType
-
-	// Determine specific offer event
-	if contains(notifType, "CREATED") {
+func (s *Server) handleOfferNotification(event string, offer *OfferEvent, embed *discordgo.MessageEmbed) {
+	switch event {
+	case OfferCreated:
 		embed.Color = 0xffaa00
 		embed.Title = "💬 New Offer Received!"
 		embed.Description = "A buyer has submitted an offer on your listing"
-	} else if contains(notifType, "ACCEPTED") {
+	case OfferAccepted:
 		embed.Color = 0x00ff00
 		embed.Title = "✅ Offer Accepted"
 		embed.Description = "An offer has been accepted"
-	} else if contains(notifType, "DECLINED") {
+	case OfferDeclined:
 		embed.Color = 0xff0000
 		embed.Title = "❌ Offer Declined"
 		embed.Description = "An offer has been declined"
-	} else if contains(notifType, "COUNTERED") || contains(notifType, "UPDATED") {
+	case OfferCountered, OfferUpdated:
 		embed.Color = 0xffaa00
 		embed.Title = "💬 Offer Updated"
 		embed.Description = "An offer has been countered or updated"
-	} else if contains(notifType, "EXPIRED") {
+	case OfferExpired:
 		embed.Color = 0x808080
 		embed.Title = "⏰ Offer Expired"
 		embed.Description = "An offer has expired"
-	} else {
+	default:
 		embed.Color = 0xffaa00
 		embed.Title = "💬 Offer Update"
 		embed.Description = "Offer status has changed"
 	}
 
-	// Extract offer details from metadata
-	if notification.Metadata != nil {
-		if offerId, ok := notification.Metadata["offerId"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "🆔 Offer ID",
-				Value:  fmt.Sprintf("`%s`", offerId),
-				Inline: false,
-			})
-		}
-		if buyer, ok := notification.Metadata["buyerUsername"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "👤 Buyer",
-				Value:  buyer,
-				Inline: true,
-			})
-		}
-		if offerPrice, ok := notification.Metadata["offerPrice"].(float64); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "💰 Offer Amount",
-				Value:  fmt.Sprintf("$%.2f", offerPrice),
-				Inline: true,
-			})
-		} else if offerPriceStr, ok := notification.Metadata["offerPrice"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "💰 Offer Amount",
-				Value:  offerPriceStr,
-				Inline: true,
-			})
-		}
-		if listPrice, ok := notification.Metadata["listPrice"].(float64); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "🏷️ List Price",
-				Value:  fmt.Sprintf("$%.2f", listPrice),
-				Inline: true,
-			})
-		}
-		if itemTitle, ok := notification.Metadata["itemTitle"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "📦 Item",
-				Value:  itemTitle,
-				Inline: false,
-			})
-		}
-		if itemId, ok := notification.Metadata["itemId"].(string); ok {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "🔗 Item ID",
-				Value:  itemId,
-				Inline: true,
-			})
-		}
+	if offer.OfferID != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "🆔 Offer ID",
+			Value:  fmt.Sprintf("`%s`", offer.OfferID),
+			Inline: false,
+		})
+	}
+	if offer.BuyerUsername != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "👤 Buyer",
+			Value:  offer.BuyerUsername,
+			Inline: true,
+		})
+	}
+	if offer.OfferPrice.Value != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "💰 Offer Amount",
+			Value:  offer.OfferPrice.String(),
+			Inline: true,
+		})
+	}
+	if offer.ListPrice.Value != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "🏷️ List Price",
+			Value:  offer.ListPrice.String(),
+			Inline: true,
+		})
+	}
+	if offer.ItemTitle != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "📦 Item",
+			Value:  offer.ItemTitle,
+			Inline: false,
+		})
+	}
+	if offer.ItemID != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "🔗 Item ID",
+			Value:  offer.ItemID,
+			Inline: true,
+		})
 	}
 
-	// Add action buttons guidance if it's a new/updated offer
-	if contains(notifType, "CREATED") || contains(notifType, "UPDATED") {
-		if offerId, ok := notification.Metadata["offerId"].(string); ok {
-			embed.Footer = &discordgo.MessageEmbedFooter{
-				Text: fmt.Sprintf("💡 Respond with: /accept-offer, /counter-offer, or /decline-offer using offer ID: %s", offerId),
-			}
+	// Without buttons, point at the slash commands instead
+	if s.offerComponents == nil && (event == OfferCreated || event == OfferUpdated) && offer.OfferID != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("💡 Respond with: /accept-offer, /counter-offer, or /decline-offer using offer ID: %s", offer.OfferID),
 		}
 	}
 }
 
-// handleGenericNotification processes other notification types
-func (s *Server) handleGenericNotification(notification *EbayNotification, embed *discordgo.MessageEmbed) {
-	notifType := notification.NotificationEventType
-
-	if contains(notifType, "ITEM_SOLD") {
-		embed.Color = 0x00ff00
-		embed.Title = "💰 Item Sold!"
-		embed.Description = "You have a new sale!"
-	} else if contains(notifType, "LISTING_ENDED") {
+// handleInventoryNotification processes ITEM_INVENTORY notifications
+func (s *Server) handleInventoryNotification(event string, item *InventoryEvent, embed *discordgo.MessageEmbed) {
+	embed.Color = 0x9b59b6
+	embed.Title = "📦 Inventory Update"
+	embed.Description = "Stock level changed for a listing"
+	switch {
+	case event == InventoryListingEnded:
+		embed.Color = 0x95a5a6
+		embed.Title = "🏁 Listing Ended"
+		embed.Description = "A listing is no longer active"
+	case item.Quantity == 0:
 		embed.Color = 0xff6600
-		embed.Title = "⏰ Listing Ended"
-		embed.Description = "One of your listings has ended"
+		embed.Title = "⚠️ Out of Stock"
+		embed.Description = "A listing has sold out"
+	}
 
-	} else {
-		embed.Description = fmt.Sprintf("Event: %s", notification.NotificationEventType)
+	if item.Title != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "📦 Item",
+			Value:  item.Title,
+			Inline: false,
+		})
 	}
+	if item.SKU != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "🏷️ SKU",
+			Value:  item.SKU,
+			Inline: true,
+		})
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   "🔢 Quantity",
+		Value:  fmt.Sprintf("%d", item.Quantity),
+		Inline: true,
+	})
+}
 
-	// Add any remaining metadata as fields
-	if notification.Metadata != nil {
-		for key, value := range notification.Metadata {
-			// Skip already displayed fields
-			if key == "orderId" || key == "offerId" || key == "buyerUsername" ||
-				key == "totalPrice" || key == "offerPrice" || key == "listPrice" ||
-				key == "itemTitle" || key == "itemId" {
-				continue
+// handleGenericNotification processes topics without a typed payload
+func (s *Server) handleGenericNotification(notification *EbayNotification, embed *discordgo.MessageEmbed) {
+	embed.Description = fmt.Sprintf("Event: %s", notification.EventType())
+
+	// Show the top-level data fields as they were received
+	var data map[string]interface{}
+	if err := json.Unmarshal(notification.Data, &data); err == nil {
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if len(embed.Fields) == 25 { // Discord's limit
+				break
 			}
 			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
 				Name:   key,
-				Value:  fmt.Sprintf("%v", value),
+				Value:  fmt.Sprintf("%v", data[key]),
 				Inline: true,
 			})
 		}
@@ -446,12 +914,3 @@ func (s *Server) handleGenericNotification(notification *EbayNotification, embed
 func contains(s, substr string) bool {
 	return strings.Contains(strings.ToUpper(s), strings.ToUpper(substr))
 }
-
-// EbayNotification represents an eBay webhook notification
-type EbayNotification struct {
-	NotificationEventType string                 `json:"notificationEventType"`
-	NotificationId        string                 `json:"notificationId"`
-	PublishDate           string                 `json:"publishDate"`
-	Metadata              map[string]interface{} `json:"metadata"`
-	Notification          map[string]interface{} `json:"notification"` // Alternative structure
-}
@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChallengeEndpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicURL string
+		target    string
+		want      string
+	}{
+		{"host header", "", "http://internal:8081/webhook/ebay/notification", "https://internal:8081/webhook/ebay/notification"},
+		{"notification", "https://bot.example.com/hooks/ebay", "http://internal:8081/webhook/ebay/notification", "https://bot.example.com/hooks/ebay"},
+		{"other path", "https://bot.example.com/hooks/ebay", "http://internal:8081/webhook/ebay/account-deletion", "https://bot.example.com/webhook/ebay/account-deletion"},
+	}
+
+	for _, tt := range tests {
+		server := NewServer(nil, "", "verify-token", "0")
+		if tt.publicURL != "" {
+			if err := server.SetPublicURL(tt.publicURL); err != nil {
+				t.Fatalf("%s: SetPublicURL failed: %v", tt.name, err)
+			}
+		}
+		if got := server.challengeEndpoint(httptest.NewRequest("GET", tt.target, nil)); got != tt.want {
+			t.Errorf("%s: challengeEndpoint() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetPublicURLRejectsRelative(t *testing.T) {
+	server := NewServer(nil, "", "verify-token", "0")
+	for _, u := range []string{"", "bot.example.com/webhook", "/webhook/ebay/notification"} {
+		if err := server.SetPublicURL(u); err == nil {
+			t.Errorf("Expected %q to be rejected", u)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+)
+
+const (
+	// publicKeyTTL is how long a fetched eBay public key is trusted before re-fetching
+	publicKeyTTL = time.Hour
+
+	// publicKeyFailureTTL is how long a kid eBay couldn't return a key for is refused
+	// without asking again
+	publicKeyFailureTTL = 5 * time.Minute
+
+	// publicKeyFetchLimit caps key lookups per publicKeyFetchWindow, as any unauthenticated
+	// POST with a new kid would otherwise make the bot call eBay
+	publicKeyFetchLimit  = 10
+	publicKeyFetchWindow = time.Minute
+)
+
+// ErrMissingSignature is returned when a notification has no X-EBAY-SIGNATURE header
+var ErrMissingSignature = errors.New("missing X-EBAY-SIGNATURE header")
+
+// PublicKeyFetcher looks up eBay notification public keys by key ID
+type PublicKeyFetcher interface {
+	GetNotificationPublicKey(kid string) (*ebay.NotificationPublicKey, error)
+}
+
+// signatureHeader is the decoded X-EBAY-SIGNATURE header
+type signatureHeader struct {
+	Alg       string `json:"alg"`
+	Kid       string `json:"kid"`
+	Signature string `json:"signature"`
+	Digest    string `json:"digest"`
+}
+
+type cachedKey struct {
+	key       *ecdsa.PublicKey
+	digest    string
+	fetchedAt time.Time
+}
+
+// SignatureVerifier checks eBay notification signatures (ECDSA over the raw body)
+// against public keys from the Notification API, caching keys by kid
+type SignatureVerifier struct {
+	fetcher PublicKeyFetcher
+
+	mu          sync.Mutex
+	keys        map[string]cachedKey
+	failed      map[string]time.Time        // kids whose lookup failed, by when
+	trusted     map[string]*ecdsa.PublicKey // local keys (the simulator's) that are never fetched
+	windowStart time.Time                   // start of the current lookup rate window
+	fetches     int                         // lookups made in the current window
+}
+
+// NewSignatureVerifier creates a verifier that fetches keys with fetcher
+func NewSignatureVerifier(fetcher PublicKeyFetcher) *SignatureVerifier {
+	return &SignatureVerifier{
+		fetcher: fetcher,
+		keys:    make(map[string]cachedKey),
+		failed:  make(map[string]time.Time),
+		trusted: make(map[string]*ecdsa.PublicKey),
+	}
+}
+
+// Trust accepts signatures made with key under kid without asking eBay for it
+func (v *SignatureVerifier) Trust(kid string, key *ecdsa.PublicKey) {
+	v.mu.Lock()
+	v.trusted[kid] = key
+	v.mu.Unlock()
+}
+
+// Verify checks header (the raw X-EBAY-SIGNATURE value) against body
+func (v *SignatureVerifier) Verify(body []byte, header string) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	sig, err := decodeSignatureHeader(header)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sig.Alg, "ECDSA") {
+		return fmt.Errorf("unsupported signature algorithm %q", sig.Alg)
+	}
+
+	key, err := v.publicKey(sig.Kid)
+	if err != nil {
+		return err
+	}
+
+	digestName := sig.Digest
+	if digestName == "" {
+		digestName = key.digest
+	}
+	h, err := newDigest(digestName)
+	if err != nil {
+		return err
+	}
+	h.Write(body)
+
+	rawSig, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("signature is not valid base64: %w", err)
+	}
+	if !ecdsa.VerifyASN1(key.key, h.Sum(nil), rawSig) {
+		return fmt.Errorf("signature does not match payload (kid %s)", sig.Kid)
+	}
+
+	return nil
+}
+
+// publicKey returns the cached key for kid, fetching it when missing or stale
+func (v *SignatureVerifier) publicKey(kid string) (cachedKey, error) {
+	if kid == "" {
+		return cachedKey{}, fmt.Errorf("signature header has no kid")
+	}
+
+	v.mu.Lock()
+	trusted := v.trusted[kid]
+	cached, ok := v.keys[kid]
+	failedAt, failed := v.failed[kid]
+	v.mu.Unlock()
+	if trusted != nil {
+		return cachedKey{key: trusted, digest: "SHA256"}, nil
+	}
+	if ok && time.Since(cached.fetchedAt) < publicKeyTTL {
+		return cached, nil
+	}
+	if failed && time.Since(failedAt) < publicKeyFailureTTL {
+		publicKeyLookups.Inc("cached_failure")
+		return cachedKey{}, fmt.Errorf("public key %s could not be fetched recently, not asking again yet", kid)
+	}
+	if !v.allowFetch(time.Now()) {
+		publicKeyLookups.Inc("rate_limited")
+		return cachedKey{}, fmt.Errorf("too many public key lookups, not fetching %s", kid)
+	}
+
+	key, digest, err := v.fetchPublicKey(kid)
+	if err != nil {
+		v.recordFailure(kid)
+		return cachedKey{}, err
+	}
+
+	cached = cachedKey{key: key, digest: digest, fetchedAt: time.Now()}
+	v.mu.Lock()
+	v.keys[kid] = cached
+	delete(v.failed, kid)
+	v.mu.Unlock()
+
+	return cached, nil
+}
+
+// fetchPublicKey asks eBay for the key kid and its digest, recording the lookup's metrics
+func (v *SignatureVerifier) fetchPublicKey(kid string) (key *ecdsa.PublicKey, digest string, err error) {
+	start := time.Now()
+	defer func() { observeKeyFetch(start, err) }()
+
+	resp, err := v.fetcher.GetNotificationPublicKey(kid)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch public key %s: %w", kid, err)
+	}
+	if key, err = parsePublicKey(resp.Key); err != nil {
+		return nil, "", fmt.Errorf("invalid public key %s: %w", kid, err)
+	}
+	return key, resp.Digest, nil
+}
+
+// allowFetch reserves a key lookup if fewer than publicKeyFetchLimit were made in the
+// current window
+func (v *SignatureVerifier) allowFetch(now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.windowStart) >= publicKeyFetchWindow {
+		v.windowStart = now
+		v.fetches = 0
+	}
+	if v.fetches >= publicKeyFetchLimit {
+		return false
+	}
+	v.fetches++
+	return true
+}
+
+// recordFailure remembers that kid couldn't be fetched, dropping failures old enough
+// to be retried so the map stays small
+func (v *SignatureVerifier) recordFailure(kid string) {
+	now := time.Now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for k, at := range v.failed {
+		if now.Sub(at) >= publicKeyFailureTTL {
+			delete(v.failed, k)
+		}
+	}
+	v.failed[kid] = now
+}
+
+// decodeSignatureHeader decodes the base64 JSON X-EBAY-SIGNATURE header
+func decodeSignatureHeader(header string) (*signatureHeader, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(header))
+	if err != nil {
+		return nil, fmt.Errorf("signature header is not valid base64: %w", err)
+	}
+
+	var sig signatureHeader
+	if err := json.Unmarshal(raw, &sig); err != nil {
+		return nil, fmt.Errorf("signature header is not valid JSON: %w", err)
+	}
+	if sig.Signature == "" {
+		return nil, fmt.Errorf("signature header has no signature")
+	}
+
+	return &sig, nil
+}
+
+// parsePublicKey parses the PEM key returned by getPublicKey. eBay sometimes returns
+// the PEM on a single line, so the armor is stripped and the body decoded directly.
+func parsePublicKey(pemKey string) (*ecdsa.PublicKey, error) {
+	body := strings.NewReplacer(
+		"-----BEGIN PUBLIC KEY-----", "",
+		"-----END PUBLIC KEY-----", "",
+		"\n", "",
+		"\r", "",
+		" ", "",
+	).Replace(pemKey)
+
+	der, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64: %w", err)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key is %T, not ECDSA", pub)
+	}
+
+	return ecKey, nil
+}
+
+// newDigest returns the hash named by eBay's digest field
+func newDigest(name string) (hash.Hash, error) {
+	switch strings.ToUpper(strings.ReplaceAll(name, "-", "")) {
+	case "SHA1", "":
+		return sha1.New(), nil
+	case "SHA256":
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported signature digest %q", name)
+	}
+}
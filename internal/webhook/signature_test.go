@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/ebay"
+)
+
+type fakeKeyFetcher struct {
+	key   *ecdsa.PrivateKey
+	calls int
+}
+
+func (f *fakeKeyFetcher) GetNotificationPublicKey(kid string) (*ebay.NotificationPublicKey, error) {
+	f.calls++
+	if kid != "test-kid" {
+		return nil, errors.New("unknown kid")
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+	return &ebay.NotificationPublicKey{
+		Algorithm: "ECDSA",
+		Digest:    "SHA1",
+		Key:       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
+
+func signBody(t *testing.T, key *ecdsa.PrivateKey, kid string, body []byte) string {
+	t.Helper()
+	digest := sha1.Sum(body)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	header, _ := json.Marshal(map[string]string{
+		"alg":       "ECDSA",
+		"kid":       kid,
+		"signature": base64.StdEncoding.EncodeToString(sig),
+		"digest":    "SHA1",
+	})
+	return base64.StdEncoding.EncodeToString(header)
+}
+
+func newTestVerifier(t *testing.T) (*SignatureVerifier, *fakeKeyFetcher) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	fetcher := &fakeKeyFetcher{key: key}
+	return NewSignatureVerifier(fetcher), fetcher
+}
+
+func TestVerifyValidSignature(t *testing.T) {
+	v, fetcher := newTestVerifier(t)
+	body := []byte(`{"metadata":{"topic":"MARKETPLACE_ORDER"}}`)
+	header := signBody(t, fetcher.key, "test-kid", body)
+
+	if err := v.Verify(body, header); err != nil {
+		t.Fatalf("Expected valid signature, got %v", err)
+	}
+	if err := v.Verify(body, header); err != nil {
+		t.Fatalf("Expected valid signature on second call, got %v", err)
+	}
+	if fetcher.calls != 1 {
+		t.Errorf("Expected public key to be cached, fetched %d times", fetcher.calls)
+	}
+}
+
+func TestVerifyRejectsTamperedBody(t *testing.T) {
+	v, fetcher := newTestVerifier(t)
+	header := signBody(t, fetcher.key, "test-kid", []byte(`{"amount":10}`))
+
+	if err := v.Verify([]byte(`{"amount":1000}`), header); err == nil {
+		t.Error("Expected tampered body to fail verification")
+	}
+}
+
+func TestVerifyRejectsMalformedHeaders(t *testing.T) {
+	v, fetcher := newTestVerifier(t)
+	body := []byte(`{}`)
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"Not base64", "%%%"},
+		{"Not JSON", base64.StdEncoding.EncodeToString([]byte("nope"))},
+		{"Unknown kid", signBody(t, fetcher.key, "other-kid", body)},
+		{"Old HMAC style", base64.StdEncoding.EncodeToString([]byte("hmac-bytes"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := v.Verify(body, tt.header); err == nil {
+				t.Error("Expected verification error")
+			}
+		})
+	}
+
+	if err := v.Verify(body, ""); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("Expected ErrMissingSignature, got %v", err)
+	}
+}
+
+func TestPublicKeyLookupsAreLimited(t *testing.T) {
+	v, fetcher := newTestVerifier(t)
+	body := []byte(`{}`)
+
+	// A kid eBay doesn't know is only asked for once
+	for i := 0; i < 3; i++ {
+		if err := v.Verify(body, signBody(t, fetcher.key, "unknown-kid", body)); err == nil {
+			t.Fatal("Expected an unknown kid to fail")
+		}
+	}
+	if fetcher.calls != 1 {
+		t.Errorf("Expected the failed lookup to be cached, fetched %d times", fetcher.calls)
+	}
+
+	// Fresh kids on every request can't make unbounded calls to eBay
+	for i := 0; i < 2*publicKeyFetchLimit; i++ {
+		v.Verify(body, signBody(t, fetcher.key, fmt.Sprintf("random-%d", i), body))
+	}
+	if fetcher.calls != publicKeyFetchLimit {
+		t.Errorf("Expected %d lookups in the window, got %d", publicKeyFetchLimit, fetcher.calls)
+	}
+
+	// The next window allows lookups again
+	v.windowStart = time.Now().Add(-publicKeyFetchWindow)
+	if err := v.Verify(body, signBody(t, fetcher.key, "test-kid", body)); err != nil {
+		t.Errorf("Expected a valid signature in the next window, got %v", err)
+	}
+}
+
+func TestStrictModeRejectsUnsigned(t *testing.T) {
+	v, _ := newTestVerifier(t)
+	server := NewServer(nil, "", "token", "0")
+
+	server.SetSignatureVerifier(v, false)
+	if _, err := server.verifyNotification([]byte(`{}`), ""); err != nil {
+		t.Errorf("Unsigned notification should be accepted outside strict mode, got %v", err)
+	}
+
+	server.SetSignatureVerifier(v, true)
+	if status, err := server.verifyNotification([]byte(`{}`), ""); err == nil || status != signatureUnsigned {
+		t.Errorf("Unsigned notification should be rejected in strict mode, got status=%s err=%v", status, err)
+	}
+}
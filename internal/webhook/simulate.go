@@ -0,0 +1,380 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// simulatedHeader marks simulator requests so they don't count as deliveries from eBay
+const simulatedHeader = "X-Simulated-Notification"
+
+// SimulatedEvent is a synthetic notification the simulator can send
+type SimulatedEvent struct {
+	Name        string // e.g. order-paid
+	Description string
+	Topic       string
+	data        func(s sample) interface{}
+}
+
+// SimulatedEvents lists every event the simulator can build
+var SimulatedEvents = []SimulatedEvent{
+	{"order-placed", "New order", TopicOrder, func(s sample) interface{} { return s.order(OrderPlaced) }},
+	{"order-paid", "Payment received", TopicOrder, func(s sample) interface{} { return s.order(OrderPaid) }},
+	{"order-shipped", "Order shipped", TopicOrder, func(s sample) interface{} { return s.order(OrderShipped) }},
+	{"offer-created", "New offer from a buyer", TopicOffer, func(s sample) interface{} { return s.offer(OfferCreated, 0.85) }},
+	{"offer-countered", "Buyer countered an offer", TopicOffer, func(s sample) interface{} { return s.offer(OfferCountered, 0.92) }},
+	{"offer-expired", "Offer expired", TopicOffer, func(s sample) interface{} { return s.offer(OfferExpired, 0.85) }},
+	{"listing-ended", "Listing ended", TopicInventory, func(s sample) interface{} {
+		return InventoryEvent{EventType: InventoryListingEnded, ItemID: s.itemID, SKU: s.item.sku, Title: s.item.title}
+	}},
+	{"account-deletion", "Marketplace account deletion", TopicAccountDeletion, func(s sample) interface{} {
+		return AccountDeletionEvent{Username: s.buyer, UserID: "sim-user-" + s.digits(8), EiasToken: "sim" + s.digits(16)}
+	}},
+}
+
+// SimulatedEventNames returns the names accepted by BuildSimulatedNotification
+func SimulatedEventNames() []string {
+	names := make([]string, len(SimulatedEvents))
+	for i, e := range SimulatedEvents {
+		names[i] = e.Name
+	}
+	return names
+}
+
+// sampleItem is a listing used in simulated notifications
+type sampleItem struct {
+	title string
+	sku   string
+	price float64
+}
+
+var (
+	sampleItems = []sampleItem{
+		{"Canon EOS R6 Mirrorless Camera Body", "CAM-R6-BODY", 1499.00},
+		{"Apple iPad Air 5th Gen 64GB Wi-Fi Space Gray", "TAB-IPAD-AIR5", 429.99},
+		{"Nintendo Switch OLED Console White", "GAME-SWITCH-OLED", 289.95},
+		{"Sony WH-1000XM5 Wireless Headphones Black", "AUD-XM5-BLK", 279.00},
+		{"Lego Star Wars Millennium Falcon 75257 Sealed", "TOY-LEGO-75257", 139.99},
+	}
+	sampleBuyers = []string{"vintage_finds_88", "camera.collector", "bargainhunter_tx", "retro-gamer-uk", "audiophile_jen"}
+)
+
+// sample is the randomly chosen buyer and item behind one simulated notification
+type sample struct {
+	rng    *mathrand.Rand
+	item   sampleItem
+	buyer  string
+	itemID string
+}
+
+func newSample() sample {
+	rng := mathrand.New(mathrand.NewSource(time.Now().UnixNano()))
+	s := sample{
+		rng:   rng,
+		item:  sampleItems[rng.Intn(len(sampleItems))],
+		buyer: sampleBuyers[rng.Intn(len(sampleBuyers))],
+	}
+	s.itemID = "3" + s.digits(11)
+	return s
+}
+
+// digits returns n random decimal digits
+func (s sample) digits(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('0' + s.rng.Intn(10))
+	}
+	return string(b)
+}
+
+func (s sample) order(event string) OrderEvent {
+	return OrderEvent{
+		EventType:     event,
+		OrderID:       fmt.Sprintf("%s-%s-%s", s.digits(2), s.digits(5), s.digits(5)),
+		BuyerUsername: s.buyer,
+		TotalPrice:    usd(s.item.price),
+		ItemTitle:     s.item.title,
+		SKUs:          []string{s.item.sku},
+	}
+}
+
+func (s sample) offer(event string, fraction float64) OfferEvent {
+	return OfferEvent{
+		EventType:     event,
+		OfferID:       s.digits(12),
+		BuyerUsername: s.buyer,
+		ItemID:        s.itemID,
+		ItemTitle:     s.item.title,
+		SKU:           s.item.sku,
+		OfferPrice:    usd(s.item.price * fraction),
+		ListPrice:     usd(s.item.price),
+	}
+}
+
+func usd(value float64) Amount {
+	return Amount{Value: fmt.Sprintf("%.2f", value), Currency: "USD"}
+}
+
+// BuildSimulatedNotification returns a Notification API payload for the named event,
+// with a fresh notification ID and randomly chosen buyer, item and IDs
+func BuildSimulatedNotification(event string) ([]byte, error) {
+	return buildSimulatedNotification(event, "")
+}
+
+// buildSimulatedNotification is BuildSimulatedNotification for the seller with eBay user
+// ID sellerID, or for no particular seller when it is empty
+func buildSimulatedNotification(event, sellerID string) ([]byte, error) {
+	var sim *SimulatedEvent
+	for i := range SimulatedEvents {
+		if SimulatedEvents[i].Name == event {
+			sim = &SimulatedEvents[i]
+		}
+	}
+	if sim == nil {
+		return nil, fmt.Errorf("unknown event %q (one of %s)", event, strings.Join(SimulatedEventNames(), ", "))
+	}
+
+	s := newSample()
+	data := sim.data(s)
+	if sellerID != "" && sim.Topic != TopicAccountDeletion {
+		data = withUserID(data, sellerID)
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"topic":         sim.Topic,
+			"schemaVersion": "1.0",
+			"deprecated":    false,
+		},
+		"notification": map[string]interface{}{
+			"notificationId":      fmt.Sprintf("sim-%s-%s", s.digits(8), s.digits(4)),
+			"eventDate":           now,
+			"publishDate":         now,
+			"publishAttemptCount": 1,
+			"data":                data,
+		},
+	})
+}
+
+// SimulatorKey signs simulated notifications the way eBay signs real ones
+type SimulatorKey struct {
+	kid string
+	key *ecdsa.PrivateKey
+}
+
+// NewSimulatorKey generates a P-256 signing key that lives only as long as the process
+func NewSimulatorKey() (*SimulatorKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate simulator key: %w", err)
+	}
+	return newSimulatorKey(key)
+}
+
+// LoadSimulatorKey reads a PEM ECDSA private key, as written by SimulatorKey.Save
+func LoadSimulatorKey(path string) (*SimulatorKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read simulator key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("simulator key %s is not PEM", path)
+	}
+
+	var key *ecdsa.PrivateKey
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed interface{}
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if k, ok := parsed.(*ecdsa.PrivateKey); ok {
+			key = k
+		} else if err == nil {
+			err = fmt.Errorf("key is %T, not ECDSA", parsed)
+		}
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulator key %s: %w", path, err)
+	}
+	return newSimulatorKey(key)
+}
+
+// newSimulatorKey derives the kid from the public key so every holder of a key file agrees on it
+func newSimulatorKey(key *ecdsa.PrivateKey) (*SimulatorKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return &SimulatorKey{kid: "simulator-" + hex.EncodeToString(sum[:6]), key: key}, nil
+}
+
+// Save writes the private key to path, readable only by the owner
+func (k *SimulatorKey) Save(path string) error {
+	der, err := x509.MarshalECPrivateKey(k.key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+}
+
+// KID returns the key ID put in signature headers
+func (k *SimulatorKey) KID() string {
+	return k.kid
+}
+
+// Sign returns an X-EBAY-SIGNATURE header for body
+func (k *SimulatorKey) Sign(body []byte) (string, error) {
+	digest := sha256.Sum256(body)
+	sig, err := ecdsa.SignASN1(rand.Reader, k.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign notification: %w", err)
+	}
+	header, err := json.Marshal(signatureHeader{
+		Alg:       "ECDSA",
+		Kid:       k.kid,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+		Digest:    "SHA256",
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(header), nil
+}
+
+// newSimulatedRequest builds the POST for a simulated notification, signed when key is set
+func newSimulatedRequest(url string, body []byte, key *SimulatorKey) (*http.Request, error) {
+	header, err := simulatedHeaders(body, key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	return req, nil
+}
+
+// simulatedHeaders returns the headers of a simulated notification, signed when key is set
+func simulatedHeaders(body []byte, key *SimulatorKey) (http.Header, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(simulatedHeader, "true")
+	if key != nil {
+		signature, err := key.Sign(body)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("X-EBAY-SIGNATURE", signature)
+	}
+	return header, nil
+}
+
+// PostSimulatedNotification POSTs body to a running notification endpoint, signed
+// with key unless it is nil, and returns the HTTP status
+func PostSimulatedNotification(url string, body []byte, key *SimulatorKey) (int, error) {
+	req, err := newSimulatedRequest(url, body, key)
+	if err != nil {
+		return 0, err
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return resp.StatusCode, simulatedResult(resp.StatusCode, reply)
+}
+
+// simulatedResult turns a non-2xx answer from the endpoint into an error
+func simulatedResult(status int, reply []byte) error {
+	if status < 200 || status > 299 {
+		return fmt.Errorf("endpoint answered %d: %s", status, strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
+
+// EnableSimulator lets SimulateNotification sign with key, and trusts key's signatures
+func (s *Server) EnableSimulator(key *SimulatorKey) {
+	s.simulator = key
+	s.trustSimulator()
+}
+
+// trustSimulator registers the simulator key with the signature verifier once both are set
+func (s *Server) trustSimulator() {
+	if s.simulator != nil && s.verifier != nil {
+		s.verifier.Trust(s.simulator.kid, &s.simulator.key.PublicKey)
+	}
+}
+
+// SimulateNotification builds a synthetic notification for event and hands it to the
+// notification endpoint's handling in-process, so it is verified, recorded, routed and
+// posted exactly like a delivery from eBay. The notification is for account's seller (the default
+// account when empty). It returns the HTTP status the endpoint answered with.
+func (s *Server) SimulateNotification(event, account string, signed bool) (int, error) {
+	sellerID, err := s.simulatedSeller(account)
+	if err != nil {
+		return 0, err
+	}
+	body, err := buildSimulatedNotification(event, sellerID)
+	if err != nil {
+		return 0, err
+	}
+
+	var key *SimulatorKey
+	if signed {
+		if s.simulator == nil {
+			return 0, fmt.Errorf("the simulator has no signing key")
+		}
+		key = s.simulator
+	}
+	header, err := simulatedHeaders(body, key)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("🧪 Simulating %s notification%s (signed: %t)", event, forAccount(account), signed)
+	status, message := s.receiveNotification(body, header, false)
+	return status, simulatedResult(status, []byte(message))
+}
+
+// simulatedSeller returns the eBay user ID a simulated notification for account carries.
+// Only the default account can be simulated before its seller is identified: the others
+// could not be told apart.
+func (s *Server) simulatedSeller(account string) (string, error) {
+	if s.accounts == nil {
+		return "", nil
+	}
+	client, err := s.accounts.Get(account)
+	if err != nil {
+		return "", err
+	}
+	if client.UserID() == "" && client != s.accounts.Default() {
+		return "", fmt.Errorf("account %s has no eBay user ID yet - run /ebay-authorize for it first", client.Account())
+	}
+	return client.UserID(), nil
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ebaymanager-bot/internal/store"
+)
+
+func TestBuildSimulatedNotification(t *testing.T) {
+	tests := []struct {
+		event    string
+		wantType string
+	}{
+		{"order-placed", "MARKETPLACE_ORDER.PLACED"},
+		{"order-paid", "MARKETPLACE_ORDER.PAID"},
+		{"order-shipped", "MARKETPLACE_ORDER.SHIPPED"},
+		{"offer-created", "MARKETPLACE_OFFER.CREATED"},
+		{"offer-countered", "MARKETPLACE_OFFER.COUNTERED"},
+		{"offer-expired", "MARKETPLACE_OFFER.EXPIRED"},
+		{"listing-ended", "ITEM_INVENTORY.LISTING_ENDED"},
+		{"account-deletion", "MARKETPLACE_ACCOUNT_DELETION"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			body, err := BuildSimulatedNotification(tt.event)
+			if err != nil {
+				t.Fatalf("BuildSimulatedNotification failed: %v", err)
+			}
+			n, err := DecodeNotification(body)
+			if err != nil {
+				t.Fatalf("Simulated payload does not decode: %v", err)
+			}
+			if n.EventType() != tt.wantType || n.NotificationID == "" {
+				t.Errorf("Got %s (id %q), want %s", n.EventType(), n.NotificationID, tt.wantType)
+			}
+		})
+	}
+
+	if len(SimulatedEvents) != len(tests) {
+		t.Errorf("Expected a test case for each of the %d simulated events", len(SimulatedEvents))
+	}
+	if _, err := BuildSimulatedNotification("order-exploded"); err == nil {
+		t.Error("Expected an unknown event to fail")
+	}
+}
+
+func TestSimulateNotification(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	verifier, _ := newTestVerifier(t)
+	server := NewServer(nil, "sales", "token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(&fakeSender{}, repo, 1))
+	server.SetSignatureVerifier(verifier, true)
+
+	if _, err := server.SimulateNotification("offer-created", "", true); err == nil {
+		t.Error("Expected signing to fail without a simulator key")
+	}
+
+	key, err := NewSimulatorKey()
+	if err != nil {
+		t.Fatalf("NewSimulatorKey failed: %v", err)
+	}
+	server.EnableSimulator(key)
+
+	status, err := server.SimulateNotification("offer-created", "", true)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Signed simulation = %d, %v", status, err)
+	}
+	stored, _ := repo.ListNotifications(1)
+	if len(stored) != 1 || stored[0].Signature != signatureVerified || stored[0].Outcome != OutcomeQueued {
+		t.Errorf("Expected a verified, queued notification in history, got %+v", stored)
+	}
+	if !server.LastWebhookDelivery().IsZero() {
+		t.Error("Simulated notifications must not count as deliveries from eBay")
+	}
+
+	// Strict mode rejects unsigned notifications, simulated or not
+	if status, err := server.SimulateNotification("order-paid", "", false); err == nil || status != http.StatusPreconditionFailed {
+		t.Errorf("Unsigned simulation in strict mode = %d, %v", status, err)
+	}
+}
+
+func TestSimulatedNotificationsAreDryRun(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	for i, buyer := range sampleBuyers {
+		repo.UpsertOrder(&store.Order{OrderID: fmt.Sprintf("real-%d", i), BuyerUsername: buyer})
+	}
+
+	verifier, _ := newTestVerifier(t)
+	server := NewServer(nil, "sales", "token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(&fakeSender{}, repo, 1))
+	server.SetSignatureVerifier(verifier, true)
+	sink := &fakeSink{}
+	server.AddSink(sink)
+	key, err := NewSimulatorKey()
+	if err != nil {
+		t.Fatalf("NewSimulatorKey failed: %v", err)
+	}
+	server.EnableSimulator(key)
+
+	tests := []struct {
+		event       string
+		wantOutcome string
+	}{
+		{"order-paid", OutcomeQueued},
+		{"offer-created", OutcomeQueued},
+		{"account-deletion", OutcomeDryRun},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			if status, err := server.SimulateNotification(tt.event, "", true); err != nil || status != http.StatusOK {
+				t.Fatalf("Simulation = %d, %v", status, err)
+			}
+			stored, _ := repo.ListNotifications(1)
+			if len(stored) != 1 || stored[0].Outcome != tt.wantOutcome {
+				t.Fatalf("Expected outcome %s in history, got %+v", tt.wantOutcome, stored)
+			}
+			if tt.event != "order-paid" {
+				return
+			}
+			n, err := DecodeNotification(stored[0].Payload)
+			if err != nil {
+				t.Fatalf("Stored payload does not decode: %v", err)
+			}
+			timeline, _ := repo.AppendOrderStatus(n.Order.OrderID, "CHECK", time.Now())
+			if len(timeline) != 1 {
+				t.Errorf("Simulated order joined an order timeline: %+v", timeline)
+			}
+		})
+	}
+
+	if len(sink.events) != 0 {
+		t.Errorf("Simulated events must not reach the event sinks, got %d", len(sink.events))
+	}
+	if audits, _ := repo.ListDeletionAudits(0); len(audits) != 0 {
+		t.Errorf("Simulated account deletion recorded %d audits", len(audits))
+	}
+	for i, buyer := range sampleBuyers {
+		if order, _ := repo.GetOrder(fmt.Sprintf("real-%d", i)); order == nil || order.BuyerUsername != buyer {
+			t.Errorf("Simulated account deletion purged order %d: %+v", i, order)
+		}
+	}
+}
+
+func TestReplayedSimulationStaysDryRun(t *testing.T) {
+	repo, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewServer(nil, "sales", "token", "0")
+	server.SetStore(repo)
+	server.SetDispatcher(NewDispatcher(&fakeSender{}, repo, 1))
+	sink := &fakeSink{}
+	server.AddSink(sink)
+
+	if status, err := server.SimulateNotification("order-paid", "", false); err != nil || status != http.StatusOK {
+		t.Fatalf("Simulation = %d, %v", status, err)
+	}
+	stored, _ := repo.ListNotifications(1)
+	if len(stored) != 1 {
+		t.Fatalf("Expected the simulation in history, got %d records", len(stored))
+	}
+	if queued, err := server.ReplayNotification(stored[0].ID); err != nil || queued != 1 {
+		t.Fatalf("ReplayNotification = %d, %v", queued, err)
+	}
+
+	if len(sink.events) != 0 {
+		t.Errorf("A replayed simulation reached the event sinks: %+v", sink.events)
+	}
+	n, _ := DecodeNotification(stored[0].Payload)
+	if timeline, _ := repo.AppendOrderStatus(n.Order.OrderID, "CHECK", time.Now()); len(timeline) != 1 {
+		t.Errorf("A replayed simulation joined an order timeline: %+v", timeline)
+	}
+}
+
+func TestSimulatorKeyFile(t *testing.T) {
+	key, err := NewSimulatorKey()
+	if err != nil {
+		t.Fatalf("NewSimulatorKey failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "simulator.pem")
+	if err := key.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadSimulatorKey(path)
+	if err != nil {
+		t.Fatalf("LoadSimulatorKey failed: %v", err)
+	}
+	if loaded.KID() != key.KID() {
+		t.Errorf("Loaded kid %s, want %s", loaded.KID(), key.KID())
+	}
+
+	// A key loaded from the file verifies against the same trusted public key
+	verifier, _ := newTestVerifier(t)
+	verifier.Trust(key.KID(), &key.key.PublicKey)
+	body := []byte(`{"metadata":{"topic":"MARKETPLACE_ORDER"}}`)
+	header, err := loaded.Sign(body)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := verifier.Verify(body, header); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
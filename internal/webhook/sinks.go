@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ebaymanager-bot/internal/metrics"
+)
+
+const (
+	// sinkQueueSize is how many events a sink buffers before it starts dropping them
+	sinkQueueSize = 256
+
+	// sinkMaxAttempts is how many times an event is posted before it is given up on
+	sinkMaxAttempts = 5
+
+	sinkRetryBaseDelay = 2 * time.Second
+	sinkRetryMaxDelay  = time.Minute
+	sinkRequestTimeout = 10 * time.Second
+)
+
+var sinkDeliveries = metrics.NewCounterVec("event_sink_deliveries_total",
+	"Events delivered to external sinks by sink and result (success, failed, dropped)", "sink", "result")
+
+// Sink consumes events. Publish must not block: sinks deliver in the background.
+type Sink interface {
+	Name() string
+	Accepts(eventType string) bool
+	Publish(event *Event)
+}
+
+// PostSink delivers events by POSTing them to a URL from a background queue, retrying
+// with backoff. Events that can't be queued or delivered are logged and dropped: the
+// Discord queue is the durable record, sinks are best-effort.
+type PostSink struct {
+	name   string
+	url    string
+	filter EventFilter
+	encode func(*Event) ([]byte, error)
+	secret []byte // signs the body when set
+	client *http.Client
+
+	retryBase time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan *Event
+	abort  chan struct{} // ends retry waits when Close gives up
+	done   chan struct{}
+}
+
+func newPostSink(name, url string, filter EventFilter, encode func(*Event) ([]byte, error)) *PostSink {
+	return &PostSink{
+		name:      name,
+		url:       url,
+		filter:    filter,
+		encode:    encode,
+		client:    &http.Client{Timeout: sinkRequestTimeout},
+		retryBase: sinkRetryBaseDelay,
+		queue:     make(chan *Event, sinkQueueSize),
+		abort:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// NewHTTPSink posts each event as JSON to url, signed with secret (see signEvent)
+func NewHTTPSink(url, secret string, filter EventFilter) *PostSink {
+	s := newPostSink("http", url, filter, func(event *Event) ([]byte, error) {
+		return json.Marshal(event)
+	})
+	s.secret = []byte(secret)
+	return s
+}
+
+// NewSlackSink posts a one-line summary of each event to a Slack incoming webhook
+func NewSlackSink(url string, filter EventFilter) *PostSink {
+	return newPostSink("slack", url, filter, slackMessage)
+}
+
+// Name identifies the sink in logs and metrics
+func (s *PostSink) Name() string {
+	return s.name
+}
+
+// Accepts reports whether the sink's filter selects eventType
+func (s *PostSink) Accepts(eventType string) bool {
+	return s.filter.Match(eventType)
+}
+
+// Publish queues event for delivery, dropping it if the queue is full or closed
+func (s *PostSink) Publish(event *Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		log.Printf("⚠️ %s event sink queue is full, dropping %s %s", s.name, event.Type, event.ID)
+		sinkDeliveries.Inc(s.name, "dropped")
+	}
+}
+
+// Start delivers queued events until Close is called
+func (s *PostSink) Start() {
+	log.Printf("📡 %s event sink started (%s)", s.name, s.filter)
+	go s.run()
+}
+
+// Close stops accepting events and waits for queued ones to be delivered, or ctx to end
+func (s *PostSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		close(s.abort)
+		return fmt.Errorf("%s event sink not drained: %w", s.name, ctx.Err())
+	}
+}
+
+func (s *PostSink) run() {
+	defer close(s.done)
+	for event := range s.queue {
+		s.deliver(event)
+	}
+}
+
+// deliver posts event until it succeeds, fails permanently or runs out of attempts
+func (s *PostSink) deliver(event *Event) {
+	body, err := s.encode(event)
+	if err != nil {
+		log.Printf("❌ Failed to encode %s for %s event sink: %v", event.ID, s.name, err)
+		sinkDeliveries.Inc(s.name, "failed")
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		delay, permanent, err := s.post(event, body, attempt)
+		if err == nil {
+			sinkDeliveries.Inc(s.name, "success")
+			return
+		}
+		if permanent || attempt >= sinkMaxAttempts {
+			log.Printf("❌ %s event sink gave up on %s %s after %d attempt(s): %v", s.name, event.Type, event.ID, attempt, err)
+			sinkDeliveries.Inc(s.name, "failed")
+			return
+		}
+		log.Printf("⚠️ %s event sink delivery of %s failed, retrying in %s: %v", s.name, event.ID, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-s.abort:
+			sinkDeliveries.Inc(s.name, "failed")
+			return
+		}
+	}
+}
+
+// post makes one delivery attempt and, on failure, says how long to wait before the next
+// and whether retrying is pointless
+func (s *PostSink) post(event *Event, body []byte, attempt int) (time.Duration, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, true, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ebaymanager-bot")
+	req.Header.Set("X-Event-ID", event.ID)
+	req.Header.Set("X-Event-Type", event.Type)
+	if len(s.secret) > 0 {
+		req.Header.Set("X-Signature", signEvent(s.secret, time.Now().Unix(), body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return s.backoff(attempt), false, err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	code := resp.StatusCode
+	if code >= 200 && code < 300 {
+		return 0, false, nil
+	}
+	err = fmt.Errorf("%s answered %d: %s", s.name, code, strings.TrimSpace(string(reply)))
+	switch {
+	case code == http.StatusTooManyRequests:
+		if after, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && after > 0 {
+			return time.Duration(after) * time.Second, false, err
+		}
+	case code == http.StatusRequestTimeout:
+	case code >= 400 && code < 500:
+		return 0, true, err
+	}
+	return s.backoff(attempt), false, err
+}
+
+// backoff doubles the wait after every failed attempt
+func (s *PostSink) backoff(attempt int) time.Duration {
+	delay := s.retryBase << (attempt - 1)
+	if delay > sinkRetryMaxDelay || delay <= 0 {
+		delay = sinkRetryMaxDelay
+	}
+	return delay
+}
+
+// signEvent returns the X-Signature header for body: the Unix timestamp and the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with secret, as t=<timestamp>,v1=<hmac>.
+// Signing the timestamp lets receivers reject replays of old deliveries.
+func signEvent(secret []byte, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// slackMessage formats event as a Slack incoming-webhook message
+func slackMessage(event *Event) ([]byte, error) {
+	text := eventSummary(event)
+	return json.Marshal(map[string]interface{}{
+		"text": text,
+		"blocks": []map[string]interface{}{{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": text},
+		}},
+	})
+}
+
+// eventSummary describes event in one line of Slack mrkdwn
+func eventSummary(event *Event) string {
+	var parts []string
+	switch {
+	case event.Order != nil:
+		o := event.Order
+		parts = append(parts, "order `"+o.OrderID+"`")
+		if o.ItemTitle != "" {
+			parts = append(parts, o.ItemTitle)
+		}
+		if o.TotalPrice.Value != "" {
+			parts = append(parts, o.TotalPrice.String())
+		}
+		if o.BuyerUsername != "" {
+			parts = append(parts, "buyer "+o.BuyerUsername)
+		}
+	case event.Offer != nil:
+		o := event.Offer
+		if o.ItemTitle != "" {
+			parts = append(parts, o.ItemTitle)
+		}
+		if o.OfferPrice.Value != "" {
+			price := o.OfferPrice.String()
+			if o.ListPrice.Value != "" {
+				price += " (listed at " + o.ListPrice.String() + ")"
+			}
+			parts = append(parts, price)
+		}
+		if o.BuyerUsername != "" {
+			parts = append(parts, "buyer "+o.BuyerUsername)
+		}
+	case event.Item != nil:
+		i := event.Item
+		if i.Title != "" {
+			parts = append(parts, i.Title)
+		}
+		if i.SKU != "" {
+			parts = append(parts, "SKU `"+i.SKU+"`")
+		}
+	default:
+		parts = append(parts, event.Topic)
+	}
+
+	summary := "*" + event.Type + "*"
+	if len(parts) > 0 {
+		summary += ": " + strings.Join(parts, " · ")
+	}
+	if event.Source != SourceWebhook {
+		summary += " _(" + event.Source + ")_"
+	}
+	return summary
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startTestSink starts sink and closes it when the test ends, with no retry backoff
+func startTestSink(t *testing.T, sink *PostSink) {
+	t.Helper()
+	sink.retryBase = time.Millisecond
+	sink.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		sink.Close(ctx)
+	})
+}
+
+func TestHTTPSinkSignsDeliveries(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, "s3cret", nil)
+	startTestSink(t, sink)
+	sink.Publish(&Event{ID: "n-1", Type: EventOrderPlaced, Order: &OrderEvent{OrderID: "12-345"}})
+
+	var r *http.Request
+	select {
+	case r = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Event was not delivered")
+	}
+	if r.Header.Get("X-Event-Type") != EventOrderPlaced || r.Header.Get("X-Event-ID") != "n-1" {
+		t.Errorf("Unexpected event headers: %v", r.Header)
+	}
+
+	// Verify the signature the way a receiver would
+	var ts, sig string
+	for _, part := range strings.Split(r.Header.Get("X-Signature"), ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	if want := hex.EncodeToString(mac.Sum(nil)); sig != want {
+		t.Errorf("Signature = %q, want %q", sig, want)
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil || event.Order == nil || event.Order.OrderID != "12-345" {
+		t.Errorf("Unexpected body %s: %v", body, err)
+	}
+}
+
+func TestPostSinkRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     int32 // requests made
+	}{
+		{"success", []int{200}, 1},
+		{"server error is retried", []int{500, 502, 200}, 3},
+		{"rate limit is retried", []int{429, 200}, 2},
+		{"client error is permanent", []int{400, 200}, 1},
+		{"gives up after max attempts", []int{500, 500, 500, 500, 500, 500}, sinkMaxAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			sink := NewHTTPSink(srv.URL, "s3cret", nil)
+			sink.retryBase = time.Millisecond
+			sink.Start()
+			sink.Publish(&Event{ID: "n-1", Type: EventOrderPlaced})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := sink.Close(ctx); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if got := calls.Load(); got != tt.want {
+				t.Errorf("Made %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkFilter(t *testing.T) {
+	sink := NewSlackSink("http://slack.invalid", EventFilter{"Order*", "PaymentReceived"})
+	if !sink.Accepts(EventOrderShipped) || !sink.Accepts(EventPaymentReceived) || sink.Accepts(EventOfferReceived) {
+		t.Error("Expected the sink to accept only order and payment events")
+	}
+}
+
+func TestSlackMessage(t *testing.T) {
+	body, err := slackMessage(&Event{
+		Type:   EventOfferReceived,
+		Source: SourcePoller,
+		Offer: &OfferEvent{
+			ItemTitle:     "Vintage Camera",
+			OfferPrice:    Amount{Value: "80.00", Currency: "USD"},
+			ListPrice:     Amount{Value: "100.00", Currency: "USD"},
+			BuyerUsername: "buyer1",
+		},
+	})
+	if err != nil {
+		t.Fatalf("slackMessage: %v", err)
+	}
+
+	var msg struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(body, &msg)
+	want := "*OfferReceived*: Vintage Camera · $80.00 (listed at $100.00) · buyer buyer1 _(poller)_"
+	if msg.Text != want {
+		t.Errorf("Text = %q, want %q", msg.Text, want)
+	}
+}
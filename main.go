@@ -1,21 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"ebaymanager-bot/internal/bot"
 	"ebaymanager-bot/internal/config"
 	"ebaymanager-bot/internal/ebay"
+	"ebaymanager-bot/internal/store"
+	"ebaymanager-bot/internal/syncer"
 	"ebaymanager-bot/internal/webhook"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/joho/godotenv"
 )
 
+// shutdownTimeout bounds how long shutdown waits for in-flight work
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -28,8 +36,46 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize eBay client
-	ebayClient := ebay.NewClient(cfg.EbayConfig)
+	// Open local store
+	repo, err := store.Open(cfg.DataPath)
+	if err != nil {
+		log.Fatalf("Failed to open local store: %v", err)
+	}
+	defer repo.Close()
+	log.Printf("🗄️ Local store: %s", repo.Path())
+
+	// Keep tokens in their own 0600 file, encrypted when a passphrase or key file is set
+	tokenStore, err := ebay.OpenTokenStore(cfg.TokenStorePath, cfg.TokenStorePassphrase, cfg.TokenStoreKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to open token store: %v", err)
+	}
+	log.Printf("🔑 Token store: %s (encrypted: %t)", tokenStore.Path(), tokenStore.Encrypted())
+
+	// Tokens saved before EBAY_ACCOUNTS was set belong to the first account
+	if first := cfg.Accounts[0].Account; first != config.DefaultAccount {
+		if err := tokenStore.RenameAccount(config.DefaultAccount, first); err != nil {
+			log.Fatalf("Failed to move saved tokens to account %s: %v", first, err)
+		}
+	}
+
+	// Initialize one eBay client per seller account; the first is the default
+	accounts, err := ebay.NewAccounts()
+	if err != nil {
+		log.Fatalf("Failed to set up eBay accounts: %v", err)
+	}
+	for _, account := range cfg.Accounts {
+		client := ebay.NewClient(account.EbayConfig)
+		if err := client.SetTokenStore(tokenStore.Account(client.Account())); err != nil {
+			log.Fatalf("Failed to load tokens for account %s: %v", client.Account(), err)
+		}
+		if err := accounts.Add(client); err != nil {
+			log.Fatalf("Failed to set up eBay accounts: %v", err)
+		}
+	}
+	ebayClient := accounts.Default()
+	if accounts.Len() > 1 {
+		log.Printf("🏪 Managing %d eBay accounts: %s", accounts.Len(), strings.Join(accounts.Names(), ", "))
+	}
 
 	// Create Discord session
 	discord, err := discordgo.New("Bot " + cfg.DiscordToken)
@@ -48,18 +94,155 @@ func main() {
 
 	// Start webhook server in background first
 	webhookServer := webhook.NewServer(discord, cfg.NotificationChannelID, cfg.WebhookVerifyToken, cfg.WebhookPort)
-	webhook.SetEbayClient(ebayClient) // Set eBay client for OAuth (package-level)
+	webhookServer.SetStore(repo)
+	webhookServer.SetAccounts(accounts)
+	for _, account := range cfg.Accounts {
+		webhookServer.SetAccountChannel(account.Account, account.ChannelID)
+	}
+	webhookServer.SetOrderFetcher(ebayClient)
+	webhookServer.SetOfferComponents(bot.OfferComponents)
+	// Signing keys are fetched with the app's credentials, so they don't depend on any seller's token
+	webhookServer.SetSignatureVerifier(webhook.NewSignatureVerifier(accounts), cfg.WebhookStrictSigs)
+
+	// Sign /webhook-simulate notifications with the key shared with the simulator CLI, or a throwaway one
+	var simulatorKey *webhook.SimulatorKey
+	if cfg.WebhookSimulatorKey != "" {
+		simulatorKey, err = webhook.LoadSimulatorKey(cfg.WebhookSimulatorKey)
+	} else {
+		simulatorKey, err = webhook.NewSimulatorKey()
+	}
+	if err != nil {
+		log.Printf("⚠️ Simulated notifications will be unsigned: %v", err)
+	} else {
+		webhookServer.EnableSimulator(simulatorKey)
+	}
+
+	if cfg.WebhookTLSCert != "" {
+		webhookServer.SetTLS(cfg.WebhookTLSCert, cfg.WebhookTLSKey)
+	}
+
+	if cfg.WebhookPublicURL != "" {
+		if err := webhookServer.SetPublicURL(cfg.WebhookPublicURL); err != nil {
+			log.Fatalf("Invalid WEBHOOK_PUBLIC_URL: %v", err)
+		}
+	}
+
+	// Deliver Discord notifications through a persistent queue with retries
+	dispatcher := webhook.NewDispatcher(discord, repo, 2)
+	webhookServer.SetDispatcher(dispatcher)
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	// Fan events out to the configured sinks; each has its own event filter
+	webhookServer.SetDiscordEvents(cfg.DiscordEvents)
+	var sinks []*webhook.PostSink
+	if cfg.EventWebhookURL != "" {
+		sinks = append(sinks, webhook.NewHTTPSink(cfg.EventWebhookURL, cfg.EventWebhookSecret, cfg.EventWebhookEvents))
+	}
+	if cfg.SlackWebhookURL != "" {
+		sinks = append(sinks, webhook.NewSlackSink(cfg.SlackWebhookURL, cfg.SlackEvents))
+	}
+	for _, sink := range sinks {
+		webhookServer.AddSink(sink)
+		sink.Start()
+	}
+
 	go func() {
 		if err := webhookServer.Start(); err != nil {
 			log.Printf("⚠️ Webhook server error: %v", err)
 		}
 	}()
 
+	// Converge eBay's destination and subscriptions on the configured endpoint and topics.
+	// Runs after the server starts so eBay's challenge of a new destination succeeds.
+	// Every account subscribes the same endpoint; eBay's notifications name the seller.
+	// The destination is shared by the app, so accounts are reconciled one after another.
+	if cfg.WebhookPublicURL != "" {
+		topics := cfg.WebhookTopics
+		if len(topics) == 0 {
+			topics = ebay.WebhookTopics
+		}
+		var reconcilers []*webhook.Reconciler
+		for _, client := range accounts.All() {
+			reconciler := webhook.NewReconciler(client, webhook.DesiredSubscriptions{
+				Endpoint:    cfg.WebhookPublicURL,
+				VerifyToken: cfg.WebhookVerifyToken,
+				Topics:      topics,
+			})
+			if accounts.Len() > 1 {
+				reconciler.SetAccount(client.Account())
+			}
+			reconciler.SetReporter(dispatcher, cfg.AdminChannelID)
+			webhookServer.SetReconciler(client.Account(), reconciler)
+			reconcilers = append(reconcilers, reconciler)
+		}
+		go func() {
+			for n, reconciler := range reconcilers {
+				if _, err := reconciler.Reconcile(false); err != nil {
+					log.Printf("⚠️ Subscription reconciliation failed for account %s: %v", accounts.Names()[n], err)
+				}
+			}
+		}()
+	} else {
+		log.Println("ℹ️ Subscription reconciliation disabled (WEBHOOK_PUBLIC_URL not set)")
+	}
+
 	// Initialize bot and register commands after connection is open
-	botHandler := bot.NewHandler(discord, ebayClient)
+	botHandler := bot.NewHandler(discord, accounts)
 	botHandler.SetWebhookServer(webhookServer) // Pass webhook server for OAuth
+	botHandler.SetStore(repo)
+	botHandler.SetDeadLetterQueue(dispatcher)
 	botHandler.RegisterCommands()
 
+	for _, client := range accounts.All() {
+		// Accounts other than the default keep their own store records, cursors and baselines
+		storeAccount := ""
+		if client != ebayClient {
+			storeAccount = client.Account()
+		}
+
+		// Learn which seller each account is signed in as, so notifications can be attributed
+		if client.UserID() == "" && client.Status().HasToken {
+			go func(client *ebay.Client) {
+				if err := client.Identify(); err != nil {
+					log.Printf("⚠️ Failed to identify the seller for account %s: %v", client.Account(), err)
+				}
+			}(client)
+		}
+
+		// Remind the admin channel to re-authorize before the refresh token expires
+		reauthReminder := webhook.NewReauthReminder(client, dispatcher, cfg.AdminChannelID)
+		reauthReminder.SetAuthorizeCommand(botHandler.CommandMention("ebay-authorize"))
+		if accounts.Len() > 1 {
+			reauthReminder.SetAccount(client.Account())
+		}
+		reauthReminder.Start()
+		defer reauthReminder.Stop()
+
+		// Keep the local copy of orders and listings up to date
+		if cfg.SyncInterval > 0 {
+			orderSync := syncer.New(client, repo, cfg.SyncInterval)
+			if storeAccount != "" {
+				orderSync.SetAccount(storeAccount)
+			}
+			orderSync.Start()
+			defer orderSync.Stop()
+		}
+
+		// Poll for new orders and offers until webhook delivery is confirmed
+		if cfg.PollInterval > 0 {
+			poller := webhook.NewPoller(webhookServer, client, repo, cfg.PollInterval)
+			if storeAccount != "" {
+				poller.SetAccount(storeAccount)
+			}
+			poller.Start()
+			defer poller.Stop()
+		}
+	}
+	if cfg.SyncInterval <= 0 {
+		log.Println("ℹ️ Background sync disabled (SYNC_INTERVAL=0)")
+	}
+
 	fmt.Println("eBay Manager Bot is now running. Press CTRL+C to exit.")
 
 	// Wait for interrupt signal
@@ -68,4 +251,20 @@ func main() {
 	<-sc
 
 	fmt.Println("\nShutting down gracefully...")
+
+	// Finish in-flight requests, queued Discord messages and sink deliveries before the deferred
+	// Stop/Close calls shut down workers, the Discord session and the store
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := webhookServer.Shutdown(ctx); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	if err := dispatcher.Drain(ctx); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	for _, sink := range sinks {
+		if err := sink.Close(ctx); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
 }
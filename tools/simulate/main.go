@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"ebaymanager-bot/internal/webhook"
+
+	"github.com/joho/godotenv"
+)
+
+// Sends synthetic eBay notifications to a running bot's webhook endpoint, for
+// testing routing and Discord formatting without waiting for eBay.
+//
+//	go run ./tools/simulate -new-key data/simulator.pem   # once; set WEBHOOK_SIMULATOR_KEY to it
+//	go run ./tools/simulate -event offer-created
+func main() {
+	godotenv.Load()
+
+	port := os.Getenv("WEBHOOK_PORT")
+	if port == "" {
+		port = "8081"
+	}
+
+	event := flag.String("event", "", "event to simulate (see -list)")
+	url := flag.String("url", "http://localhost:"+port+"/webhook/ebay/notification", "notification endpoint to POST to")
+	keyPath := flag.String("key", os.Getenv("WEBHOOK_SIMULATOR_KEY"), "PEM key to sign with (default: WEBHOOK_SIMULATOR_KEY)")
+	unsigned := flag.Bool("unsigned", false, "send without an X-EBAY-SIGNATURE header")
+	newKey := flag.String("new-key", "", "generate a signing key at this path and exit")
+	list := flag.Bool("list", false, "list the events that can be simulated")
+	dryRun := flag.Bool("print", false, "print the payload instead of sending it")
+	flag.Parse()
+
+	switch {
+	case *newKey != "":
+		key, err := webhook.NewSimulatorKey()
+		if err == nil {
+			err = key.Save(*newKey)
+		}
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Wrote simulator key %s to %s\n", key.KID(), *newKey)
+		fmt.Printf("   Set WEBHOOK_SIMULATOR_KEY=%s for the bot and restart it\n", *newKey)
+		return
+	case *list:
+		for _, e := range webhook.SimulatedEvents {
+			fmt.Printf("%-18s %s\n", e.Name, e.Description)
+		}
+		return
+	case *event == "":
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	body, err := webhook.BuildSimulatedNotification(*event)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Println(string(body))
+		return
+	}
+
+	var key *webhook.SimulatorKey
+	if !*unsigned {
+		if *keyPath == "" {
+			fmt.Println("❌ No signing key: pass -key, set WEBHOOK_SIMULATOR_KEY, or use -unsigned")
+			os.Exit(1)
+		}
+		if key, err = webhook.LoadSimulatorKey(*keyPath); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	status, err := webhook.PostSimulatedNotification(*url, body, key)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %s sent to %s (HTTP %d)\n", *event, *url, status)
+}
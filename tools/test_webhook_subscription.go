@@ -37,7 +37,11 @@ func main() {
 		"MARKETPLACE_OFFER",
 	}
 
-	webhookURL := "https://jacob.it.com/webhook/ebay/notification"
+	webhookURL := os.Getenv("WEBHOOK_PUBLIC_URL")
+	if webhookURL == "" {
+		fmt.Println("Error: WEBHOOK_PUBLIC_URL not set")
+		return
+	}
 
 	// Build subscription payload
 	payload := map[string]interface{}{